@@ -243,11 +243,16 @@ ByteSliceArrayResult arbCoreGetMessages(CArbCore* arbcore_ptr,
 
 ByteSliceArrayResult arbCoreGetSequencerBatchItems(
     CArbCore* arbcore_ptr,
-    const void* start_index_ptr) {
+    const void* start_index_ptr,
+    uint64_t max_count) {
     try {
-        auto messages =
-            static_cast<const ArbCore*>(arbcore_ptr)
-                ->getSequencerBatchItems(receiveUint256(start_index_ptr));
+        std::optional<uint64_t> limit;
+        if (max_count > 0) {
+            limit = max_count;
+        }
+        auto messages = static_cast<const ArbCore*>(arbcore_ptr)
+                            ->getSequencerBatchItems(
+                                receiveUint256(start_index_ptr), limit);
         if (!messages.status.ok()) {
             return {{}, false};
         }
@@ -597,6 +602,68 @@ void arbCoreUpdateCheckpointPruningGas(CArbCore* arbcore_ptr,
     arbCore->updateCheckpointPruningGas(gas);
 }
 
+ByteSliceArrayResult arbCoreListCheckpoints(CArbCore* arbcore_ptr) {
+    auto arbCore = static_cast<ArbCore*>(arbcore_ptr);
+    try {
+        auto result = arbCore->listCheckpoints();
+        if (!result.status.ok()) {
+            return {{}, false};
+        }
+
+        std::vector<std::vector<unsigned char>> data;
+        for (const auto& checkpoint : result.data) {
+            std::vector<unsigned char> entry;
+            marshal_uint256_t(checkpoint.output.arb_gas_used, entry);
+            marshal_uint256_t(checkpoint.output.fully_processed_inbox.count,
+                              entry);
+            marshal_uint256_t(checkpoint.output.l2_block_number, entry);
+            marshal_uint256_t(checkpoint.output.log_count, entry);
+            marshal_uint256_t(checkpoint.output.send_count, entry);
+            marshal_uint256_t(checkpoint.output.last_inbox_timestamp, entry);
+            entry.push_back(checkpoint.has_machine ? 1 : 0);
+            marshal_uint64_t(checkpoint.entry_size, entry);
+            data.push_back(std::move(entry));
+        }
+        return {returnCharVectorVector(data), true};
+    } catch (const std::exception& e) {
+        return {{}, false};
+    }
+}
+
+Uint64Result arbCoreDeleteCheckpoints(CArbCore* arbcore_ptr,
+                                      ByteSlice gas_values) {
+    auto arbCore = static_cast<ArbCore*>(arbcore_ptr);
+    try {
+        std::vector<uint256_t> input;
+        auto bytes = receiveByteSlice(gas_values);
+        auto it = bytes.cbegin();
+        for (uint64_t i = 0; i < bytes.size(); i += 32) {
+            input.push_back(extractUint256(it));
+        }
+
+        return returnUint64Result(arbCore->deleteCheckpoints(input));
+    } catch (const std::exception& e) {
+        return {0, false};
+    }
+}
+
+Uint256Result arbCoreGetGasAtEndOfBlock(CArbCore* arbcore_ptr,
+                                        uint64_t block_number) {
+    auto arbCore = static_cast<ArbCore*>(arbcore_ptr);
+    try {
+        return returnUint256Result(arbCore->getGasAtEndOfBlock(block_number));
+    } catch (const std::exception& e) {
+        return {{}, false};
+    }
+}
+
+void arbCoreReorgMachineCache(CArbCore* arbcore_ptr,
+                              const void* next_gas_used_ptr) {
+    auto arbCore = static_cast<ArbCore*>(arbcore_ptr);
+    auto next_gas_used = receiveUint256(next_gas_used_ptr);
+    arbCore->reorgMachineCache(next_gas_used);
+}
+
 CMachine* arbCoreTakeMachine(CArbCore* arbcore_ptr,
                              CExecutionCursor* execution_cursor_ptr) {
     auto arbCore = static_cast<ArbCore*>(arbcore_ptr);
@@ -61,7 +61,8 @@ ByteSliceArrayResult arbCoreGetMessages(CArbCore* arbcore_ptr,
                                         const void* count_ptr);
 
 ByteSliceArrayResult arbCoreGetSequencerBatchItems(CArbCore* arbcore_ptr,
-                                                   const void* start_index_ptr);
+                                                   const void* start_index_ptr,
+                                                   uint64_t max_count);
 
 Uint256Result arbCoreGetSequencerBlockNumberAt(CArbCore* arbcore_ptr,
                                                const void* seq_num_ptr);
@@ -124,6 +125,14 @@ CExecutionCursorResult arbCoreGetExecutionCursorAtEndOfBlock(
 void arbCoreUpdateCheckpointPruningGas(CArbCore* arbcore_ptr,
                                        const void* gas_ptr);
 
+ByteSliceArrayResult arbCoreListCheckpoints(CArbCore* arbcore_ptr);
+Uint64Result arbCoreDeleteCheckpoints(CArbCore* arbcore_ptr,
+                                      ByteSlice gas_values);
+Uint256Result arbCoreGetGasAtEndOfBlock(CArbCore* arbcore_ptr,
+                                        uint64_t block_number);
+void arbCoreReorgMachineCache(CArbCore* arbcore_ptr,
+                              const void* next_gas_used_ptr);
+
 void arbCorePrintCoreThreadBacktrace(CArbCore* arbcore_ptr);
 
 #ifdef __cplusplus
@@ -33,6 +33,8 @@ CArbStorage* createArbStorage(const char* db_path,
     auto string_filename = std::string(db_path);
     auto string_save_rocksdb_path =
         std::string(arb_core_config.database_save_path);
+    auto string_secondary_path =
+        std::string(arb_core_config.database_secondary_path);
     ArbCoreConfig coreConfig{};
     coreConfig.message_process_count = arb_core_config.message_process_count;
     coreConfig.add_messages_max_failure_count =
@@ -78,6 +80,8 @@ CArbStorage* createArbStorage(const char* db_path,
     coreConfig.database_save_on_startup =
         arb_core_config.database_save_on_startup;
     coreConfig.database_exit_after = arb_core_config.database_exit_after;
+    coreConfig.database_secondary = arb_core_config.database_secondary;
+    coreConfig.database_secondary_path = string_secondary_path;
     coreConfig.test_reorg_to_l1_block = arb_core_config.test_reorg_to_l1_block;
     coreConfig.test_reorg_to_l2_block = arb_core_config.test_reorg_to_l2_block;
     coreConfig.test_reorg_to_log = arb_core_config.test_reorg_to_log;
@@ -167,6 +171,67 @@ int arbStorageInitialized(CArbStorage* storage_ptr) {
     return static_cast<ArbStorage*>(storage_ptr)->initialized();
 }
 
+uint64_t arbStorageCurrentSchemaVersion() {
+    return intx::narrow_cast<uint64_t>(ArbStorage::currentSchemaVersion());
+}
+
+// Returns 1 and sets version if the database has a schema version, 0 if it
+// does not and -1 on error
+int arbStorageGetSchemaVersion(CArbStorage* storage_ptr, uint64_t* version) {
+    auto storage = static_cast<ArbStorage*>(storage_ptr);
+    try {
+        auto result = storage->schemaVersion();
+        if (result.status.IsNotFound()) {
+            return 0;
+        }
+        if (!result.status.ok()) {
+            std::cerr << "Error getting schema version: "
+                      << result.status.ToString() << std::endl;
+            return -1;
+        }
+        *version = intx::narrow_cast<uint64_t>(result.data);
+        return 1;
+    } catch (const std::exception& e) {
+        std::cerr << "Exception getting schema version: " << e.what()
+                  << std::endl;
+        return -1;
+    }
+}
+
+int arbStorageSetSchemaVersion(CArbStorage* storage_ptr, uint64_t version) {
+    auto storage = static_cast<ArbStorage*>(storage_ptr);
+    try {
+        auto status = storage->updateSchemaVersion(version);
+        if (!status.ok()) {
+            std::cerr << "Error saving schema version: " << status.ToString()
+                      << std::endl;
+            return false;
+        }
+        return true;
+    } catch (const std::exception& e) {
+        std::cerr << "Exception saving schema version: " << e.what()
+                  << std::endl;
+        return false;
+    }
+}
+
+int arbStorageCatchUpWithPrimary(CArbStorage* storage_ptr) {
+    auto storage = static_cast<ArbStorage*>(storage_ptr);
+    try {
+        auto status = storage->catchUpWithPrimary();
+        if (!status.ok()) {
+            std::cerr << "Error catching up with primary database: "
+                      << status.ToString() << std::endl;
+            return false;
+        }
+        return true;
+    } catch (const std::exception& e) {
+        std::cerr << "Exception catching up with primary database: "
+                  << e.what() << std::endl;
+        return false;
+    }
+}
+
 int closeArbStorage(CArbStorage* storage_ptr) {
     auto storage = static_cast<ArbStorage*>(storage_ptr);
     return storage->closeArbStorage();
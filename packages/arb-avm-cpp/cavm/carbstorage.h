@@ -55,6 +55,8 @@ typedef struct {
     const char* database_save_path;
     int32_t database_save_on_startup;
     int32_t database_exit_after;
+    int32_t database_secondary;
+    const char* database_secondary_path;
     int32_t test_reorg_to_l1_block;
     int32_t test_reorg_to_l2_block;
     int32_t test_reorg_to_log;
@@ -70,6 +72,10 @@ void printDatabaseMetadata(CArbStorage* storage_ptr);
 int initializeArbStorage(CArbStorage* storage_ptr, const char* executable_path);
 int applyArbStorageConfig(CArbStorage* storage_ptr);
 int arbStorageInitialized(CArbStorage* storage_ptr);
+uint64_t arbStorageCurrentSchemaVersion();
+int arbStorageGetSchemaVersion(CArbStorage* storage_ptr, uint64_t* version);
+int arbStorageSetSchemaVersion(CArbStorage* storage_ptr, uint64_t version);
+int arbStorageCatchUpWithPrimary(CArbStorage* storage_ptr);
 void destroyArbStorage(CArbStorage* storage);
 int closeArbStorage(CArbStorage* storage_ptr);
 int cleanupValidator(CArbStorage* storage_ptr);
@@ -25,6 +25,7 @@ package cmachine
 import "C"
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/json"
 	"io/ioutil"
 	"math/big"
@@ -274,10 +275,21 @@ func (ac *ArbCore) GetMessages(startIndex *big.Int, count *big.Int) ([]inbox.Inb
 }
 
 func (ac *ArbCore) GetSequencerBatchItems(startIndex *big.Int) ([]inbox.SequencerBatchItem, error) {
+	return ac.getSequencerBatchItems(startIndex, 0)
+}
+
+func (ac *ArbCore) GetSequencerBatchItemsPage(startIndex *big.Int, maxCount uint64) ([]inbox.SequencerBatchItem, error) {
+	if maxCount == 0 {
+		return nil, nil
+	}
+	return ac.getSequencerBatchItems(startIndex, maxCount)
+}
+
+func (ac *ArbCore) getSequencerBatchItems(startIndex *big.Int, maxCount uint64) ([]inbox.SequencerBatchItem, error) {
 	defer runtime.KeepAlive(ac)
 	startIndexData := math.U256Bytes(startIndex)
 
-	result := C.arbCoreGetSequencerBatchItems(ac.c, unsafeDataPointer(startIndexData))
+	result := C.arbCoreGetSequencerBatchItems(ac.c, unsafeDataPointer(startIndexData), C.uint64_t(maxCount))
 	if result.found == 0 {
 		return nil, errors.New("failed to get messages")
 	}
@@ -488,6 +500,63 @@ func (ac *ArbCore) UpdateCheckpointPruningGas(gas *big.Int) {
 	C.arbCoreUpdateCheckpointPruningGas(ac.c, unsafeDataPointer(gasData))
 }
 
+func (ac *ArbCore) ListCheckpoints() ([]core.CheckpointInfo, error) {
+	defer runtime.KeepAlive(ac)
+	result := C.arbCoreListCheckpoints(ac.c)
+	if result.found == 0 {
+		return nil, errors.New("failed to list checkpoints")
+	}
+
+	data := receiveByteSliceArray(result.array)
+	checkpoints := make([]core.CheckpointInfo, 0, len(data))
+	for _, entry := range data {
+		if len(entry) != 32*6+1+8 {
+			return nil, errors.Errorf("unexpected checkpoint info length %v", len(entry))
+		}
+		checkpoints = append(checkpoints, core.CheckpointInfo{
+			TotalGasUsed:       new(big.Int).SetBytes(entry[0:32]),
+			MessageCount:       new(big.Int).SetBytes(entry[32:64]),
+			L2BlockNumber:      new(big.Int).SetBytes(entry[64:96]),
+			LogCount:           new(big.Int).SetBytes(entry[96:128]),
+			SendCount:          new(big.Int).SetBytes(entry[128:160]),
+			LastInboxTimestamp: new(big.Int).SetBytes(entry[160:192]),
+			HasMachine:         entry[192] != 0,
+			EntrySize:          binary.BigEndian.Uint64(entry[193:]),
+		})
+	}
+	return checkpoints, nil
+}
+
+func (ac *ArbCore) DeleteCheckpoints(gasValues []*big.Int) (uint64, error) {
+	defer runtime.KeepAlive(ac)
+	data := make([]byte, 0, len(gasValues)*32)
+	for _, gas := range gasValues {
+		data = append(data, math.U256Bytes(new(big.Int).Set(gas))...)
+	}
+	result := C.arbCoreDeleteCheckpoints(ac.c, toByteSliceView(data))
+	if result.found == 0 {
+		return 0, errors.New("failed to delete checkpoints")
+	}
+
+	return uint64(result.value), nil
+}
+
+func (ac *ArbCore) GetGasAtEndOfBlock(blockNumber uint64) (*big.Int, error) {
+	defer runtime.KeepAlive(ac)
+	result := C.arbCoreGetGasAtEndOfBlock(ac.c, C.uint64_t(blockNumber))
+	if result.found == 0 {
+		return nil, errors.Errorf("failed to get gas at end of block %v", blockNumber)
+	}
+
+	return receiveBigInt(result.value), nil
+}
+
+func (ac *ArbCore) ReorgMachineCache(nextGasUsed *big.Int) {
+	defer runtime.KeepAlive(ac)
+	nextGasUsedData := math.U256Bytes(new(big.Int).Set(nextGasUsed))
+	C.arbCoreReorgMachineCache(ac.c, unsafeDataPointer(nextGasUsedData))
+}
+
 func (ac *ArbCore) TakeMachine(executionCursor core.ExecutionCursor) (machine.Machine, error) {
 	defer runtime.KeepAlive(ac)
 	defer runtime.KeepAlive(executionCursor)
@@ -506,50 +575,14 @@ func (ac *ArbCore) TakeMachine(executionCursor core.ExecutionCursor) (machine.Ma
 }
 
 func (ac *ArbCore) DumpArbosState(m machine.Machine, blockNum uint64, dirname string) error {
-	defer runtime.KeepAlive(ac)
-	mach, ok := m.(*Machine)
-	if !ok {
-		return errors.Errorf("bad machine")
-	}
-	defer runtime.KeepAlive(mach)
-
-	type ArbosInitFileContents struct {
-		NextBlockNumber          uint64
-		AddressTableContentsPath string
-		RetryableDataPath        string
-		AccountsPath             string
-	}
-
-	indexJson := ArbosInitFileContents{
-		NextBlockNumber:          blockNum + 1,
-		AddressTableContentsPath: "addresstable.json",
-		RetryableDataPath:        "retryables.json",
-		AccountsPath:             "accounts.json",
-	}
-
-	caccountspath := C.CString(filepath.Join(dirname, indexJson.AccountsPath))
-	defer C.free(unsafe.Pointer(caccountspath))
-	retval := C.dumpAccounts(ac.c, mach.c, caccountspath)
-	if retval != 0 {
-		return errors.Errorf("dumpAccounts failed")
-	}
-
-	cretryablespath := C.CString(filepath.Join(dirname, indexJson.RetryableDataPath))
-	defer C.free(unsafe.Pointer(cretryablespath))
-	retval = C.dumpRetryables(ac.c, mach.c, cretryablespath)
-	if retval != 0 {
-		return errors.Errorf("dumpRetryables failed")
-	}
-
-	caddresstablepath := C.CString(filepath.Join(dirname, indexJson.AddressTableContentsPath))
-	defer C.free(unsafe.Pointer(caddresstablepath))
-	retval = C.dumpAddressTable(ac.c, mach.c, caddresstablepath)
-	if retval != 0 {
-		return errors.Errorf("dumpAddressTable failed")
+	for _, section := range core.ArbosStateSections {
+		if err := ac.DumpArbosStateSection(m, section, filepath.Join(dirname, section.Filename())); err != nil {
+			return err
+		}
 	}
 
 	indexPath := filepath.Join(dirname, "index.json")
-	indexBytes, err := json.Marshal(indexJson)
+	indexBytes, err := json.Marshal(core.NewArbosStateIndex(blockNum))
 	if err != nil {
 		return err
 	}
@@ -560,6 +593,33 @@ func (ac *ArbCore) DumpArbosState(m machine.Machine, blockNum uint64, dirname st
 	return nil
 }
 
+func (ac *ArbCore) DumpArbosStateSection(m machine.Machine, section core.ArbosStateSection, filename string) error {
+	defer runtime.KeepAlive(ac)
+	mach, ok := m.(*Machine)
+	if !ok {
+		return errors.Errorf("bad machine")
+	}
+	defer runtime.KeepAlive(mach)
+
+	cfilename := C.CString(filename)
+	defer C.free(unsafe.Pointer(cfilename))
+	var retval C.int
+	switch section {
+	case core.ArbosStateAccounts:
+		retval = C.dumpAccounts(ac.c, mach.c, cfilename)
+	case core.ArbosStateRetryables:
+		retval = C.dumpRetryables(ac.c, mach.c, cfilename)
+	case core.ArbosStateAddressTable:
+		retval = C.dumpAddressTable(ac.c, mach.c, cfilename)
+	default:
+		return errors.Errorf("unknown arbos state section %v", section)
+	}
+	if retval != 0 {
+		return errors.Errorf("dumping %v failed", section)
+	}
+	return nil
+}
+
 func (ac *ArbCore) LogsCursorPosition(cursorIndex *big.Int) (*big.Int, error) {
 	defer runtime.KeepAlive(ac)
 	cursorIndexData := math.U256Bytes(cursorIndex)
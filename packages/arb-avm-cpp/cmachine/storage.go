@@ -64,6 +64,9 @@ func NewArbStorage(dbPath string, coreConfig *configuration.Core) (*ArbStorage,
 	cDatabaseSavePath := C.CString(coreConfig.Database.SavePath)
 	defer C.free(unsafe.Pointer(cDatabaseSavePath))
 
+	cSecondaryPath := C.CString(coreConfig.Database.Secondary.Path)
+	defer C.free(unsafe.Pointer(cSecondaryPath))
+
 	checkpointPruningMode, err := stringToPruningMode(coreConfig.CheckpointPruningMode)
 	if err != nil {
 		return nil, err
@@ -101,6 +104,8 @@ func NewArbStorage(dbPath string, coreConfig *configuration.Core) (*ArbStorage,
 		database_exit_after:                boolToCInt(coreConfig.Database.ExitAfter),
 		database_save_interval:             C.int(databaseSaveIntervalSeconds),
 		database_save_path:                 cDatabaseSavePath,
+		database_secondary:                 boolToCInt(coreConfig.Database.Secondary.Enable),
+		database_secondary_path:            cSecondaryPath,
 		test_reorg_to_l1_block:             C.int(coreConfig.Test.ReorgTo.L1Block),
 		test_reorg_to_l2_block:             C.int(coreConfig.Test.ReorgTo.L2Block),
 		test_reorg_to_log:                  C.int(coreConfig.Test.ReorgTo.Log),
@@ -119,9 +124,49 @@ func NewArbStorage(dbPath string, coreConfig *configuration.Core) (*ArbStorage,
 	returnVal := &ArbStorage{cArbStorage}
 	runtime.SetFinalizer(returnVal, cDestroyArbStorage)
 
+	version, found, err := returnVal.SchemaVersion()
+	if err != nil {
+		returnVal.CloseArbStorage()
+		return nil, err
+	}
+	if found && version > returnVal.CurrentSchemaVersion() {
+		returnVal.CloseArbStorage()
+		return nil, errors.Errorf("database %v has unknown schema version %v, newest supported is %v", dbPath, version, returnVal.CurrentSchemaVersion())
+	}
+
 	return returnVal, nil
 }
 
+func (s *ArbStorage) CurrentSchemaVersion() uint64 {
+	return uint64(C.arbStorageCurrentSchemaVersion())
+}
+
+func (s *ArbStorage) SchemaVersion() (version uint64, found bool, err error) {
+	defer runtime.KeepAlive(s)
+	var cVersion C.uint64_t
+	status := C.arbStorageGetSchemaVersion(s.c, &cVersion)
+	if status < 0 {
+		return 0, false, errors.New("failed to get database schema version")
+	}
+	return uint64(cVersion), status == 1, nil
+}
+
+func (s *ArbStorage) SetSchemaVersion(version uint64) error {
+	defer runtime.KeepAlive(s)
+	if C.arbStorageSetSchemaVersion(s.c, C.uint64_t(version)) == 0 {
+		return errors.Errorf("failed to set database schema version to %v", version)
+	}
+	return nil
+}
+
+func (s *ArbStorage) CatchUpWithPrimary() error {
+	defer runtime.KeepAlive(s)
+	if C.arbStorageCatchUpWithPrimary(s.c) == 0 {
+		return errors.New("failed to catch up with primary database")
+	}
+	return nil
+}
+
 func (s *ArbStorage) PrintDatabaseMetadata() {
 	defer runtime.KeepAlive(s)
 	C.printDatabaseMetadata(s.c)
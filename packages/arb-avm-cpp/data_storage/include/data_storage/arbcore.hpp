@@ -80,6 +80,12 @@ struct DebugPrintCollectionOptions {
     uint256_t log_number_end;
 };
 
+struct CheckpointSummary {
+    MachineOutput output;
+    bool has_machine;
+    size_t entry_size;
+};
+
 class ArbCore {
    public:
     typedef enum {
@@ -220,12 +226,15 @@ class ArbCore {
     bool startThread();
     void abortThread();
 
-   private:
-    // Private database interaction
+    // Schema version checked by applyConfig, updated by database migrations
+    [[nodiscard]] static uint256_t currentSchemaVersion();
     [[nodiscard]] ValueResult<uint256_t> schemaVersion(
         ReadTransaction& tx) const;
     rocksdb::Status updateSchemaVersion(ReadWriteTransaction& tx,
                                         const uint256_t& schema_version);
+
+   private:
+    // Private database interaction
     ValueResult<std::string> pruningMode(ReadTransaction& tx) const;
     rocksdb::Status updatePruningMode(ReadWriteTransaction& tx,
                                       const std::string& pruning_mode);
@@ -316,6 +325,16 @@ class ArbCore {
     // Controlling checkpoint pruning
     void updateCheckpointPruningGas(uint256_t gas);
 
+    // Inspecting and manually pruning checkpoints
+    ValueResult<std::vector<CheckpointSummary>> listCheckpoints();
+    ValueResult<uint64_t> deleteCheckpoints(
+        const std::vector<uint256_t>& gas_values);
+    ValueResult<uint256_t> getGasAtEndOfBlock(const uint256_t& block_number);
+
+    // Drop cached machines at or after next_gas_used, used by secondary
+    // instances after the primary reorged
+    void reorgMachineCache(const uint256_t& next_gas_used);
+
     // Useful for manual value loading
     std::shared_ptr<DataStorage> getDataStorage();
 
@@ -417,7 +436,9 @@ class ArbCore {
     [[nodiscard]] ValueResult<std::vector<std::vector<unsigned char>>>
     getMessages(uint256_t index, uint256_t count) const;
     [[nodiscard]] ValueResult<std::vector<std::vector<unsigned char>>>
-    getSequencerBatchItems(uint256_t index) const;
+    getSequencerBatchItems(
+        uint256_t index,
+        std::optional<uint64_t> max_count = std::nullopt) const;
     [[nodiscard]] ValueResult<uint256_t> getSequencerBlockNumberAt(
         uint256_t sequence_number) const;
     [[nodiscard]] ValueResult<std::vector<unsigned char>> genInboxProof(
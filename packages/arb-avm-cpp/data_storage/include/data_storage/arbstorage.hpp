@@ -47,6 +47,11 @@ class ArbStorage {
     InitializeResult applyConfig();
     [[nodiscard]] bool initialized() const;
 
+    // ArbCore schema version recorded in the database
+    [[nodiscard]] static uint256_t currentSchemaVersion();
+    [[nodiscard]] ValueResult<uint256_t> schemaVersion() const;
+    rocksdb::Status updateSchemaVersion(const uint256_t& version);
+
     [[nodiscard]] std::unique_ptr<AggregatorStore> getAggregatorStore() const;
     [[nodiscard]] std::shared_ptr<ArbCore> getArbCore();
     [[nodiscard]] std::shared_ptr<DataStorage> getDataStorage();
@@ -61,6 +66,7 @@ class ArbStorage {
     [[nodiscard]] std::unique_ptr<ReadWriteTransaction>
     makeReadWriteTransaction();
     rocksdb::Status cleanupValidator();
+    rocksdb::Status catchUpWithPrimary();
 };
 
 #endif /* arbstorage_hpp */
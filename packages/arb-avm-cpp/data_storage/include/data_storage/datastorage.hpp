@@ -18,6 +18,7 @@
 #define datastorage_hpp
 
 #include <memory>
+#include <shared_mutex>
 #include <string>
 #include <utility>
 #include <vector>
@@ -70,6 +71,9 @@ class DataStorage {
     };
     std::string txn_db_path;
     std::unique_ptr<rocksdb::TransactionDB> txn_db;
+    // Only set when opened as a read only secondary instance, in which case
+    // txn_db is null
+    std::unique_ptr<rocksdb::DB> secondary_db;
     std::vector<rocksdb::ColumnFamilyHandle*> column_handles;
     std::vector<uint8_t> secret_hash_seed;
 
@@ -84,17 +88,34 @@ class DataStorage {
     [[nodiscard]] DbLockShared tryLockShared() const;
     rocksdb::Status cleanupValidator();
     rocksdb::Status compact(bool aggressive);
+    rocksdb::Status tryCatchUpWithPrimary();
+
+    [[nodiscard]] bool isSecondary() const { return secondary_db != nullptr; }
+    [[nodiscard]] rocksdb::DB* db() const {
+        if (secondary_db) {
+            return secondary_db.get();
+        }
+        return txn_db.get();
+    }
 
    private:
     std::atomic<bool> shutting_down{false};
     mutable std::atomic<int64_t> concurrent_database_access_counter{0};
 
+    // Secondary instance can't use snapshots, so transactions hold this
+    // shared to keep a consistent view while catching up holds it unique
+    std::shared_mutex secondary_mutex;
+
     rocksdb::Status updateSecretHashSeed();
 
     [[nodiscard]] std::unique_ptr<rocksdb::Transaction> beginTransaction() {
         // Make sure database isn't closed while it is being used
         auto counter = tryLockShared();
 
+        if (secondary_db) {
+            // Secondary instance is read only, reads go directly to database
+            return nullptr;
+        }
         return std::unique_ptr<rocksdb::Transaction>{
             txn_db->BeginTransaction(rocksdb::WriteOptions())};
     }
@@ -107,15 +128,27 @@ class Transaction {
     std::shared_ptr<DataStorage> datastorage;
     std::unique_ptr<rocksdb::Transaction> transaction;
 
+   private:
+    // Only held for secondary instance, see DataStorage::secondary_mutex
+    std::shared_lock<std::shared_mutex> secondary_lock;
+
+   public:
     Transaction(std::shared_ptr<DataStorage> datastorage_,
                 std::unique_ptr<rocksdb::Transaction> transaction_)
         : datastorage(std::move(datastorage_)),
-          transaction(std::move(transaction_)) {}
+          transaction(std::move(transaction_)) {
+        if (datastorage->secondary_db) {
+            secondary_lock = std::shared_lock(datastorage->secondary_mutex);
+        }
+    }
 
     rocksdb::Status commit() {
         // Make sure database isn't closed while it is being used
         auto counter = datastorage->tryLockShared();
 
+        if (!transaction) {
+            return rocksdb::Status::NotSupported("read only database");
+        }
         return transaction->Commit();
     }
 
@@ -123,9 +156,58 @@ class Transaction {
         // Make sure database isn't closed while it is being used
         auto counter = datastorage->tryLockShared();
 
+        if (!transaction) {
+            return rocksdb::Status::NotSupported("read only database");
+        }
         return transaction->Rollback();
     }
 
+    rocksdb::Status get(const rocksdb::ReadOptions& read_options,
+                        rocksdb::ColumnFamilyHandle* family,
+                        const rocksdb::Slice& key,
+                        std::string* value) const {
+        if (!transaction) {
+            return datastorage->db()->Get(read_options, family, key, value);
+        }
+        return transaction->Get(read_options, family, key, value);
+    }
+
+    rocksdb::Status get(const rocksdb::ReadOptions& read_options,
+                        rocksdb::ColumnFamilyHandle* family,
+                        const rocksdb::Slice& key,
+                        rocksdb::PinnableSlice* value) const {
+        if (!transaction) {
+            return datastorage->db()->Get(read_options, family, key, value);
+        }
+        return transaction->Get(read_options, family, key, value);
+    }
+
+    rocksdb::Status put(rocksdb::ColumnFamilyHandle* family,
+                        const rocksdb::Slice& key,
+                        const rocksdb::Slice& value) {
+        if (!transaction) {
+            return rocksdb::Status::NotSupported("read only database");
+        }
+        return transaction->Put(family, key, value);
+    }
+
+    rocksdb::Status del(rocksdb::ColumnFamilyHandle* family,
+                        const rocksdb::Slice& key) {
+        if (!transaction) {
+            return rocksdb::Status::NotSupported("read only database");
+        }
+        return transaction->Delete(family, key);
+    }
+
+    [[nodiscard]] rocksdb::Iterator* getIterator(
+        const rocksdb::ReadOptions& read_options,
+        rocksdb::ColumnFamilyHandle* family) const {
+        if (!transaction) {
+            return datastorage->db()->NewIterator(read_options, family);
+        }
+        return transaction->GetIterator(read_options, family);
+    }
+
    private:
     static std::unique_ptr<Transaction> makeTransaction(
         std::shared_ptr<DataStorage> store);
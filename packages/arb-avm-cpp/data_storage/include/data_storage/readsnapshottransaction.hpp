@@ -27,9 +27,18 @@ class ReadSnapshotTransaction : public ReadConsistentTransaction {
         // Make sure database isn't closed while it is being used
         auto counter = transaction->datastorage->tryLockShared();
 
+        if (transaction->datastorage->isSecondary()) {
+            // Secondary instance doesn't support snapshots, the transaction
+            // blocks catching up with the primary instead
+            return;
+        }
         read_options.snapshot = transaction->datastorage->txn_db->GetSnapshot();
     }
     ~ReadSnapshotTransaction() {
+        if (read_options.snapshot == nullptr) {
+            return;
+        }
+
         // Make sure database isn't closed while it is being used
         auto counter = transaction->datastorage->tryLockShared();
 
@@ -26,8 +26,8 @@ class ReadWriteTransaction : public ReadConsistentTransaction {
    public:
     explicit ReadWriteTransaction(std::shared_ptr<DataStorage> store);
 
-    rocksdb::Status commit() { return transaction->transaction->Commit(); }
-    rocksdb::Status rollback() { return transaction->transaction->Rollback(); }
+    rocksdb::Status commit() { return transaction->commit(); }
+    rocksdb::Status rollback() { return transaction->rollback(); }
 
     rocksdb::Status defaultPut(const rocksdb::Slice& key,
                                const rocksdb::Slice& value);
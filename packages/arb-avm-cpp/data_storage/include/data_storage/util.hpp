@@ -127,6 +127,12 @@ struct ArbCoreConfig {
     // Exit after manipulating database
     bool database_exit_after{false};
 
+    // Open database read only as a secondary instance of another process
+    bool database_secondary{false};
+
+    // Directory where secondary instance keeps its own info log and metadata
+    std::string database_secondary_path{};
+
     // Number of seconds to keep checkpoints
     uint64_t checkpoint_pruning_age_seconds{0};
 
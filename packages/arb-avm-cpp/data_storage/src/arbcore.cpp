@@ -2149,7 +2149,8 @@ rocksdb::Status ArbCore::saveSends(
 }
 
 ValueResult<std::vector<std::vector<unsigned char>>>
-ArbCore::getSequencerBatchItems(uint256_t index) const {
+ArbCore::getSequencerBatchItems(uint256_t index,
+                                std::optional<uint64_t> max_count) const {
     ReadTransaction tx(data_storage);
 
     std::vector<unsigned char> first_key_vec;
@@ -2159,7 +2160,7 @@ ArbCore::getSequencerBatchItems(uint256_t index) const {
     it->Seek(first_key_slice);
 
     std::vector<std::vector<unsigned char>> ret;
-    while (it->Valid()) {
+    while (it->Valid() && (!max_count || ret.size() < *max_count)) {
         auto key_ptr = reinterpret_cast<const unsigned char*>(it->key().data());
         auto value_ptr =
             reinterpret_cast<const unsigned char*>(it->value().data());
@@ -3146,6 +3147,10 @@ rocksdb::Status ArbCore::updateSendInsertedCount(ReadWriteTransaction& tx,
     return tx.statePut(vecToSlice(send_inserted_key), vecToSlice(value));
 }
 
+uint256_t ArbCore::currentSchemaVersion() {
+    return arbcore_schema_version;
+}
+
 ValueResult<uint256_t> ArbCore::schemaVersion(ReadTransaction& tx) const {
     return tx.stateGetUint256(vecToSlice(schema_version_key));
 }
@@ -4123,6 +4128,91 @@ uint256_t ArbCore::getCheckpointPruningGas() {
     return unsafe_checkpoint_pruning_gas_used;
 }
 
+ValueResult<std::vector<CheckpointSummary>> ArbCore::listCheckpoints() {
+    ReadTransaction tx(data_storage);
+    auto it = tx.checkpointGetIterator();
+    std::vector<CheckpointSummary> checkpoints;
+    for (it->SeekToFirst(); it->Valid(); it->Next()) {
+        std::vector<unsigned char> checkpoint_vector(
+            it->value().data(), it->value().data() + it->value().size());
+        auto checkpoint_variant = extractMachineStateKeys(checkpoint_vector);
+        checkpoints.push_back(CheckpointSummary{
+            getMachineOutput(checkpoint_variant),
+            std::holds_alternative<MachineStateKeys>(checkpoint_variant),
+            it->key().size() + it->value().size()});
+    }
+    if (!it->status().ok()) {
+        return {it->status(), {}};
+    }
+
+    return {rocksdb::Status::OK(), std::move(checkpoints)};
+}
+
+// deleteCheckpoints removes the checkpoints saved at the given gas values.
+// Like pruneCheckpoints, the 0th, genesis and last checkpoints are never
+// deleted. Returns the number of checkpoints deleted.
+ValueResult<uint64_t> ArbCore::deleteCheckpoints(
+    const std::vector<uint256_t>& gas_values) {
+    ReadWriteTransaction tx(data_storage);
+    auto it = tx.checkpointGetIterator();
+
+    std::set<uint256_t> protected_gas;
+    it->SeekToFirst();
+    for (int i = 0; i < 2 && it->Valid(); i++, it->Next()) {
+        protected_gas.insert(deserializeUint256t(it->key().data()));
+    }
+    it->SeekToLast();
+    if (it->Valid()) {
+        protected_gas.insert(deserializeUint256t(it->key().data()));
+    }
+    if (!it->status().ok()) {
+        return {it->status(), 0};
+    }
+
+    uint64_t deleted_count = 0;
+    for (const auto& gas : gas_values) {
+        if (protected_gas.count(gas) > 0) {
+            continue;
+        }
+
+        std::vector<unsigned char> key;
+        marshal_uint256_t(gas, key);
+        auto checkpoint_result = tx.checkpointGetVector(vecToSlice(key));
+        if (checkpoint_result.status.IsNotFound()) {
+            continue;
+        }
+        if (!checkpoint_result.status.ok()) {
+            return {checkpoint_result.status, 0};
+        }
+
+        auto checkpoint_variant =
+            extractMachineStateKeys(checkpoint_result.data);
+        auto machine_output = getMachineOutput(checkpoint_variant);
+        deleteCheckpoint(tx, checkpoint_variant);
+        printMachineOutputInfo("Deleted checkpoint", machine_output);
+        deleted_count++;
+    }
+
+    auto status = tx.commit();
+    if (!status.ok()) {
+        std::cerr << "unable to delete checkpoints, "
+                  << "error calling commit: " << status.ToString() << std::endl;
+        return {status, 0};
+    }
+
+    return {rocksdb::Status::OK(), deleted_count};
+}
+
+ValueResult<uint256_t> ArbCore::getGasAtEndOfBlock(
+    const uint256_t& block_number) {
+    ReadTransaction tx(data_storage);
+    return getGasAtBlock(tx, block_number);
+}
+
+void ArbCore::reorgMachineCache(const uint256_t& next_gas_used) {
+    combined_machine_cache.reorg(next_gas_used);
+}
+
 std::shared_ptr<DataStorage> ArbCore::getDataStorage() {
     return data_storage;
 }
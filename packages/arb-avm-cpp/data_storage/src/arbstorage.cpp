@@ -17,6 +17,7 @@
 #include <data_storage/arbstorage.hpp>
 
 #include <data_storage/aggregator.hpp>
+#include <data_storage/readwritetransaction.hpp>
 #include <data_storage/storageresult.hpp>
 #include <data_storage/value/code.hpp>
 
@@ -52,6 +53,24 @@ bool ArbStorage::initialized() const {
     return arb_core->initialized();
 }
 
+uint256_t ArbStorage::currentSchemaVersion() {
+    return ArbCore::currentSchemaVersion();
+}
+
+ValueResult<uint256_t> ArbStorage::schemaVersion() const {
+    ReadTransaction tx(datastorage);
+    return arb_core->schemaVersion(tx);
+}
+
+rocksdb::Status ArbStorage::updateSchemaVersion(const uint256_t& version) {
+    ReadWriteTransaction tx(datastorage);
+    auto status = arb_core->updateSchemaVersion(tx, version);
+    if (!status.ok()) {
+        return status;
+    }
+    return tx.commit();
+}
+
 bool ArbStorage::closeArbStorage() {
     arb_core->abortThread();
     auto status = datastorage->closeDb();
@@ -66,6 +85,10 @@ rocksdb::Status ArbStorage::cleanupValidator() {
     return datastorage->cleanupValidator();
 }
 
+rocksdb::Status ArbStorage::catchUpWithPrimary() {
+    return datastorage->tryCatchUpWithPrimary();
+}
+
 std::unique_ptr<AggregatorStore> ArbStorage::getAggregatorStore() const {
     return std::make_unique<AggregatorStore>(datastorage);
 }
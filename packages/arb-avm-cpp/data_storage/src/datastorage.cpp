@@ -87,6 +87,28 @@ DataStorage::DataStorage(const std::string& db_path,
     column_descriptors[AGGREGATOR_COLUMN] = {"aggregator", cf_options};
     column_descriptors[REFCOUNTED_COLUMN] = {"refcounted", hashkey_cf_options};
 
+    if (coreConfig.database_secondary) {
+        // Secondary instance must keep all files of primary open
+        options.max_open_files = -1;
+        options.create_if_missing = false;
+        options.create_missing_column_families = false;
+
+        rocksdb::DB* db = nullptr;
+        auto status = rocksdb::DB::OpenAsSecondary(
+            options, txn_db_path, coreConfig.database_secondary_path,
+            column_descriptors, &column_handles, &db);
+        if (!status.ok()) {
+            throw std::runtime_error(status.ToString());
+        }
+        secondary_db = std::unique_ptr<rocksdb::DB>(db);
+
+        status = updateSecretHashSeed();
+        if (!status.ok()) {
+            throw std::runtime_error(status.ToString());
+        }
+        return;
+    }
+
     rocksdb::TransactionDB* db = nullptr;
     auto status =
         rocksdb::TransactionDB::Open(options, txn_options, txn_db_path,
@@ -129,7 +151,7 @@ DataStorage::~DataStorage() {
 }
 
 rocksdb::Status DataStorage::closeDb() {
-    if (txn_db) {
+    if (txn_db || secondary_db) {
         std::cerr << "closing ArbStorage" << std::endl;
         shutting_down = true;
         auto last_concurrent_counter =
@@ -159,12 +181,19 @@ rocksdb::Status DataStorage::closeDb() {
             last_concurrent_counter = concurrent_database_access_counter.load();
         }
         for (auto handle : column_handles) {
-            auto status = txn_db->DestroyColumnFamilyHandle(handle);
+            auto status = db()->DestroyColumnFamilyHandle(handle);
             if (!status.ok()) {
                 return status;
             }
         }
 
+        if (secondary_db) {
+            auto s = secondary_db->Close();
+            secondary_db.reset();
+            std::cerr << "closed ArbStorage" << std::endl;
+            return s;
+        }
+
         txn_db->SyncWAL();
         auto s = txn_db->Close();
         for (std::chrono::seconds close_seconds_left = std::chrono::minutes(10);
@@ -200,10 +229,17 @@ rocksdb::Status DataStorage::closeDb() {
 }
 
 rocksdb::Status DataStorage::cleanupValidator() {
+    if (secondary_db) {
+        return rocksdb::Status::NotSupported("read only database");
+    }
     return txn_db->DropColumnFamily(column_handles[AGGREGATOR_COLUMN]);
 }
 
 rocksdb::Status DataStorage::compact(bool aggressive) {
+    if (secondary_db) {
+        return rocksdb::Status::NotSupported("read only database");
+    }
+
     auto cr_options = rocksdb::CompactRangeOptions();
     rocksdb::FlushOptions compact_flush_options;
 
@@ -233,6 +269,20 @@ rocksdb::Status DataStorage::compact(bool aggressive) {
     return rocksdb::Status::OK();
 }
 
+rocksdb::Status DataStorage::tryCatchUpWithPrimary() {
+    if (!secondary_db) {
+        return rocksdb::Status::NotSupported("not a secondary database");
+    }
+
+    // Make sure database isn't closed while it is being used
+    auto lock = tryLockShared();
+
+    // Wait for open transactions so none see a mix of old and new data
+    std::unique_lock secondary_lock(secondary_mutex);
+
+    return secondary_db->TryCatchUpWithPrimary();
+}
+
 std::unique_ptr<Transaction> Transaction::makeTransaction(
     std::shared_ptr<DataStorage> store) {
     // Make sure database isn't closed while it is being used
@@ -246,6 +296,10 @@ rocksdb::Status DataStorage::clearDBExceptInbox() {
     // Make sure database isn't closed while it is being used
     auto lock = tryLockShared();
 
+    if (secondary_db) {
+        return rocksdb::Status::NotSupported("read only database");
+    }
+
     for (int i = 0; i < FAMILY_COLUMN_COUNT; i++) {
         if (i == DEFAULT_COLUMN || i == DELAYEDMESSAGE_COLUMN ||
             i == SEQUENCERBATCHITEM_COLUMN || i == SEQUENCERBATCH_COLUMN) {
@@ -268,8 +322,8 @@ rocksdb::Status DataStorage::updateSecretHashSeed() {
     rocksdb::PinnableSlice value;
     rocksdb::ReadOptions read_opts;
     auto status =
-        txn_db->Get(read_opts, column_handles[STATE_COLUMN], key, &value);
-    if (status.IsNotFound()) {
+        db()->Get(read_opts, column_handles[STATE_COLUMN], key, &value);
+    if (status.IsNotFound() && !secondary_db) {
         secret_hash_seed.resize(32);
         RAND_bytes(secret_hash_seed.data(),
                    static_cast<int>(secret_hash_seed.size()));
@@ -30,7 +30,7 @@ rocksdb::Status ReadTransaction::createRocksdbCheckpoint(
 
     rocksdb::Checkpoint* checkpoint;
     auto status = rocksdb::Checkpoint::Create(
-        transaction->datastorage->txn_db.get(), &checkpoint);
+        transaction->datastorage->db(), &checkpoint);
     if (!status.ok()) {
         return status;
     }
@@ -46,7 +46,7 @@ rocksdb::Status ReadTransaction::defaultGet(const rocksdb::Slice& key,
     // Make sure database isn't closed while it is being used
     auto counter = transaction->datastorage->tryLockShared();
 
-    return transaction->transaction->Get(
+    return transaction->get(
         read_options,
         transaction->datastorage->column_handles[DataStorage::DEFAULT_COLUMN],
         key, value);
@@ -56,7 +56,7 @@ rocksdb::Status ReadTransaction::stateGet(const rocksdb::Slice& key,
     // Make sure database isn't closed while it is being used
     auto counter = transaction->datastorage->tryLockShared();
 
-    return transaction->transaction->Get(
+    return transaction->get(
         read_options,
         transaction->datastorage->column_handles[DataStorage::STATE_COLUMN],
         key, value);
@@ -66,7 +66,7 @@ rocksdb::Status ReadTransaction::checkpointGet(const rocksdb::Slice& key,
     // Make sure database isn't closed while it is being used
     auto counter = transaction->datastorage->tryLockShared();
 
-    return transaction->transaction->Get(
+    return transaction->get(
         read_options,
         transaction->datastorage
             ->column_handles[DataStorage::CHECKPOINT_COLUMN],
@@ -77,7 +77,7 @@ rocksdb::Status ReadTransaction::logGet(const rocksdb::Slice& key,
     // Make sure database isn't closed while it is being used
     auto counter = transaction->datastorage->tryLockShared();
 
-    return transaction->transaction->Get(
+    return transaction->get(
         read_options,
         transaction->datastorage->column_handles[DataStorage::LOG_COLUMN], key,
         value);
@@ -87,7 +87,7 @@ rocksdb::Status ReadTransaction::sendGet(const rocksdb::Slice& key,
     // Make sure database isn't closed while it is being used
     auto counter = transaction->datastorage->tryLockShared();
 
-    return transaction->transaction->Get(
+    return transaction->get(
         read_options,
         transaction->datastorage->column_handles[DataStorage::SEND_COLUMN], key,
         value);
@@ -97,7 +97,7 @@ rocksdb::Status ReadTransaction::sideloadGet(const rocksdb::Slice& key,
     // Make sure database isn't closed while it is being used
     auto counter = transaction->datastorage->tryLockShared();
 
-    return transaction->transaction->Get(
+    return transaction->get(
         read_options,
         transaction->datastorage->column_handles[DataStorage::SIDELOAD_COLUMN],
         key, value);
@@ -108,7 +108,7 @@ rocksdb::Status ReadTransaction::aggregatorGet(const rocksdb::Slice& key,
     // Make sure database isn't closed while it is being used
     auto counter = transaction->datastorage->tryLockShared();
 
-    return transaction->transaction->Get(
+    return transaction->get(
         read_options,
         transaction->datastorage
             ->column_handles[DataStorage::AGGREGATOR_COLUMN],
@@ -120,7 +120,7 @@ rocksdb::Status ReadTransaction::refCountedGet(const rocksdb::Slice& key,
     // Make sure database isn't closed while it is being used
     auto counter = transaction->datastorage->tryLockShared();
 
-    return transaction->transaction->Get(
+    return transaction->get(
         read_options,
         transaction->datastorage
             ->column_handles[DataStorage::REFCOUNTED_COLUMN],
@@ -133,7 +133,7 @@ rocksdb::Status ReadTransaction::refCountedGet(
     // Make sure database isn't closed while it is being used
     auto counter = transaction->datastorage->tryLockShared();
 
-    return transaction->transaction->Get(
+    return transaction->get(
         read_options,
         transaction->datastorage
             ->column_handles[DataStorage::REFCOUNTED_COLUMN],
@@ -144,7 +144,7 @@ std::unique_ptr<rocksdb::Iterator> ReadTransaction::stateGetIterator() const {
     // Make sure database isn't closed while it is being used
     auto counter = transaction->datastorage->tryLockShared();
 
-    auto it = transaction->transaction->GetIterator(
+    auto it = transaction->getIterator(
         read_options,
         transaction->datastorage->column_handles[DataStorage::STATE_COLUMN]);
     return std::unique_ptr<rocksdb::Iterator>(it);
@@ -155,7 +155,7 @@ std::unique_ptr<rocksdb::Iterator> ReadTransaction::checkpointGetIterator()
     // Make sure database isn't closed while it is being used
     auto counter = transaction->datastorage->tryLockShared();
 
-    auto it = transaction->transaction->GetIterator(
+    auto it = transaction->getIterator(
         read_options, transaction->datastorage
                           ->column_handles[DataStorage::CHECKPOINT_COLUMN]);
     return std::unique_ptr<rocksdb::Iterator>(it);
@@ -171,7 +171,7 @@ ReadTransaction::sequencerBatchItemGetIterator(
     auto read_opts = read_options;
     read_opts.iterate_lower_bound = lower_bound;
     read_opts.iterate_upper_bound = upper_bound;
-    auto it = transaction->transaction->GetIterator(
+    auto it = transaction->getIterator(
         read_options,
         transaction->datastorage
             ->column_handles[DataStorage::SEQUENCERBATCHITEM_COLUMN]);
@@ -187,7 +187,7 @@ std::unique_ptr<rocksdb::Iterator> ReadTransaction::delayedMessageGetIterator(
     auto read_opts = read_options;
     read_opts.iterate_lower_bound = lower_bound;
     read_opts.iterate_upper_bound = upper_bound;
-    auto it = transaction->transaction->GetIterator(
+    auto it = transaction->getIterator(
         read_options, transaction->datastorage
                           ->column_handles[DataStorage::DELAYEDMESSAGE_COLUMN]);
     return std::unique_ptr<rocksdb::Iterator>(it);
@@ -202,7 +202,7 @@ std::unique_ptr<rocksdb::Iterator> ReadTransaction::logGetIterator(
     auto read_opts = read_options;
     read_opts.iterate_lower_bound = lower_bound;
     read_opts.iterate_upper_bound = upper_bound;
-    auto it = transaction->transaction->GetIterator(
+    auto it = transaction->getIterator(
         read_opts,
         transaction->datastorage->column_handles[DataStorage::LOG_COLUMN]);
     return std::unique_ptr<rocksdb::Iterator>(it);
@@ -217,7 +217,7 @@ std::unique_ptr<rocksdb::Iterator> ReadTransaction::sendGetIterator(
     auto read_opts = read_options;
     read_opts.iterate_lower_bound = lower_bound;
     read_opts.iterate_upper_bound = upper_bound;
-    auto it = transaction->transaction->GetIterator(
+    auto it = transaction->getIterator(
         read_opts,
         transaction->datastorage->column_handles[DataStorage::SEND_COLUMN]);
     return std::unique_ptr<rocksdb::Iterator>(it);
@@ -228,7 +228,7 @@ std::unique_ptr<rocksdb::Iterator> ReadTransaction::sideloadGetIterator()
     // Make sure database isn't closed while it is being used
     auto counter = transaction->datastorage->tryLockShared();
 
-    auto it = transaction->transaction->GetIterator(
+    auto it = transaction->getIterator(
         read_options,
         transaction->datastorage->column_handles[DataStorage::SIDELOAD_COLUMN]);
     return std::unique_ptr<rocksdb::Iterator>(it);
@@ -239,7 +239,7 @@ std::unique_ptr<rocksdb::Iterator> ReadTransaction::aggregatorGetIterator()
     // Make sure database isn't closed while it is being used
     auto counter = transaction->datastorage->tryLockShared();
 
-    auto it = transaction->transaction->GetIterator(
+    auto it = transaction->getIterator(
         read_options, transaction->datastorage
                           ->column_handles[DataStorage::AGGREGATOR_COLUMN]);
     return std::unique_ptr<rocksdb::Iterator>(it);
@@ -250,7 +250,7 @@ std::unique_ptr<rocksdb::Iterator> ReadTransaction::refCountedGetIterator()
     // Make sure database isn't closed while it is being used
     auto counter = transaction->datastorage->tryLockShared();
 
-    auto it = transaction->transaction->GetIterator(
+    auto it = transaction->getIterator(
         read_options, transaction->datastorage
                           ->column_handles[DataStorage::REFCOUNTED_COLUMN]);
     return std::unique_ptr<rocksdb::Iterator>(it);
@@ -418,7 +418,7 @@ ReadTransaction::getVectorVectorUsingFamilyAndKey(
     auto counter = transaction->datastorage->tryLockShared();
 
     auto it = std::unique_ptr<rocksdb::Iterator>(
-        transaction->transaction->GetIterator(read_options, family));
+        transaction->getIterator(read_options, family));
 
     // Find first message
     it->Seek(vecToSlice(first_key_slice));
@@ -452,8 +452,8 @@ ReadTransaction::getVectorUsingFamilyAndKey(
 
     std::string returned_value;
 
-    auto status = transaction->transaction->Get(read_options, family, key_slice,
-                                                &returned_value);
+    auto status =
+        transaction->get(read_options, family, key_slice, &returned_value);
     if (!status.ok()) {
         return {status, {}};
     }
@@ -473,7 +473,7 @@ ReadTransaction::getUint256VectorUsingFamilyAndKey(
     auto counter = transaction->datastorage->tryLockShared();
 
     auto it = std::unique_ptr<rocksdb::Iterator>(
-        transaction->transaction->GetIterator(read_options, family));
+        transaction->getIterator(read_options, family));
 
     // Find first message
     it->Seek(vecToSlice(first_key_slice));
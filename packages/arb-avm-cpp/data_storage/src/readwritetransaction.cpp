@@ -26,7 +26,7 @@ rocksdb::Status ReadWriteTransaction::defaultPut(const rocksdb::Slice& key,
     // Make sure database isn't closed while it is being used
     auto counter = transaction->datastorage->tryLockShared();
 
-    return transaction->transaction->Put(
+    return transaction->put(
         transaction->datastorage->column_handles[DataStorage::DEFAULT_COLUMN],
         key, value);
 }
@@ -36,7 +36,7 @@ rocksdb::Status ReadWriteTransaction::statePut(const rocksdb::Slice& key,
     // Make sure database isn't closed while it is being used
     auto counter = transaction->datastorage->tryLockShared();
 
-    return transaction->transaction->Put(
+    return transaction->put(
         transaction->datastorage->column_handles[DataStorage::STATE_COLUMN],
         key, value);
 }
@@ -47,7 +47,7 @@ rocksdb::Status ReadWriteTransaction::checkpointPut(
     // Make sure database isn't closed while it is being used
     auto counter = transaction->datastorage->tryLockShared();
 
-    return transaction->transaction->Put(
+    return transaction->put(
         transaction->datastorage
             ->column_handles[DataStorage::CHECKPOINT_COLUMN],
         key, value);
@@ -58,7 +58,7 @@ rocksdb::Status ReadWriteTransaction::logPut(const rocksdb::Slice& key,
     // Make sure database isn't closed while it is being used
     auto counter = transaction->datastorage->tryLockShared();
 
-    return transaction->transaction->Put(
+    return transaction->put(
         transaction->datastorage->column_handles[DataStorage::LOG_COLUMN], key,
         value);
 }
@@ -68,7 +68,7 @@ rocksdb::Status ReadWriteTransaction::sendPut(const rocksdb::Slice& key,
     // Make sure database isn't closed while it is being used
     auto counter = transaction->datastorage->tryLockShared();
 
-    return transaction->transaction->Put(
+    return transaction->put(
         transaction->datastorage->column_handles[DataStorage::SEND_COLUMN], key,
         value);
 }
@@ -78,7 +78,7 @@ rocksdb::Status ReadWriteTransaction::sideloadPut(const rocksdb::Slice& key,
     // Make sure database isn't closed while it is being used
     auto counter = transaction->datastorage->tryLockShared();
 
-    return transaction->transaction->Put(
+    return transaction->put(
         transaction->datastorage->column_handles[DataStorage::SIDELOAD_COLUMN],
         key, value);
 }
@@ -89,7 +89,7 @@ rocksdb::Status ReadWriteTransaction::aggregatorPut(
     // Make sure database isn't closed while it is being used
     auto counter = transaction->datastorage->tryLockShared();
 
-    return transaction->transaction->Put(
+    return transaction->put(
         transaction->datastorage
             ->column_handles[DataStorage::AGGREGATOR_COLUMN],
         key, value);
@@ -101,7 +101,7 @@ rocksdb::Status ReadWriteTransaction::refCountedPut(
     // Make sure database isn't closed while it is being used
     auto counter = transaction->datastorage->tryLockShared();
 
-    return transaction->transaction->Put(
+    return transaction->put(
         transaction->datastorage
             ->column_handles[DataStorage::REFCOUNTED_COLUMN],
         key, value);
@@ -113,7 +113,7 @@ rocksdb::Status ReadWriteTransaction::sequencerBatchItemPut(
     // Make sure database isn't closed while it is being used
     auto counter = transaction->datastorage->tryLockShared();
 
-    return transaction->transaction->Put(
+    return transaction->put(
         transaction->datastorage
             ->column_handles[DataStorage::SEQUENCERBATCHITEM_COLUMN],
         key, value);
@@ -125,7 +125,7 @@ rocksdb::Status ReadWriteTransaction::delayedMessagePut(
     // Make sure database isn't closed while it is being used
     auto counter = transaction->datastorage->tryLockShared();
 
-    return transaction->transaction->Put(
+    return transaction->put(
         transaction->datastorage
             ->column_handles[DataStorage::DELAYEDMESSAGE_COLUMN],
         key, value);
@@ -135,7 +135,7 @@ rocksdb::Status ReadWriteTransaction::defaultDelete(const rocksdb::Slice& key) {
     // Make sure database isn't closed while it is being used
     auto counter = transaction->datastorage->tryLockShared();
 
-    return transaction->transaction->Delete(
+    return transaction->del(
         transaction->datastorage->column_handles[DataStorage::DEFAULT_COLUMN],
         key);
 }
@@ -144,7 +144,7 @@ rocksdb::Status ReadWriteTransaction::stateDelete(const rocksdb::Slice& key) {
     // Make sure database isn't closed while it is being used
     auto counter = transaction->datastorage->tryLockShared();
 
-    return transaction->transaction->Delete(
+    return transaction->del(
         transaction->datastorage->column_handles[DataStorage::STATE_COLUMN],
         key);
 }
@@ -153,7 +153,7 @@ rocksdb::Status ReadWriteTransaction::checkpointDelete(
     // Make sure database isn't closed while it is being used
     auto counter = transaction->datastorage->tryLockShared();
 
-    return transaction->transaction->Delete(
+    return transaction->del(
         transaction->datastorage
             ->column_handles[DataStorage::CHECKPOINT_COLUMN],
         key);
@@ -162,14 +162,14 @@ rocksdb::Status ReadWriteTransaction::logDelete(const rocksdb::Slice& key) {
     // Make sure database isn't closed while it is being used
     auto counter = transaction->datastorage->tryLockShared();
 
-    return transaction->transaction->Delete(
+    return transaction->del(
         transaction->datastorage->column_handles[DataStorage::LOG_COLUMN], key);
 }
 rocksdb::Status ReadWriteTransaction::sendDelete(const rocksdb::Slice& key) {
     // Make sure database isn't closed while it is being used
     auto counter = transaction->datastorage->tryLockShared();
 
-    return transaction->transaction->Delete(
+    return transaction->del(
         transaction->datastorage->column_handles[DataStorage::SEND_COLUMN],
         key);
 }
@@ -178,7 +178,7 @@ rocksdb::Status ReadWriteTransaction::sideloadDelete(
     // Make sure database isn't closed while it is being used
     auto counter = transaction->datastorage->tryLockShared();
 
-    return transaction->transaction->Delete(
+    return transaction->del(
         transaction->datastorage->column_handles[DataStorage::SIDELOAD_COLUMN],
         key);
 }
@@ -187,7 +187,7 @@ rocksdb::Status ReadWriteTransaction::aggregatorDelete(
     // Make sure database isn't closed while it is being used
     auto counter = transaction->datastorage->tryLockShared();
 
-    return transaction->transaction->Delete(
+    return transaction->del(
         transaction->datastorage
             ->column_handles[DataStorage::AGGREGATOR_COLUMN],
         key);
@@ -197,7 +197,7 @@ rocksdb::Status ReadWriteTransaction::refCountedDelete(
     // Make sure database isn't closed while it is being used
     auto counter = transaction->datastorage->tryLockShared();
 
-    return transaction->transaction->Delete(
+    return transaction->del(
         transaction->datastorage
             ->column_handles[DataStorage::REFCOUNTED_COLUMN],
         key);
@@ -207,7 +207,7 @@ rocksdb::Status ReadWriteTransaction::sequencerBatchItemDelete(
     // Make sure database isn't closed while it is being used
     auto counter = transaction->datastorage->tryLockShared();
 
-    return transaction->transaction->Delete(
+    return transaction->del(
         transaction->datastorage
             ->column_handles[DataStorage::SEQUENCERBATCHITEM_COLUMN],
         key);
@@ -217,7 +217,7 @@ rocksdb::Status ReadWriteTransaction::delayedMessageDelete(
     // Make sure database isn't closed while it is being used
     auto counter = transaction->datastorage->tryLockShared();
 
-    return transaction->transaction->Delete(
+    return transaction->del(
         transaction->datastorage
             ->column_handles[DataStorage::DELAYEDMESSAGE_COLUMN],
         key);
@@ -21,6 +21,8 @@ import (
 	"strings"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
 
 	"github.com/offchainlabs/arbitrum/packages/arb-evm/arboscontracts"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
@@ -71,6 +73,30 @@ func AddressTableLookupIndexData(index *big.Int) []byte {
 	return makeFuncData(addressTableLookupIndexABI, index)
 }
 
+func ParseAddressTableSizeResult(data []byte) (*big.Int, error) {
+	vals, err := addressTableSizeABI.Outputs.UnpackValues(data)
+	if err != nil {
+		return nil, err
+	}
+	val, ok := vals[0].(*big.Int)
+	if !ok {
+		return nil, errors.New("unexpected tx result")
+	}
+	return val, nil
+}
+
+func ParseAddressTableLookupIndexResult(data []byte) (common.Address, error) {
+	vals, err := addressTableLookupIndexABI.Outputs.UnpackValues(data)
+	if err != nil {
+		return common.Address{}, err
+	}
+	val, ok := vals[0].(ethcommon.Address)
+	if !ok {
+		return common.Address{}, errors.New("unexpected tx result")
+	}
+	return common.NewAddressFromEth(val), nil
+}
+
 func AddressTableDecompressData(buf []byte, offset *big.Int) []byte {
 	return makeFuncData(addressTableDecompressABI, buf, offset)
 }
@@ -37,6 +37,8 @@ var (
 
 	createRetryableTicketABI abi.Method
 	redeemABI                abi.Method
+	getTimeoutABI            abi.Method
+	getBeneficiaryABI        abi.Method
 )
 
 func init() {
@@ -53,6 +55,8 @@ func init() {
 	RetryCanceledEvent = parsedABI.Events["Canceled"]
 	RetryRedeemedEvent = parsedABI.Events["Redeemed"]
 	redeemABI = parsedABI.Methods["redeem"]
+	getTimeoutABI = parsedABI.Methods["getTimeout"]
+	getBeneficiaryABI = parsedABI.Methods["getBeneficiary"]
 	createRetryableTicketABI = creatorABI.Methods["createRetryableTicket"]
 }
 
@@ -77,6 +81,40 @@ func RedeemData(txId common.Hash) []byte {
 	return append(redeemABI.ID, txId[:]...)
 }
 
+func GetTimeoutData(txId common.Hash) []byte {
+	return append(getTimeoutABI.ID, txId[:]...)
+}
+
+// ParseGetTimeoutResult returns the timeout of a retryable, which is zero if
+// it doesn't exist
+func ParseGetTimeoutResult(data []byte) (*big.Int, error) {
+	vals, err := getTimeoutABI.Outputs.UnpackValues(data)
+	if err != nil {
+		return nil, err
+	}
+	val, ok := vals[0].(*big.Int)
+	if !ok {
+		return nil, errors.New("unexpected tx result")
+	}
+	return val, nil
+}
+
+func GetBeneficiaryData(txId common.Hash) []byte {
+	return append(getBeneficiaryABI.ID, txId[:]...)
+}
+
+func ParseGetBeneficiaryResult(data []byte) (common.Address, error) {
+	vals, err := getBeneficiaryABI.Outputs.UnpackValues(data)
+	if err != nil {
+		return common.Address{}, err
+	}
+	val, ok := vals[0].(ethcommon.Address)
+	if !ok {
+		return common.Address{}, errors.New("unexpected tx result")
+	}
+	return common.NewAddressFromEth(val), nil
+}
+
 func ParseCreateRetryableTicketTx(tx *types.Transaction) (*message.RetryableTx, error) {
 	if !bytes.Equal(tx.Data()[:4], createRetryableTicketABI.ID) {
 		return nil, errors.New("bad func id")
@@ -17,6 +17,7 @@
 package evm
 
 import (
+	"fmt"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common/math"
@@ -81,6 +82,45 @@ func (b *BlockInfo) GasLimit() *big.Int {
 	return limit
 }
 
+func compareOutputStatistics(kind string, stats1 *OutputStatistics, stats2 *OutputStatistics) []string {
+	var differences []string
+	compare := func(name string, val1 *big.Int, val2 *big.Int) {
+		if val1.Cmp(val2) != 0 {
+			differences = append(differences, fmt.Sprintf("different %v %v %v and %v", kind, name, val1, val2))
+		}
+	}
+	compare("gas used", stats1.GasUsed, stats2.GasUsed)
+	compare("tx count", stats1.TxCount, stats2.TxCount)
+	compare("evm log count", stats1.EVMLogCount, stats2.EVMLogCount)
+	compare("avm log count", stats1.AVMLogCount, stats2.AVMLogCount)
+	compare("avm send count", stats1.AVMSendCount, stats2.AVMSendCount)
+	return differences
+}
+
+func CompareBlockResults(block1 *BlockInfo, block2 *BlockInfo) []string {
+	var differences []string
+	compare := func(name string, val1 *big.Int, val2 *big.Int) {
+		if val1.Cmp(val2) != 0 {
+			differences = append(differences, fmt.Sprintf("different %v %v and %v", name, val1, val2))
+		}
+	}
+	compare("block number", block1.BlockNum, block2.BlockNum)
+	compare("timestamp", block1.Timestamp, block2.Timestamp)
+	compare("previous height", block1.PreviousHeight, block2.PreviousHeight)
+	compare("l1 block number", block1.L1BlockNum, block2.L1BlockNum)
+	differences = append(differences, compareOutputStatistics("block", block1.BlockStats, block2.BlockStats)...)
+	differences = append(differences, compareOutputStatistics("chain", block1.ChainStats, block2.ChainStats)...)
+	gas1 := block1.GasSummary
+	gas2 := block2.GasSummary
+	compare("price per l1 calldata byte", gas1.PricePerL1CalldataByte, gas2.PricePerL1CalldataByte)
+	compare("price per storage cell", gas1.PricePerStorageCell, gas2.PricePerStorageCell)
+	compare("base arbgas price", gas1.PricePerArbGasBase, gas2.PricePerArbGasBase)
+	compare("congestion arbgas price", gas1.PricePerArbGasCongestion, gas2.PricePerArbGasCongestion)
+	compare("total arbgas price", gas1.PricePerArbGasTotal, gas2.PricePerArbGasTotal)
+	compare("gas pool", gas1.GasPool, gas2.GasPool)
+	return differences
+}
+
 func parseBlockResult(
 	blockNum value.Value,
 	timestamp value.Value,
@@ -0,0 +1,95 @@
+/*
+ * Copyright 2022, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package evm
+
+import (
+	"math/big"
+	"testing"
+)
+
+func newTestBlockInfo(txGasUsed int64) *BlockInfo {
+	stats := func(gasUsed int64) *OutputStatistics {
+		return &OutputStatistics{
+			GasUsed:      big.NewInt(gasUsed),
+			TxCount:      big.NewInt(1),
+			EVMLogCount:  big.NewInt(2),
+			AVMLogCount:  big.NewInt(3),
+			AVMSendCount: big.NewInt(0),
+		}
+	}
+	return &BlockInfo{
+		BlockNum:   big.NewInt(7),
+		Timestamp:  big.NewInt(1000),
+		BlockStats: stats(txGasUsed),
+		ChainStats: stats(txGasUsed + 5000),
+		GasSummary: &GasAccountingSummary{
+			PricePerL1CalldataByte:   big.NewInt(16),
+			PricePerStorageCell:      big.NewInt(20000),
+			PricePerArbGasBase:       big.NewInt(1),
+			PricePerArbGasCongestion: big.NewInt(0),
+			PricePerArbGasTotal:      big.NewInt(1),
+			GasPool:                  big.NewInt(100000),
+		},
+		PreviousHeight: big.NewInt(6),
+		L1BlockNum:     big.NewInt(3),
+	}
+}
+
+func TestCompareBlockResults(t *testing.T) {
+	if differences := CompareBlockResults(newTestBlockInfo(400), newTestBlockInfo(400)); len(differences) != 0 {
+		t.Error("unexpected differences", differences)
+	}
+
+	// A block whose receipt used more gas shows up in both the block and
+	// chain totals
+	differences := CompareBlockResults(newTestBlockInfo(400), newTestBlockInfo(450))
+	expected := []string{
+		"different block gas used 400 and 450",
+		"different chain gas used 5400 and 5450",
+	}
+	if len(differences) != len(expected) {
+		t.Fatal("wrong differences", differences)
+	}
+	for i := range expected {
+		if differences[i] != expected[i] {
+			t.Error("expected", expected[i], "but got", differences[i])
+		}
+	}
+
+	repriced := newTestBlockInfo(400)
+	repriced.GasSummary.PricePerArbGasTotal = big.NewInt(2)
+	differences = CompareBlockResults(newTestBlockInfo(400), repriced)
+	if len(differences) != 1 || differences[0] != "different total arbgas price 1 and 2" {
+		t.Error("wrong differences", differences)
+	}
+}
+
+func TestCompareDivergentReceipt(t *testing.T) {
+	res := NewRandomResult(2)
+	same := *res
+	if differences := CompareResults(res, &same); len(differences) != 0 {
+		t.Error("unexpected differences", differences)
+	}
+
+	divergent := *res
+	divergent.GasUsed = new(big.Int).Add(res.GasUsed, big.NewInt(1))
+	divergent.EVMLogs = res.EVMLogs[:1]
+	differences := CompareResults(res, &divergent)
+	if len(differences) != 2 {
+		t.Error("wrong differences", differences)
+	}
+}
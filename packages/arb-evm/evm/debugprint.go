@@ -237,6 +237,14 @@ type EVMOpcodeLog struct {
 	op uint64
 }
 
+func (r *EVMOpcodeLog) PC() uint64 {
+	return r.pc
+}
+
+func (r *EVMOpcodeLog) Op() uint64 {
+	return r.op
+}
+
 func (r *EVMOpcodeLog) String() string {
 	return fmt.Sprintf("EVMOpcodeLog{0x%x, %x}", r.pc, r.op)
 }
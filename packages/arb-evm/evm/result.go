@@ -207,7 +207,7 @@ func CompareResults(res1 *TxResult, res2 *TxResult) []string {
 		differences = append(differences, fmt.Sprintf("different return data 0x%X and 0x%X", res1.ReturnData, res2.ReturnData))
 	}
 	if len(res1.EVMLogs) != len(res2.EVMLogs) {
-
+		differences = append(differences, fmt.Sprintf("different log counts %v and %v", len(res1.EVMLogs), len(res2.EVMLogs)))
 	} else {
 		for i, log1 := range res1.EVMLogs {
 			log2 := res2.EVMLogs[i]
@@ -0,0 +1,232 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/arblog"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+)
+
+var logger = arblog.Logger.With().Str("component", "alerts").Logger()
+
+type Kind string
+
+const (
+	InvalidNodeKind       Kind = "invalid-node"
+	ChallengeStartedKind  Kind = "challenge-started"
+	ChallengeDeadlineKind Kind = "challenge-deadline"
+	StakeSlashedKind      Kind = "stake-slashed"
+	StakeConfirmedKind    Kind = "stake-confirmed"
+	LowBalanceKind        Kind = "low-balance"
+)
+
+type Severity string
+
+const (
+	InfoSeverity     Severity = "info"
+	WarningSeverity  Severity = "warning"
+	CriticalSeverity Severity = "critical"
+)
+
+// Alert is the JSON payload delivered to every sink
+type Alert struct {
+	Kind      Kind                   `json:"kind"`
+	Severity  Severity               `json:"severity"`
+	Time      time.Time              `json:"time"`
+	Rollup    ethcommon.Address      `json:"rollup"`
+	Validator ethcommon.Address      `json:"validator"`
+	Message   string                 `json:"message"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+type Sink interface {
+	Send(ctx context.Context, alert *Alert) error
+}
+
+// WebhookSink POSTs each alert as a JSON document to a fixed URL
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookSink(url string, timeout time.Duration) *WebhookSink {
+	return &WebhookSink{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (w *WebhookSink) Send(ctx context.Context, alert *Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", w.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "error posting alert to %v", w.url)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP status '%v' when posting alert to %v", resp.Status, w.url)
+	}
+	return nil
+}
+
+// FileSink appends each alert as a line of JSON to a local file, which is
+// mostly useful for testing alerting setups
+type FileSink struct {
+	mutex    sync.Mutex
+	filename string
+}
+
+func NewFileSink(filename string) *FileSink {
+	return &FileSink{filename: filename}
+}
+
+func (f *FileSink) Send(_ context.Context, alert *Alert) error {
+	line, err := json.Marshal(alert)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	line = append(line, '\n')
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	file, err := os.OpenFile(f.filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer file.Close()
+	_, err = file.Write(line)
+	return errors.WithStack(err)
+}
+
+// alertQueueSize is how many alerts can wait for delivery before new alerts
+// are dropped
+const alertQueueSize = 64
+
+// Alerter fills in the common alert fields and fans alerts out to all
+// configured sinks. Alerts are delivered by a background goroutine and sink
+// failures are logged rather than returned so that alerting can never
+// interfere with staking.
+type Alerter struct {
+	sinks     []Sink
+	rollup    common.Address
+	validator common.Address
+
+	queue chan *Alert
+	done  chan struct{}
+}
+
+func NewAlerter(rollup common.Address, validator common.Address, sinks ...Sink) *Alerter {
+	return newAlerter(alertQueueSize, rollup, validator, sinks...)
+}
+
+func newAlerter(queueSize int, rollup common.Address, validator common.Address, sinks ...Sink) *Alerter {
+	a := &Alerter{
+		sinks:     sinks,
+		rollup:    rollup,
+		validator: validator,
+		queue:     make(chan *Alert, queueSize),
+		done:      make(chan struct{}),
+	}
+	if len(sinks) > 0 {
+		go a.deliver()
+	} else {
+		close(a.done)
+	}
+	return a
+}
+
+func NewAlerterFromConfig(config configuration.ValidatorAlerts, rollup common.Address, validator common.Address) *Alerter {
+	var sinks []Sink
+	for _, url := range config.Webhooks {
+		if len(url) == 0 {
+			continue
+		}
+		sinks = append(sinks, NewWebhookSink(url, config.Timeout))
+	}
+	if len(config.File) != 0 {
+		sinks = append(sinks, NewFileSink(config.File))
+	}
+	return NewAlerter(rollup, validator, sinks...)
+}
+
+func (a *Alerter) Enabled() bool {
+	return a != nil && len(a.sinks) > 0
+}
+
+// Fire queues an alert for delivery without waiting for the sinks, dropping
+// it if too many alerts are already queued
+func (a *Alerter) Fire(kind Kind, severity Severity, message string, details map[string]interface{}) {
+	if !a.Enabled() {
+		return
+	}
+	alert := &Alert{
+		Kind:      kind,
+		Severity:  severity,
+		Time:      time.Now().UTC(),
+		Rollup:    a.rollup.ToEthAddress(),
+		Validator: a.validator.ToEthAddress(),
+		Message:   message,
+		Details:   details,
+	}
+	select {
+	case a.queue <- alert:
+	default:
+		logger.Error().Str("kind", string(kind)).Str("message", message).Msg("alert queue full, dropping alert")
+	}
+}
+
+// Close waits for queued alerts to be delivered. Fire must not be called
+// afterwards.
+func (a *Alerter) Close() {
+	if !a.Enabled() {
+		return
+	}
+	close(a.queue)
+	<-a.done
+}
+
+func (a *Alerter) deliver() {
+	defer close(a.done)
+	for alert := range a.queue {
+		for _, sink := range a.sinks {
+			if err := sink.Send(context.Background(), alert); err != nil {
+				logger.Warn().Err(err).Str("kind", string(alert.Kind)).Msg("failed to deliver alert")
+			}
+		}
+	}
+}
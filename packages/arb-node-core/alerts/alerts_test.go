@@ -0,0 +1,155 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package alerts
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+)
+
+func TestAlerterSinks(t *testing.T) {
+	received := make(chan Alert, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert Alert
+		if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- alert
+	}))
+	defer server.Close()
+
+	alertsFile := filepath.Join(t.TempDir(), "alerts.json")
+	rollup := common.RandAddress()
+	validator := common.RandAddress()
+	alerter := NewAlerterFromConfig(configuration.ValidatorAlerts{
+		Webhooks: []string{server.URL},
+		File:     alertsFile,
+		Timeout:  time.Second,
+	}, rollup, validator)
+	if !alerter.Enabled() {
+		t.Fatal("alerter should be enabled")
+	}
+
+	alerter.Fire(InvalidNodeKind, CriticalSeverity, "bad node", map[string]interface{}{"node": "5"})
+	alerter.Close()
+
+	select {
+	case alert := <-received:
+		if alert.Kind != InvalidNodeKind || alert.Rollup != rollup.ToEthAddress() || alert.Validator != validator.ToEthAddress() {
+			t.Error("webhook received wrong alert", alert)
+		}
+		if alert.Details["node"] != "5" {
+			t.Error("webhook alert missing details", alert.Details)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook didn't receive alert")
+	}
+
+	file, err := os.Open(alertsFile)
+	test.FailIfError(t, err)
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	lines := 0
+	for scanner.Scan() {
+		var alert Alert
+		test.FailIfError(t, json.Unmarshal(scanner.Bytes(), &alert))
+		if alert.Kind != InvalidNodeKind || alert.Message != "bad node" {
+			t.Error("file received wrong alert", alert)
+		}
+		lines++
+	}
+	if lines != 1 {
+		t.Error("expected one alert in file but got", lines)
+	}
+}
+
+func TestWebhookSinkErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(server.URL, time.Second)
+	if err := sink.Send(context.Background(), &Alert{Kind: LowBalanceKind}); err == nil {
+		t.Error("expected error from failing webhook")
+	}
+}
+
+func TestDisabledAlerter(t *testing.T) {
+	alerter := NewAlerterFromConfig(configuration.ValidatorAlerts{}, common.Address{}, common.Address{})
+	if alerter.Enabled() {
+		t.Error("alerter without sinks shouldn't be enabled")
+	}
+	var nilAlerter *Alerter
+	// Firing on a nil or disabled alerter must be a no-op
+	nilAlerter.Fire(LowBalanceKind, WarningSeverity, "", nil)
+	alerter.Fire(LowBalanceKind, WarningSeverity, "", nil)
+	alerter.Close()
+}
+
+type blockingSink struct {
+	release chan struct{}
+	mutex   sync.Mutex
+	count   int
+}
+
+func (s *blockingSink) Send(ctx context.Context, alert *Alert) error {
+	<-s.release
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.count++
+	return nil
+}
+
+func TestFireDoesNotBlock(t *testing.T) {
+	sink := &blockingSink{release: make(chan struct{})}
+	alerter := newAlerter(1, common.Address{}, common.Address{}, sink)
+
+	fired := make(chan struct{})
+	go func() {
+		// The first alert blocks delivery, the second is queued and the rest
+		// are dropped
+		for i := 0; i < 5; i++ {
+			alerter.Fire(LowBalanceKind, WarningSeverity, "", nil)
+		}
+		close(fired)
+	}()
+	select {
+	case <-fired:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Fire blocked on slow sink")
+	}
+
+	close(sink.release)
+	alerter.Close()
+	if sink.count < 1 || sink.count > 2 {
+		t.Error("unexpected number of alerts delivered", sink.count)
+	}
+}
@@ -0,0 +1,460 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auditor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/ethbridge"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/ethbridgecontracts"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/arblog"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethutils"
+)
+
+var logger = arblog.Logger.With().Str("component", "auditor").Logger()
+
+const (
+	UnresolvedStatus = "unresolved"
+	ConfirmedStatus  = "confirmed"
+	RejectedStatus   = "rejected"
+
+	UnknownValidity = "unknown"
+	ValidValidity   = "valid"
+	InvalidValidity = "invalid"
+)
+
+type ChallengeRecord struct {
+	Challenge  ethcommon.Address `json:"challenge"`
+	Asserter   ethcommon.Address `json:"asserter"`
+	Challenger ethcommon.Address `json:"challenger"`
+	Block      uint64            `json:"block"`
+}
+
+// NodeRecord is our view of a single rollup node. Creator is the address
+// that sent the node creation to the rollup, which is the smart contract
+// wallet for validators that use one.
+type NodeRecord struct {
+	Node          uint64            `json:"node"`
+	Hash          ethcommon.Hash    `json:"hash"`
+	Parent        uint64            `json:"parent"`
+	ProposedBlock uint64            `json:"proposedBlock"`
+	CreationTx    ethcommon.Hash    `json:"creationTx"`
+	Creator       ethcommon.Address `json:"creator"`
+	Status        string            `json:"status"`
+	Validity      string            `json:"validity"`
+	Challenges    []ChallengeRecord `json:"challenges"`
+	// Error is set if the assertion couldn't be checked even though the
+	// local ArbCore has caught up to it
+	Error string `json:"error,omitempty"`
+}
+
+// State is persisted after every update so that audits can be resumed
+type State struct {
+	Rollup             ethcommon.Address `json:"rollup"`
+	NextChallengeBlock uint64            `json:"nextChallengeBlock"`
+	Nodes              []*NodeRecord     `json:"nodes"`
+}
+
+// rollupWatcher is the part of ethbridge.RollupWatcher used by the auditor
+type rollupWatcher interface {
+	LatestConfirmedNode(ctx context.Context) (*big.Int, error)
+	FirstUnresolvedNode(ctx context.Context) (*big.Int, error)
+	NodeHash(ctx context.Context, node core.NodeID) (common.Hash, error)
+	LookupCreation(ctx context.Context) (*ethbridgecontracts.RollupUserFacetRollupCreated, error)
+	LookupNode(ctx context.Context, number *big.Int) (*core.NodeInfo, error)
+	LookupNodeChildren(ctx context.Context, parentHash [32]byte, fromBlock *big.Int) ([]*core.NodeInfo, error)
+	LookupChallenges(ctx context.Context, fromBlock *big.Int) ([]*ethbridgecontracts.RollupUserFacetRollupChallengeStarted, error)
+}
+
+// Auditor independently tracks every node created on a rollup and checks
+// each assertion against the local ArbCore. It never sends transactions.
+type Auditor struct {
+	rollup        rollupWatcher
+	rollupAddress common.Address
+	client        ethutils.EthClient
+	lookup        core.ArbCoreLookup
+	filename      string
+
+	nextChallengeBlock uint64
+	nodes              map[uint64]*NodeRecord
+	// Nodes looked up during this run, kept to avoid repeated log queries
+	infos map[uint64]*core.NodeInfo
+}
+
+func NewAuditor(
+	rollup *ethbridge.RollupWatcher,
+	rollupAddress common.Address,
+	client ethutils.EthClient,
+	lookup core.ArbCoreLookup,
+	filename string,
+) (*Auditor, error) {
+	a := &Auditor{
+		rollup:        rollup,
+		rollupAddress: rollupAddress,
+		client:        client,
+		lookup:        lookup,
+		filename:      filename,
+		nodes:         make(map[uint64]*NodeRecord),
+		infos:         make(map[uint64]*core.NodeInfo),
+	}
+	state, err := LoadState(filename)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return a, nil
+	}
+	if state.Rollup != rollupAddress.ToEthAddress() {
+		return nil, errors.Errorf("audit file %v is for rollup %v", filename, state.Rollup.Hex())
+	}
+	a.nextChallengeBlock = state.NextChallengeBlock
+	for _, node := range state.Nodes {
+		a.nodes[node.Node] = node
+	}
+	return a, nil
+}
+
+// LoadState reads a saved audit, returning nil if there isn't one yet
+func LoadState(filename string) (*State, error) {
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	state := &State{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, errors.Wrapf(err, "error reading audit from %v", filename)
+	}
+	return state, nil
+}
+
+func (a *Auditor) save() error {
+	state := &State{
+		Rollup:             a.rollupAddress.ToEthAddress(),
+		NextChallengeBlock: a.nextChallengeBlock,
+		Nodes:              a.Nodes(),
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	// Write then rename so that an interrupted save never loses the audit
+	tmpFilename := a.filename + ".tmp"
+	if err := ioutil.WriteFile(tmpFilename, data, 0644); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.Rename(tmpFilename, a.filename))
+}
+
+// Nodes returns all known nodes ordered by node number
+func (a *Auditor) Nodes() []*NodeRecord {
+	nodes := make([]*NodeRecord, 0, len(a.nodes))
+	for _, node := range a.nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Node < nodes[j].Node
+	})
+	return nodes
+}
+
+// Update discovers new nodes and challenges, refreshes node statuses, checks
+// any assertions we haven't been able to evaluate yet, and saves the audit
+func (a *Auditor) Update(ctx context.Context) error {
+	if len(a.nodes) == 0 {
+		if err := a.addGenesis(ctx); err != nil {
+			return err
+		}
+	}
+	latestConfirmed, err := a.rollup.LatestConfirmedNode(ctx)
+	if err != nil {
+		return err
+	}
+	if err := a.discoverNodes(ctx, latestConfirmed.Uint64()); err != nil {
+		return err
+	}
+	if err := a.updateChallenges(ctx); err != nil {
+		return err
+	}
+	firstUnresolved, err := a.rollup.FirstUnresolvedNode(ctx)
+	if err != nil {
+		return err
+	}
+	resolveStatuses(a.nodes, latestConfirmed.Uint64(), firstUnresolved.Uint64())
+	if err := a.checkValidity(ctx); err != nil {
+		return err
+	}
+	a.logAnomalies()
+	return a.save()
+}
+
+func (a *Auditor) addGenesis(ctx context.Context) error {
+	hash, err := a.rollup.NodeHash(ctx, big.NewInt(0))
+	if err != nil {
+		return err
+	}
+	creation, err := a.rollup.LookupCreation(ctx)
+	if err != nil {
+		return err
+	}
+	a.nodes[0] = &NodeRecord{
+		Node:          0,
+		Hash:          hash.ToEthHash(),
+		ProposedBlock: creation.Raw.BlockNumber,
+		CreationTx:    creation.Raw.TxHash,
+		Status:        ConfirmedStatus,
+		// The initial node is defined by the rollup parameters
+		Validity: ValidValidity,
+	}
+	a.nextChallengeBlock = creation.Raw.BlockNumber
+	return nil
+}
+
+// discoverNodes walks the node tree from every node which could still gain
+// children, as well as any node found along the way. If the latest confirmed
+// node isn't known yet, as on the first run against a rollup that has already
+// confirmed nodes, the whole tree is walked from the known nodes.
+func (a *Auditor) discoverNodes(ctx context.Context, latestConfirmed uint64) error {
+	_, knowLatestConfirmed := a.nodes[latestConfirmed]
+	var queue []*NodeRecord
+	for _, node := range a.Nodes() {
+		if !knowLatestConfirmed || node.Status == UnresolvedStatus || node.Node == latestConfirmed {
+			queue = append(queue, node)
+		}
+	}
+	for len(queue) > 0 {
+		parent := queue[0]
+		queue = queue[1:]
+		children, err := a.rollup.LookupNodeChildren(ctx, parent.Hash, new(big.Int).SetUint64(parent.ProposedBlock))
+		if err != nil {
+			return err
+		}
+		for _, child := range children {
+			nodeNum := (*big.Int)(child.NodeNum).Uint64()
+			if _, ok := a.nodes[nodeNum]; ok {
+				continue
+			}
+			creator, err := a.lookupCreator(ctx, child.CreationTxHash.ToEthHash())
+			if err != nil {
+				return err
+			}
+			record := &NodeRecord{
+				Node:          nodeNum,
+				Hash:          child.NodeHash.ToEthHash(),
+				Parent:        parent.Node,
+				ProposedBlock: child.BlockProposed.Height.AsInt().Uint64(),
+				CreationTx:    child.CreationTxHash.ToEthHash(),
+				Creator:       creator,
+				Status:        UnresolvedStatus,
+				Validity:      UnknownValidity,
+			}
+			logger.Info().Uint64("node", nodeNum).Uint64("parent", parent.Node).Str("creator", creator.Hex()).Msg("found new node")
+			a.nodes[nodeNum] = record
+			a.infos[nodeNum] = child
+			queue = append(queue, record)
+		}
+	}
+	return nil
+}
+
+func (a *Auditor) lookupCreator(ctx context.Context, txHash ethcommon.Hash) (ethcommon.Address, error) {
+	tx, _, err := a.client.TransactionByHash(ctx, txHash)
+	if err != nil {
+		return ethcommon.Address{}, errors.Wrapf(err, "error looking up node creation transaction %v", txHash.Hex())
+	}
+	if tx.To() != nil && *tx.To() != a.rollupAddress.ToEthAddress() {
+		// Sent through a smart contract wallet
+		return *tx.To(), nil
+	}
+	sender, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		logger.Warn().Err(err).Str("tx", txHash.Hex()).Msg("couldn't recover sender of node creation")
+		return ethcommon.Address{}, nil
+	}
+	return sender, nil
+}
+
+func (a *Auditor) updateChallenges(ctx context.Context) error {
+	challenges, err := a.rollup.LookupChallenges(ctx, new(big.Int).SetUint64(a.nextChallengeBlock))
+	if err != nil {
+		return err
+	}
+	for _, challenge := range challenges {
+		node, ok := a.nodes[challenge.ChallengedNode.Uint64()]
+		if !ok {
+			return errors.Errorf("challenge %v is for unknown node %v", challenge.ChallengeContract.Hex(), challenge.ChallengedNode)
+		}
+		// The last block is scanned again next time, so skip duplicates
+		duplicate := false
+		for _, existing := range node.Challenges {
+			if existing.Challenge == challenge.ChallengeContract {
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			continue
+		}
+		node.Challenges = append(node.Challenges, ChallengeRecord{
+			Challenge:  challenge.ChallengeContract,
+			Asserter:   challenge.Asserter,
+			Challenger: challenge.Challenger,
+			Block:      challenge.Raw.BlockNumber,
+		})
+		if challenge.Raw.BlockNumber > a.nextChallengeBlock {
+			a.nextChallengeBlock = challenge.Raw.BlockNumber
+		}
+	}
+	return nil
+}
+
+// resolveStatuses marks nodes before the first unresolved node as confirmed
+// if they are an ancestor of the latest confirmed node and rejected otherwise
+func resolveStatuses(nodes map[uint64]*NodeRecord, latestConfirmed uint64, firstUnresolved uint64) {
+	confirmed := make(map[uint64]bool)
+	for nodeNum := latestConfirmed; ; {
+		confirmed[nodeNum] = true
+		node, ok := nodes[nodeNum]
+		if !ok || nodeNum == 0 {
+			break
+		}
+		nodeNum = node.Parent
+	}
+	for _, node := range nodes {
+		if node.Node >= firstUnresolved {
+			node.Status = UnresolvedStatus
+		} else if confirmed[node.Node] {
+			node.Status = ConfirmedStatus
+		} else {
+			node.Status = RejectedStatus
+		}
+	}
+}
+
+// checkValidity evaluates every node we haven't been able to yet. Nodes the
+// local ArbCore hasn't caught up to are left for a later update, while a
+// failed check is recorded on the node without stopping the others.
+func (a *Auditor) checkValidity(ctx context.Context) error {
+	messageCount, err := a.lookup.GetMessageCount()
+	if err != nil {
+		return err
+	}
+	totalGas, err := a.lookup.GetLastMachineTotalGas()
+	if err != nil {
+		return err
+	}
+	for _, node := range a.Nodes() {
+		if node.Validity != UnknownValidity {
+			continue
+		}
+		info, ok := a.infos[node.Node]
+		if !ok {
+			var err error
+			info, err = a.rollup.LookupNode(ctx, new(big.Int).SetUint64(node.Node))
+			if err != nil {
+				return err
+			}
+			a.infos[node.Node] = info
+		}
+		if !caughtUp(info, messageCount, totalGas) {
+			logger.Debug().Uint64("node", node.Node).Msg("can't evaluate node until caught up")
+			continue
+		}
+		execTracker := core.NewExecutionTracker(a.lookup, false, []*big.Int{info.Assertion.After.TotalGasConsumed}, false)
+		valid, err := core.IsNodeValid(info, execTracker)
+		if err != nil {
+			logger.Error().Err(err).Uint64("node", node.Node).Msg("failed to evaluate node")
+			node.Error = err.Error()
+			continue
+		}
+		node.Error = ""
+		if valid {
+			node.Validity = ValidValidity
+		} else {
+			node.Validity = InvalidValidity
+		}
+	}
+	return nil
+}
+
+// caughtUp returns whether ArbCore has read every message and executed all
+// the gas the node's assertion needs
+func caughtUp(info *core.NodeInfo, messageCount *big.Int, totalGas *big.Int) bool {
+	after := info.Assertion.After
+	return after.TotalMessagesRead.Cmp(messageCount) <= 0 &&
+		info.AfterInboxBatchEndCount.Cmp(messageCount) <= 0 &&
+		after.TotalGasConsumed.Cmp(totalGas) <= 0
+}
+
+func (a *Auditor) logAnomalies() {
+	for _, node := range a.Nodes() {
+		if node.Validity == InvalidValidity && node.Status != RejectedStatus {
+			logger.Error().Uint64("node", node.Node).Str("status", node.Status).Str("creator", node.Creator.Hex()).Msg("invalid node hasn't been rejected")
+		} else if node.Validity == ValidValidity && node.Status == RejectedStatus {
+			logger.Error().Uint64("node", node.Node).Str("creator", node.Creator.Hex()).Msg("valid node was rejected")
+		}
+	}
+}
+
+// WriteTable prints nodes as an aligned text table
+func WriteTable(w io.Writer, nodes []*NodeRecord) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	if _, err := fmt.Fprintln(tw, "NODE\tPARENT\tBLOCK\tCREATOR\tSTATUS\tVALIDITY\tCHALLENGES"); err != nil {
+		return errors.WithStack(err)
+	}
+	for _, node := range nodes {
+		challenges := "-"
+		for i, challenge := range node.Challenges {
+			if i == 0 {
+				challenges = challenge.Challenge.Hex()
+			} else {
+				challenges += "," + challenge.Challenge.Hex()
+			}
+		}
+		_, err := fmt.Fprintf(
+			tw,
+			"%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+			node.Node,
+			node.Parent,
+			node.ProposedBlock,
+			node.Creator.Hex(),
+			node.Status,
+			node.Validity,
+			challenges,
+		)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return errors.WithStack(tw.Flush())
+}
@@ -0,0 +1,250 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auditor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethutils"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+)
+
+// Nodes 1 and 2 extend the chain from genesis, 3 is a sibling of 2, and 4
+// and 5 are both children of 2
+func testNodes() map[uint64]*NodeRecord {
+	parents := map[uint64]uint64{1: 0, 2: 1, 3: 1, 4: 2, 5: 2}
+	nodes := map[uint64]*NodeRecord{
+		0: {Node: 0, Status: ConfirmedStatus, Validity: ValidValidity},
+	}
+	for node, parent := range parents {
+		nodes[node] = &NodeRecord{Node: node, Parent: parent, Status: UnresolvedStatus, Validity: UnknownValidity}
+	}
+	return nodes
+}
+
+func TestResolveStatuses(t *testing.T) {
+	nodes := testNodes()
+	resolveStatuses(nodes, 2, 4)
+	expected := map[uint64]string{
+		0: ConfirmedStatus,
+		1: ConfirmedStatus,
+		2: ConfirmedStatus,
+		3: RejectedStatus,
+		4: UnresolvedStatus,
+		5: UnresolvedStatus,
+	}
+	for node, status := range expected {
+		if nodes[node].Status != status {
+			t.Error("node", node, "has status", nodes[node].Status, "but expected", status)
+		}
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "audit.json")
+	rollup := common.RandAddress()
+	a := &Auditor{
+		rollupAddress:      rollup,
+		filename:           filename,
+		nextChallengeBlock: 7,
+		nodes:              testNodes(),
+	}
+	challenge := common.RandAddress().ToEthAddress()
+	a.nodes[3].Challenges = []ChallengeRecord{{Challenge: challenge, Block: 7}}
+	test.FailIfError(t, a.save())
+
+	state, err := LoadState(filename)
+	test.FailIfError(t, err)
+	if state.Rollup != rollup.ToEthAddress() || state.NextChallengeBlock != 7 {
+		t.Error("wrong audit state", state.Rollup, state.NextChallengeBlock)
+	}
+	if len(state.Nodes) != 6 {
+		t.Fatal("expected 6 nodes but got", len(state.Nodes))
+	}
+	for i, node := range state.Nodes {
+		if node.Node != uint64(i) {
+			t.Error("nodes out of order")
+		}
+	}
+	if len(state.Nodes[3].Challenges) != 1 || state.Nodes[3].Challenges[0].Challenge != challenge {
+		t.Error("challenge not saved")
+	}
+
+	var table bytes.Buffer
+	test.FailIfError(t, WriteTable(&table, state.Nodes))
+	lines := strings.Split(strings.TrimSpace(table.String()), "\n")
+	if len(lines) != 7 {
+		t.Error("expected header and 6 rows but got", len(lines))
+	}
+	if !strings.Contains(lines[4], challenge.Hex()) {
+		t.Error("challenge missing from table row", lines[4])
+	}
+}
+
+func TestMissingState(t *testing.T) {
+	state, err := LoadState(filepath.Join(t.TempDir(), "audit.json"))
+	test.FailIfError(t, err)
+	if state != nil {
+		t.Error("expected no state")
+	}
+}
+
+// nodeTree serves node creation events for the tree from testNodes
+type nodeTree struct {
+	rollupWatcher
+	parents map[uint64]uint64
+}
+
+func nodeHash(node uint64) common.Hash {
+	return common.Hash{byte(node + 1)}
+}
+
+func (w *nodeTree) LookupNodeChildren(_ context.Context, parentHash [32]byte, _ *big.Int) ([]*core.NodeInfo, error) {
+	var children []*core.NodeInfo
+	for node := uint64(1); node <= uint64(len(w.parents)); node++ {
+		if nodeHash(w.parents[node]) != parentHash {
+			continue
+		}
+		children = append(children, &core.NodeInfo{
+			NodeNum:        new(big.Int).SetUint64(node),
+			BlockProposed:  &common.BlockId{Height: common.NewTimeBlocks(new(big.Int).SetUint64(10 + node))},
+			NodeHash:       nodeHash(node),
+			CreationTxHash: common.Hash{byte(node)},
+		})
+	}
+	return children, nil
+}
+
+// creatorClient reports every node as created through the same wallet
+type creatorClient struct {
+	ethutils.EthClient
+	wallet ethcommon.Address
+}
+
+func (c *creatorClient) TransactionByHash(context.Context, ethcommon.Hash) (*types.Transaction, bool, error) {
+	return types.NewTransaction(0, c.wallet, big.NewInt(0), 0, big.NewInt(0), nil), false, nil
+}
+
+func newDiscoveryAuditor() *Auditor {
+	parents := map[uint64]uint64{1: 0, 2: 1, 3: 1, 4: 2, 5: 2}
+	return &Auditor{
+		rollup:        &nodeTree{parents: parents},
+		rollupAddress: common.RandAddress(),
+		client:        &creatorClient{wallet: common.RandAddress().ToEthAddress()},
+		nodes: map[uint64]*NodeRecord{
+			0: {Node: 0, Hash: nodeHash(0).ToEthHash(), Status: ConfirmedStatus, Validity: ValidValidity},
+		},
+		infos: make(map[uint64]*core.NodeInfo),
+	}
+}
+
+func TestDiscoverNodes(t *testing.T) {
+	ctx := context.Background()
+	expected := testNodes()
+
+	// A new audit of a rollup that has already confirmed node 2
+	a := newDiscoveryAuditor()
+	test.FailIfError(t, a.discoverNodes(ctx, 2))
+	if len(a.nodes) != len(expected) {
+		t.Fatal("expected", len(expected), "nodes but found", len(a.nodes))
+	}
+	for node, record := range expected {
+		found := a.nodes[node]
+		if found.Parent != record.Parent || found.Hash != nodeHash(node).ToEthHash() {
+			t.Error("node", node, "has parent", found.Parent, "and hash", found.Hash)
+		}
+		if node > 0 && (found.ProposedBlock != 10+node || found.Creator != a.client.(*creatorClient).wallet) {
+			t.Error("node", node, "proposed in block", found.ProposedBlock, "by", found.Creator)
+		}
+	}
+	resolveStatuses(a.nodes, 2, 4)
+	if a.nodes[3].Status != RejectedStatus || a.nodes[4].Status != UnresolvedStatus {
+		t.Error("wrong statuses after discovery")
+	}
+
+	// A resumed audit only looks for children of unresolved nodes and the
+	// latest confirmed node
+	a.rollup.(*nodeTree).parents[6] = 4
+	a.rollup.(*nodeTree).parents[7] = 3
+	test.FailIfError(t, a.discoverNodes(ctx, 2))
+	if _, ok := a.nodes[6]; !ok {
+		t.Error("child of unresolved node not found")
+	}
+	if _, ok := a.nodes[7]; ok {
+		t.Error("walked children of rejected node")
+	}
+}
+
+// syncedLookup has read 10 messages and fails every inbox lookup
+type syncedLookup struct {
+	core.ArbCoreLookup
+}
+
+func (l *syncedLookup) GetMessageCount() (*big.Int, error) {
+	return big.NewInt(10), nil
+}
+
+func (l *syncedLookup) GetLastMachineTotalGas() (*big.Int, error) {
+	return big.NewInt(1000), nil
+}
+
+func (l *syncedLookup) GetInboxAccPair(*big.Int, *big.Int) (common.Hash, common.Hash, error) {
+	return common.Hash{}, common.Hash{}, errors.New("lookup failed")
+}
+
+func TestCheckValidityError(t *testing.T) {
+	nodeInfo := func(messagesRead int64) *core.NodeInfo {
+		return &core.NodeInfo{
+			Assertion: &core.Assertion{After: &core.ExecutionState{
+				TotalMessagesRead: big.NewInt(messagesRead),
+				TotalGasConsumed:  big.NewInt(100),
+			}},
+			AfterInboxBatchEndCount: big.NewInt(messagesRead + 1),
+		}
+	}
+	a := &Auditor{
+		lookup: &syncedLookup{},
+		nodes: map[uint64]*NodeRecord{
+			0: {Node: 0, Validity: ValidValidity},
+			1: {Node: 1, Validity: UnknownValidity},
+			2: {Node: 2, Parent: 1, Validity: UnknownValidity},
+			3: {Node: 3, Parent: 1, Validity: UnknownValidity},
+		},
+		// Node 2 is past the messages ArbCore has read
+		infos: map[uint64]*core.NodeInfo{1: nodeInfo(5), 2: nodeInfo(20), 3: nodeInfo(6)},
+	}
+	test.FailIfError(t, a.checkValidity(context.Background()))
+	for _, node := range []uint64{1, 3} {
+		if a.nodes[node].Validity != UnknownValidity || !strings.Contains(a.nodes[node].Error, "lookup failed") {
+			t.Error("node", node, "has validity", a.nodes[node].Validity, "and error", a.nodes[node].Error)
+		}
+	}
+	if a.nodes[2].Validity != UnknownValidity || a.nodes[2].Error != "" {
+		t.Error("node that isn't caught up has validity", a.nodes[2].Validity, "and error", a.nodes[2].Error)
+	}
+}
@@ -0,0 +1,214 @@
+/*
+ * Copyright 2022, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package backup
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/arbos"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/message"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/monitor"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/protocol"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+)
+
+func TestTarballRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	files := map[string][]byte{
+		"CURRENT":          []byte("MANIFEST-000001\n"),
+		"000001.sst":       common.RandBytes(1000),
+		ManifestFilename:   []byte("{}"),
+		"nested/file.data": common.RandBytes(10),
+	}
+	for name, data := range files {
+		path := filepath.Join(src, name)
+		test.FailIfError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		test.FailIfError(t, ioutil.WriteFile(path, data, 0644))
+	}
+
+	tarballPath := filepath.Join(dir, "1"+TarballExtension)
+	test.FailIfError(t, packTarball(src, tarballPath))
+	dst := filepath.Join(dir, "dst")
+	test.FailIfError(t, extractTarball(tarballPath, dst))
+	for name, data := range files {
+		extracted, err := ioutil.ReadFile(filepath.Join(dst, name))
+		test.FailIfError(t, err)
+		if !bytes.Equal(extracted, data) {
+			t.Error("wrong contents for", name)
+		}
+	}
+}
+
+func TestRetention(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"100", "200", "300"} {
+		test.FailIfError(t, os.MkdirAll(filepath.Join(dir, name), 0755))
+		test.FailIfError(t, WriteManifest(filepath.Join(dir, name), &Manifest{}))
+	}
+	test.FailIfError(t, ioutil.WriteFile(filepath.Join(dir, "400"+TarballExtension), nil, 0644))
+	// Incomplete checkpoints and tarballs are ignored
+	test.FailIfError(t, os.MkdirAll(filepath.Join(dir, "500.tmp"), 0755))
+	test.FailIfError(t, ioutil.WriteFile(filepath.Join(dir, "600"+TarballExtension+".tmp"), nil, 0644))
+
+	manager := NewManager(dir, configuration.DatabaseBackup{Generations: 2})
+	test.FailIfError(t, manager.Update())
+
+	generations, pending, err := ListGenerations(dir)
+	test.FailIfError(t, err)
+	if len(pending) != 0 {
+		t.Error("unexpected pending backups", pending)
+	}
+	if len(generations) != 2 {
+		t.Fatal("expected 2 generations, got", generations)
+	}
+	for _, gen := range generations {
+		if gen.Timestamp != 300 && gen.Timestamp != 400 {
+			t.Error("kept wrong generation", gen.Timestamp)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "500.tmp")); err != nil {
+		t.Error("incomplete checkpoint was removed")
+	}
+}
+
+// newTestBackup creates a database in dir which executed an init message and
+// writes its manifest, as the manager does for a checkpoint
+func newTestBackup(t *testing.T, dir string) *Manifest {
+	arbosPath, err := arbos.Path(false)
+	test.FailIfError(t, err)
+	mon, err := monitor.NewInitializedMonitor(dir, arbosPath, configuration.DefaultCoreSettingsNoMaxExecution())
+	test.FailIfError(t, err)
+	for !mon.Core.MachineIdle() {
+		<-time.After(time.Millisecond * 200)
+	}
+
+	initMsg, err := message.NewInitMessage(protocol.NewRandomChainParams(), common.RandAddress(), nil)
+	test.FailIfError(t, err)
+	chainTime := inbox.ChainTime{
+		BlockNum:  common.NewTimeBlocksInt(1),
+		Timestamp: big.NewInt(1000),
+	}
+	messages := []inbox.InboxMessage{
+		message.NewInboxMessage(initMsg, common.RandAddress(), big.NewInt(0), big.NewInt(0), chainTime),
+		message.NewInboxMessage(message.EndBlockMessage{}, common.RandAddress(), big.NewInt(1), big.NewInt(0), chainTime),
+	}
+	monitor.DeliverMessagesToCore(context.Background(), t, mon.Core, big.NewInt(0), big.NewInt(0), common.Hash{}, messages)
+	mon.Close()
+
+	manifest, err := DescribeDatabase(dir)
+	test.FailIfError(t, err)
+	if manifest.MessageCount != 2 {
+		t.Fatal("backup has", manifest.MessageCount, "messages")
+	}
+	test.FailIfError(t, WriteManifest(dir, manifest))
+	return manifest
+}
+
+func TestValidate(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "backup")
+	manifest := newTestBackup(t, dir)
+
+	validated, err := Validate(dir)
+	test.FailIfError(t, err)
+	if differences := manifest.Compare(validated); len(differences) > 0 {
+		t.Error("validated wrong manifest:", differences)
+	}
+
+	wrong := *manifest
+	wrong.MessageCount++
+	wrong.MachineHash = common.RandHash().ToEthHash()
+	test.FailIfError(t, WriteManifest(dir, &wrong))
+	_, err = Validate(dir)
+	if err == nil || !strings.Contains(err.Error(), "message count") || !strings.Contains(err.Error(), "machine hash") {
+		t.Error("expected manifest mismatch, got", err)
+	}
+
+	test.FailIfError(t, os.Remove(filepath.Join(dir, ManifestFilename)))
+	if _, err := Validate(dir); err == nil {
+		t.Error("validated backup without a manifest")
+	}
+	if _, err := Validate(t.TempDir()); err == nil {
+		t.Error("validated empty directory")
+	}
+}
+
+func TestRestore(t *testing.T) {
+	dir := t.TempDir()
+	backupPath := filepath.Join(dir, "100")
+	manifest := newTestBackup(t, backupPath)
+	tarballPath := backupPath + TarballExtension
+	test.FailIfError(t, packTarball(backupPath, tarballPath))
+
+	// Restoring over an existing database keeps the old one
+	dbPath := filepath.Join(dir, "db")
+	test.FailIfError(t, os.MkdirAll(dbPath, 0755))
+	test.FailIfError(t, ioutil.WriteFile(filepath.Join(dbPath, "marker"), nil, 0644))
+	restored, oldPath, err := Restore(backupPath, dbPath)
+	test.FailIfError(t, err)
+	if differences := manifest.Compare(restored); len(differences) > 0 {
+		t.Error("restored wrong manifest:", differences)
+	}
+	if _, err := os.Stat(filepath.Join(oldPath, "marker")); err != nil {
+		t.Error("previous database not kept:", err)
+	}
+	actual, err := DescribeDatabase(dbPath)
+	test.FailIfError(t, err)
+	if differences := manifest.Compare(actual); len(differences) > 0 {
+		t.Error("restored database doesn't match backup:", differences)
+	}
+	if _, err := Validate(backupPath); err != nil {
+		t.Error("backup changed by restore:", err)
+	}
+
+	tarballDBPath := filepath.Join(dir, "tarball-db")
+	restored, oldPath, err = Restore(tarballPath, tarballDBPath)
+	test.FailIfError(t, err)
+	if oldPath != "" {
+		t.Error("unexpected previous database", oldPath)
+	}
+	if differences := manifest.Compare(restored); len(differences) > 0 {
+		t.Error("restored wrong manifest from tarball:", differences)
+	}
+
+	// A backup that doesn't match its manifest leaves the database alone
+	wrong := *manifest
+	wrong.BlockCount++
+	test.FailIfError(t, WriteManifest(backupPath, &wrong))
+	if _, _, err := Restore(backupPath, dbPath); err == nil {
+		t.Fatal("restored backup which doesn't match its manifest")
+	}
+	if _, err := os.Stat(dbPath + ".restore"); !os.IsNotExist(err) {
+		t.Error("staging directory not removed")
+	}
+	actual, err = DescribeDatabase(dbPath)
+	test.FailIfError(t, err)
+	if differences := manifest.Compare(actual); len(differences) > 0 {
+		t.Error("failed restore changed database:", differences)
+	}
+}
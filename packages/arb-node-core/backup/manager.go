@@ -0,0 +1,167 @@
+/*
+ * Copyright 2022, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package backup
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/arblog"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+)
+
+var logger = arblog.Logger.With().Str("component", "backup").Logger()
+
+const TarballExtension = ".tar.gz"
+
+// Generation is a single backup in the save path
+type Generation struct {
+	Timestamp int64
+	Path      string
+	Tarball   bool
+}
+
+// Manager processes the database checkpoints saved by ArbCore into the save
+// path. ArbCore names each checkpoint after the unix time it was taken and
+// only renames it into place once complete. The manager writes a manifest
+// into each new checkpoint, optionally packs it into a tarball, and deletes
+// the oldest generations beyond the configured count.
+type Manager struct {
+	savePath    string
+	generations int
+	tarball     bool
+}
+
+func NewManager(savePath string, config configuration.DatabaseBackup) *Manager {
+	return &Manager{
+		savePath:    savePath,
+		generations: config.Generations,
+		tarball:     config.Tarball,
+	}
+}
+
+// Start processes new checkpoints every interval until ctx is done
+func (m *Manager) Start(ctx context.Context, interval time.Duration) error {
+	if err := os.MkdirAll(m.savePath, 0755); err != nil {
+		return errors.WithStack(err)
+	}
+	go func() {
+		for {
+			if err := m.Update(); err != nil {
+				logger.Warn().Err(err).Msg("error updating backups")
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+		}
+	}()
+	return nil
+}
+
+// Update writes manifests for new checkpoints and applies the retention
+// policy
+func (m *Manager) Update() error {
+	generations, pending, err := ListGenerations(m.savePath)
+	if err != nil {
+		return err
+	}
+	for _, gen := range pending {
+		manifest, err := DescribeDatabase(gen.Path)
+		if err != nil {
+			return errors.Wrapf(err, "error describing backup %v", gen.Path)
+		}
+		manifest.Timestamp = gen.Timestamp
+		if err := WriteManifest(gen.Path, manifest); err != nil {
+			return err
+		}
+		if m.tarball {
+			tarballPath := gen.Path + TarballExtension
+			if err := packTarball(gen.Path, tarballPath); err != nil {
+				return err
+			}
+			if err := os.RemoveAll(gen.Path); err != nil {
+				return errors.WithStack(err)
+			}
+			gen.Path = tarballPath
+			gen.Tarball = true
+		}
+		logger.Info().
+			Str("path", gen.Path).
+			Uint64("messages", manifest.MessageCount).
+			Uint64("blocks", manifest.BlockCount).
+			Msg("saved backup")
+		generations = append(generations, gen)
+	}
+	return m.prune(generations)
+}
+
+func (m *Manager) prune(generations []Generation) error {
+	if m.generations <= 0 || len(generations) <= m.generations {
+		return nil
+	}
+	sort.Slice(generations, func(i, j int) bool {
+		return generations[i].Timestamp > generations[j].Timestamp
+	})
+	for _, gen := range generations[m.generations:] {
+		logger.Info().Str("path", gen.Path).Msg("deleting old backup")
+		if err := os.RemoveAll(gen.Path); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// ListGenerations returns the complete backups in savePath, and the
+// checkpoints which don't have a manifest yet
+func ListGenerations(savePath string) ([]Generation, []Generation, error) {
+	entries, err := ioutil.ReadDir(savePath)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	var generations []Generation
+	var pending []Generation
+	for _, entry := range entries {
+		name := entry.Name()
+		tarball := !entry.IsDir() && strings.HasSuffix(name, TarballExtension)
+		if !entry.IsDir() && !tarball {
+			continue
+		}
+		timestamp, err := strconv.ParseInt(strings.TrimSuffix(name, TarballExtension), 10, 64)
+		if err != nil {
+			continue
+		}
+		gen := Generation{Timestamp: timestamp, Path: filepath.Join(savePath, name), Tarball: tarball}
+		if !tarball {
+			if _, err := os.Stat(filepath.Join(gen.Path, ManifestFilename)); os.IsNotExist(err) {
+				pending = append(pending, gen)
+				continue
+			}
+		}
+		generations = append(generations, gen)
+	}
+	return generations, pending, nil
+}
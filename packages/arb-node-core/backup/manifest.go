@@ -0,0 +1,124 @@
+/*
+ * Copyright 2022, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/monitor"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+)
+
+// ManifestFilename is stored in each backup next to the database files
+const ManifestFilename = "arb-backup.json"
+
+// Manifest describes the state of the chain saved in a backup
+type Manifest struct {
+	Timestamp    int64          `json:"timestamp"`
+	MessageCount uint64         `json:"messageCount"`
+	BlockCount   uint64         `json:"blockCount"`
+	TotalGas     string         `json:"totalGas"`
+	MachineHash  ethcommon.Hash `json:"machineHash"`
+}
+
+// Compare returns how the state of two backups differs, ignoring when they
+// were taken
+func (m *Manifest) Compare(other *Manifest) []string {
+	var differences []string
+	if m.MessageCount != other.MessageCount {
+		differences = append(differences, fmt.Sprintf("message count %v instead of %v", other.MessageCount, m.MessageCount))
+	}
+	if m.BlockCount != other.BlockCount {
+		differences = append(differences, fmt.Sprintf("block count %v instead of %v", other.BlockCount, m.BlockCount))
+	}
+	if m.TotalGas != other.TotalGas {
+		differences = append(differences, fmt.Sprintf("machine total gas %v instead of %v", other.TotalGas, m.TotalGas))
+	}
+	if m.MachineHash != other.MachineHash {
+		differences = append(differences, fmt.Sprintf("machine hash %v instead of %v", other.MachineHash, m.MachineHash))
+	}
+	return differences
+}
+
+func ReadManifest(dir string) (*Manifest, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, ManifestFilename))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	manifest := &Manifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, errors.Wrap(err, "invalid backup manifest")
+	}
+	return manifest, nil
+}
+
+func WriteManifest(dir string, manifest *Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	tmpPath := filepath.Join(dir, ManifestFilename+".tmp")
+	if err := ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.Rename(tmpPath, filepath.Join(dir, ManifestFilename)))
+}
+
+// DescribeDatabase opens the database in dir and reads the state of the
+// chain as of its last machine checkpoint
+func DescribeDatabase(dir string) (*Manifest, error) {
+	if !configuration.DatabaseInDirectory(dir) {
+		return nil, errors.Errorf("no database in %v", dir)
+	}
+	coreConfig := configuration.DefaultCoreSettingsMaxExecution()
+	coreConfig.Database.ExitAfter = true
+	mon, err := monitor.NewMonitor(dir, coreConfig)
+	if err != nil {
+		return nil, err
+	}
+	defer mon.Close()
+
+	msgCount, err := mon.Core.GetMessageCount()
+	if err != nil {
+		return nil, err
+	}
+	blockCount, err := mon.Storage.GetNodeStore().BlockCount()
+	if err != nil {
+		return nil, err
+	}
+	totalGas, err := mon.Core.GetLastMachineTotalGas()
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := mon.Core.GetExecutionCursor(totalGas, true)
+	if err != nil {
+		return nil, errors.Wrap(err, "error loading last machine")
+	}
+	return &Manifest{
+		MessageCount: msgCount.Uint64(),
+		BlockCount:   blockCount,
+		TotalGas:     totalGas.String(),
+		MachineHash:  cursor.MachineHash().ToEthHash(),
+	}, nil
+}
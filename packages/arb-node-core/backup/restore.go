@@ -0,0 +1,83 @@
+/*
+ * Copyright 2022, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package backup
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/utils"
+)
+
+// Restore replaces the database in dbPath with a backup directory or
+// tarball. The backup is copied next to the database and checked against
+// its manifest before anything is replaced. The previous database is kept,
+// and its new location is returned.
+func Restore(backupPath, dbPath string) (*Manifest, string, error) {
+	stagingPath := dbPath + ".restore"
+	if err := os.RemoveAll(stagingPath); err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+	var err error
+	if strings.HasSuffix(backupPath, TarballExtension) {
+		err = extractTarball(backupPath, stagingPath)
+	} else {
+		err = utils.CopyDatabase(backupPath, stagingPath, true)
+	}
+	if err != nil {
+		_ = os.RemoveAll(stagingPath)
+		return nil, "", err
+	}
+
+	manifest, err := Validate(stagingPath)
+	if err != nil {
+		_ = os.RemoveAll(stagingPath)
+		return nil, "", err
+	}
+
+	var oldPath string
+	if _, err := os.Stat(dbPath); err == nil {
+		oldPath = dbPath + ".old-" + strconv.FormatInt(time.Now().Unix(), 10)
+		if err := os.Rename(dbPath, oldPath); err != nil {
+			return nil, "", errors.WithStack(err)
+		}
+	}
+	if err := os.Rename(stagingPath, dbPath); err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+	return manifest, oldPath, nil
+}
+
+// Validate checks that the database in dir opens and matches its manifest
+func Validate(dir string) (*Manifest, error) {
+	manifest, err := ReadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	actual, err := DescribeDatabase(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "backup database is unreadable")
+	}
+	if differences := manifest.Compare(actual); len(differences) > 0 {
+		return nil, errors.Errorf("backup doesn't match its manifest: %v", strings.Join(differences, ", "))
+	}
+	return manifest, nil
+}
@@ -25,6 +25,10 @@ func (c *Challenger) ChallengeAddress() common.Address {
 	return c.challenge.Address()
 }
 
+func (c *Challenger) Challenge() *ethbridge.Challenge {
+	return c.challenge
+}
+
 func NewChallenger(challenge *ethbridge.Challenge, sequencerInbox *ethbridge.SequencerInboxWatcher, lookup core.ArbCoreLookup, challengedAssertion *core.Assertion, stakerAddress common.Address) *Challenger {
 	return &Challenger{
 		challenge:           challenge,
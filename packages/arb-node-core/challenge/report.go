@@ -0,0 +1,223 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package challenge
+
+import (
+	"bufio"
+	"encoding/json"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+)
+
+// MoveRecord describes a single challenge move sent to L1 by one of our
+// validators. Gas figures are for the whole L1 transaction, which may also
+// contain other staker actions batched through the validator wallet.
+type MoveRecord struct {
+	Challenge      ethcommon.Address `json:"challenge"`
+	Staker         ethcommon.Address `json:"staker"`
+	Kind           string            `json:"kind"`
+	Move           json.RawMessage   `json:"move"`
+	Time           time.Time         `json:"time"`
+	SubmittedBlock uint64            `json:"submittedBlock"`
+	BlocksLeft     *big.Int          `json:"blocksLeft"`
+	TxHash         ethcommon.Hash    `json:"txHash"`
+	IncludedBlock  uint64            `json:"includedBlock"`
+	GasUsed        uint64            `json:"gasUsed"`
+	GasPrice       *big.Int          `json:"gasPrice"`
+	Cost           *big.Int          `json:"cost"`
+}
+
+// NewMoveRecord captures a move when it is submitted. BlocksLeft is the time
+// the current responder had left on their clock when the move was made.
+func NewMoveRecord(challenge common.Address, staker common.Address, move Move, submittedBlock uint64, blocksLeft *big.Int) (*MoveRecord, error) {
+	moveData, err := json.Marshal(move)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var kind struct {
+		Kind string
+	}
+	if err := json.Unmarshal(moveData, &kind); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &MoveRecord{
+		Challenge:      challenge.ToEthAddress(),
+		Staker:         staker.ToEthAddress(),
+		Kind:           kind.Kind,
+		Move:           moveData,
+		Time:           time.Now().UTC(),
+		SubmittedBlock: submittedBlock,
+		BlocksLeft:     blocksLeft,
+	}, nil
+}
+
+// SetReceipt fills in the on-chain results of the transaction containing the move
+func (m *MoveRecord) SetReceipt(txHash ethcommon.Hash, includedBlock uint64, gasUsed uint64, gasPrice *big.Int) {
+	m.TxHash = txHash
+	m.IncludedBlock = includedBlock
+	m.GasUsed = gasUsed
+	m.GasPrice = gasPrice
+	m.Cost = new(big.Int).Mul(new(big.Int).SetUint64(gasUsed), gasPrice)
+}
+
+type ChallengeReport struct {
+	Challenge     ethcommon.Address `json:"challenge"`
+	Moves         []*MoveRecord     `json:"moves"`
+	TotalGasUsed  uint64            `json:"totalGasUsed"`
+	TotalCost     *big.Int          `json:"totalCost"`
+	MinBlocksLeft *big.Int          `json:"minBlocksLeft"`
+}
+
+func newChallengeReport(challenge ethcommon.Address, records []*MoveRecord) *ChallengeReport {
+	report := &ChallengeReport{
+		Challenge: challenge,
+		Moves:     []*MoveRecord{},
+		TotalCost: big.NewInt(0),
+	}
+	for _, record := range records {
+		if record.Challenge != challenge {
+			continue
+		}
+		report.Moves = append(report.Moves, record)
+		report.TotalGasUsed += record.GasUsed
+		if record.Cost != nil {
+			report.TotalCost.Add(report.TotalCost, record.Cost)
+		}
+		if record.Kind == "Timeout" || record.BlocksLeft == nil {
+			// Timeouts are made on our opponent's clock
+			continue
+		}
+		if report.MinBlocksLeft == nil || record.BlocksLeft.Cmp(report.MinBlocksLeft) < 0 {
+			report.MinBlocksLeft = record.BlocksLeft
+		}
+	}
+	return report
+}
+
+// MoveRecorder persists move records as JSON lines so that reports survive
+// restarts of the validator
+type MoveRecorder struct {
+	mutex    sync.Mutex
+	filename string
+	records  []*MoveRecord
+}
+
+func NewMoveRecorder(filename string) (*MoveRecorder, error) {
+	records, err := readMoveRecords(filename)
+	if err != nil {
+		return nil, err
+	}
+	return &MoveRecorder{
+		filename: filename,
+		records:  records,
+	}, nil
+}
+
+func readMoveRecords(filename string) ([]*MoveRecord, error) {
+	file, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer file.Close()
+	var records []*MoveRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 16*1024*1024)
+	for scanner.Scan() {
+		record := &MoveRecord{}
+		if err := json.Unmarshal(scanner.Bytes(), record); err != nil {
+			return nil, errors.Wrapf(err, "error reading challenge moves from %v", filename)
+		}
+		records = append(records, record)
+	}
+	return records, errors.WithStack(scanner.Err())
+}
+
+func (r *MoveRecorder) Record(record *MoveRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	line = append(line, '\n')
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	file, err := os.OpenFile(r.filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer file.Close()
+	if _, err := file.Write(line); err != nil {
+		return errors.WithStack(err)
+	}
+	r.records = append(r.records, record)
+	return nil
+}
+
+func (r *MoveRecorder) Challenges() []ethcommon.Address {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	seen := make(map[ethcommon.Address]bool)
+	challenges := []ethcommon.Address{}
+	for _, record := range r.records {
+		if !seen[record.Challenge] {
+			seen[record.Challenge] = true
+			challenges = append(challenges, record.Challenge)
+		}
+	}
+	return challenges
+}
+
+func (r *MoveRecorder) Report(challenge common.Address) *ChallengeReport {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return newChallengeReport(challenge.ToEthAddress(), r.records)
+}
+
+// LoadChallengeReport builds a report directly from a move record file
+func LoadChallengeReport(filename string, challenge common.Address) (*ChallengeReport, error) {
+	records, err := readMoveRecords(filename)
+	if err != nil {
+		return nil, err
+	}
+	return newChallengeReport(challenge.ToEthAddress(), records), nil
+}
+
+type ReportRPCServer struct {
+	recorder *MoveRecorder
+}
+
+func NewReportRPCServer(recorder *MoveRecorder) *ReportRPCServer {
+	return &ReportRPCServer{recorder: recorder}
+}
+
+func (s *ReportRPCServer) Challenges() []ethcommon.Address {
+	return s.recorder.Challenges()
+}
+
+func (s *ReportRPCServer) ChallengeReport(challenge ethcommon.Address) *ChallengeReport {
+	return s.recorder.Report(common.NewAddressFromEth(challenge))
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package challenge
+
+import (
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+)
+
+func TestMoveRecorder(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "challengeMoves.json")
+	challengeAddress := common.RandAddress()
+	otherChallenge := common.RandAddress()
+	staker := common.RandAddress()
+
+	recorder, err := NewMoveRecorder(filename)
+	test.FailIfError(t, err)
+
+	addMove := func(challenge common.Address, move Move, blocksLeft int64, gasUsed uint64) {
+		record, err := NewMoveRecord(challenge, staker, move, 100, big.NewInt(blocksLeft))
+		test.FailIfError(t, err)
+		record.SetReceipt(common.RandHash().ToEthHash(), 101, gasUsed, big.NewInt(2))
+		test.FailIfError(t, recorder.Record(record))
+	}
+	addMove(challengeAddress, &BisectMove{}, 500, 1000)
+	addMove(challengeAddress, &BisectMove{}, 200, 3000)
+	addMove(challengeAddress, &TimeoutMove{}, -5, 100)
+	addMove(otherChallenge, &BisectMove{}, 10, 7)
+
+	if len(recorder.Challenges()) != 2 {
+		t.Fatal("expected 2 challenges but got", len(recorder.Challenges()))
+	}
+
+	// Reload from disk to make sure records survive a restart
+	report, err := LoadChallengeReport(filename, challengeAddress)
+	test.FailIfError(t, err)
+	if len(report.Moves) != 3 {
+		t.Fatal("expected 3 moves but got", len(report.Moves))
+	}
+	if report.Moves[0].Kind != "Bisect" || report.Moves[2].Kind != "Timeout" {
+		t.Error("wrong move kinds", report.Moves[0].Kind, report.Moves[2].Kind)
+	}
+	if report.TotalGasUsed != 4100 {
+		t.Error("wrong total gas used", report.TotalGasUsed)
+	}
+	if report.TotalCost.Cmp(big.NewInt(8200)) != 0 {
+		t.Error("wrong total cost", report.TotalCost)
+	}
+	if report.MinBlocksLeft.Cmp(big.NewInt(200)) != 0 {
+		t.Error("wrong min blocks left", report.MinBlocksLeft)
+	}
+}
@@ -0,0 +1,161 @@
+/*
+ * Copyright 2022, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package checkpoints
+
+import (
+	"math/big"
+
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+)
+
+// Summary describes how many checkpoints a database holds and how far apart
+// the ones with a machine are
+type Summary struct {
+	Count          int      `json:"count"`
+	WithMachine    int      `json:"withMachine"`
+	TotalEntrySize uint64   `json:"totalEntrySize"`
+	FirstGas       *big.Int `json:"firstGas"`
+	LastGas        *big.Int `json:"lastGas"`
+	MinGap         *big.Int `json:"minGap"`
+	MaxGap         *big.Int `json:"maxGap"`
+	AverageGap     *big.Int `json:"averageGap"`
+}
+
+func Summarize(checkpoints []core.CheckpointInfo) Summary {
+	summary := Summary{Count: len(checkpoints)}
+	var previous *big.Int
+	gaps := 0
+	totalGap := big.NewInt(0)
+	for _, checkpoint := range checkpoints {
+		summary.TotalEntrySize += checkpoint.EntrySize
+		if !checkpoint.HasMachine {
+			continue
+		}
+		summary.WithMachine++
+		if summary.FirstGas == nil {
+			summary.FirstGas = checkpoint.TotalGasUsed
+		}
+		summary.LastGas = checkpoint.TotalGasUsed
+		if previous != nil {
+			gap := new(big.Int).Sub(checkpoint.TotalGasUsed, previous)
+			if summary.MinGap == nil || gap.Cmp(summary.MinGap) < 0 {
+				summary.MinGap = gap
+			}
+			if summary.MaxGap == nil || gap.Cmp(summary.MaxGap) > 0 {
+				summary.MaxGap = gap
+			}
+			totalGap.Add(totalGap, gap)
+			gaps++
+		}
+		previous = checkpoint.TotalGasUsed
+	}
+	if gaps > 0 {
+		summary.AverageGap = totalGap.Div(totalGap, big.NewInt(int64(gaps)))
+	}
+	return summary
+}
+
+// QueryEstimate is the work needed to answer a query at a block when starting
+// from the closest database checkpoint, ignoring any machines cached in memory
+type QueryEstimate struct {
+	Block           uint64   `json:"block"`
+	TargetGas       *big.Int `json:"targetGas"`
+	CheckpointGas   *big.Int `json:"checkpointGas"`
+	CheckpointBlock *big.Int `json:"checkpointBlock"`
+	ExecutionGas    *big.Int `json:"executionGas"`
+
+	// EstimatedGas is core.checkpoint-load-gas-cost plus the execution gas,
+	// the same cost the machine cache weighs a database checkpoint by
+	EstimatedGas *big.Int `json:"estimatedGas"`
+
+	// TooMuchExecution is set when the node would refuse the query because
+	// of core.checkpoint-max-execution-gas
+	TooMuchExecution bool `json:"tooMuchExecution"`
+}
+
+// EstimateQuery finds the last checkpoint with a machine at or before
+// targetGas, the same one ArbCore loads for an archive query
+func EstimateQuery(checkpoints []core.CheckpointInfo, block uint64, targetGas *big.Int, loadGasCost, maxExecutionGas int) (*QueryEstimate, error) {
+	var closest *core.CheckpointInfo
+	for i := range checkpoints {
+		if checkpoints[i].TotalGasUsed.Cmp(targetGas) > 0 {
+			break
+		}
+		if checkpoints[i].HasMachine {
+			closest = &checkpoints[i]
+		}
+	}
+	if closest == nil {
+		return nil, errors.Errorf("no checkpoint with machine at or before gas %v", targetGas)
+	}
+
+	executionGas := new(big.Int).Sub(targetGas, closest.TotalGasUsed)
+	estimatedGas := new(big.Int).Add(executionGas, big.NewInt(int64(loadGasCost)))
+	return &QueryEstimate{
+		Block:            block,
+		TargetGas:        targetGas,
+		CheckpointGas:    closest.TotalGasUsed,
+		CheckpointBlock:  closest.L2BlockNumber,
+		ExecutionGas:     executionGas,
+		EstimatedGas:     estimatedGas,
+		TooMuchExecution: maxExecutionGas != 0 && executionGas.Cmp(big.NewInt(int64(maxExecutionGas))) > 0,
+	}, nil
+}
+
+// SelectForPruning picks the checkpoints with a machine whose L2 block is in
+// [fromBlock, toBlock) that can be deleted while leaving no more than maxGap
+// gas between the remaining checkpoints with a machine. A toBlock of 0 means
+// no upper bound. The first two and last checkpoints are never selected since
+// ArbCore refuses to delete them.
+func SelectForPruning(checkpoints []core.CheckpointInfo, fromBlock, toBlock uint64, maxGap *big.Int) []*big.Int {
+	from := new(big.Int).SetUint64(fromBlock)
+	to := new(big.Int).SetUint64(toBlock)
+
+	var selected []*big.Int
+	var lastKept *big.Int
+	for i := range checkpoints {
+		checkpoint := checkpoints[i]
+		if !checkpoint.HasMachine {
+			continue
+		}
+		inRange := checkpoint.L2BlockNumber.Cmp(from) >= 0 &&
+			(toBlock == 0 || checkpoint.L2BlockNumber.Cmp(to) < 0)
+		next := nextWithMachine(checkpoints, i+1)
+		if !inRange || i < 2 || next == nil || lastKept == nil {
+			lastKept = checkpoint.TotalGasUsed
+			continue
+		}
+		gap := new(big.Int).Sub(next.TotalGasUsed, lastKept)
+		if gap.Cmp(maxGap) > 0 {
+			lastKept = checkpoint.TotalGasUsed
+			continue
+		}
+		selected = append(selected, checkpoint.TotalGasUsed)
+	}
+	return selected
+}
+
+func nextWithMachine(checkpoints []core.CheckpointInfo, start int) *core.CheckpointInfo {
+	for i := start; i < len(checkpoints); i++ {
+		if checkpoints[i].HasMachine {
+			return &checkpoints[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright 2022, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package checkpoints
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+)
+
+// testCheckpoints has a machine checkpoint every 100 gas, one per block
+func testCheckpoints(count int) []core.CheckpointInfo {
+	checkpoints := make([]core.CheckpointInfo, 0, count)
+	for i := 0; i < count; i++ {
+		checkpoints = append(checkpoints, core.CheckpointInfo{
+			TotalGasUsed:  big.NewInt(int64(i * 100)),
+			L2BlockNumber: big.NewInt(int64(i)),
+			HasMachine:    true,
+			EntrySize:     10,
+		})
+	}
+	return checkpoints
+}
+
+func TestSummarize(t *testing.T) {
+	checkpoints := testCheckpoints(5)
+	checkpoints[2].HasMachine = false
+	summary := Summarize(checkpoints)
+	if summary.Count != 5 || summary.WithMachine != 4 || summary.TotalEntrySize != 50 {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+	if summary.MinGap.Int64() != 100 || summary.MaxGap.Int64() != 200 || summary.AverageGap.Int64() != 133 {
+		t.Errorf("unexpected gaps min %v max %v average %v", summary.MinGap, summary.MaxGap, summary.AverageGap)
+	}
+}
+
+func TestEstimateQuery(t *testing.T) {
+	checkpoints := testCheckpoints(5)
+	checkpoints[3].HasMachine = false
+	estimate, err := EstimateQuery(checkpoints, 7, big.NewInt(350), 1000, 100)
+	test.FailIfError(t, err)
+	if estimate.CheckpointGas.Int64() != 200 || estimate.ExecutionGas.Int64() != 150 {
+		t.Errorf("used checkpoint at %v executing %v", estimate.CheckpointGas, estimate.ExecutionGas)
+	}
+	if estimate.EstimatedGas.Int64() != 1150 {
+		t.Errorf("unexpected estimated gas %v", estimate.EstimatedGas)
+	}
+	if !estimate.TooMuchExecution {
+		t.Error("expected query to exceed max execution gas")
+	}
+}
+
+func TestSelectForPruning(t *testing.T) {
+	checkpoints := testCheckpoints(10)
+	selected := SelectForPruning(checkpoints, 0, 0, big.NewInt(300))
+	expected := []int64{200, 300, 500, 600, 800}
+	if len(selected) != len(expected) {
+		t.Fatalf("selected %v but expected %v", selected, expected)
+	}
+	for i, gas := range selected {
+		if gas.Int64() != expected[i] {
+			t.Errorf("selected %v but expected %v", selected, expected)
+		}
+	}
+
+	selected = SelectForPruning(checkpoints, 4, 6, big.NewInt(1000))
+	if len(selected) != 2 || selected[0].Int64() != 400 || selected[1].Int64() != 500 {
+		t.Errorf("unexpected selection in block range %v", selected)
+	}
+
+	if selected = SelectForPruning(checkpoints, 0, 0, big.NewInt(100)); len(selected) != 0 {
+		t.Errorf("pruned below existing spacing %v", selected)
+	}
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	golog "log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/pkgerrors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/auditor"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/cmdhelp"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/ethbridge"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/monitor"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/arblog"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+)
+
+var logger zerolog.Logger
+
+func main() {
+	// Enable line numbers in logging
+	golog.SetFlags(golog.LstdFlags | golog.Lshortfile)
+
+	// Print stack trace when `.Error().Stack().Err(err).` is added to zerolog call
+	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
+
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	logger = arblog.Logger.With().Str("component", "arb-audit").Logger()
+
+	if err := startup(); err != nil {
+		logger.Error().Err(err).Msg("Error running arb-audit")
+	}
+}
+
+func startup() error {
+	ctx, cancelFunc, _ := cmdhelp.CreateLaunchContext()
+	defer cancelFunc()
+
+	config, l1Client, err := configuration.ParseAudit(ctx)
+	if err != nil || len(config.Rollup.Address) == 0 || len(config.BridgeUtilsAddress) == 0 || len(config.Rollup.Machine.Filename) == 0 {
+		fmt.Printf("\n")
+		fmt.Printf("Sample usage: %s --l1.url=<L1 RPC> [--audit.once] [--audit.filename=audit.json]\n", os.Args[0])
+		if err != nil && !strings.Contains(err.Error(), "help requested") {
+			fmt.Printf("%s\n", err.Error())
+		}
+		return nil
+	}
+
+	rollupAddress := common.HexToAddress(config.Rollup.Address)
+	rollup, err := ethbridge.NewRollupWatcher(rollupAddress.ToEthAddress(), config.Rollup.FromBlock, l1Client, bind.CallOpts{})
+	if err != nil {
+		return err
+	}
+
+	mon, err := monitor.NewMonitor(config.GetDatabasePath(), &config.Core)
+	if err != nil {
+		return err
+	}
+	if err := mon.Initialize(config.Rollup.Machine.Filename); err != nil {
+		return err
+	}
+	if err := mon.Start(); err != nil {
+		return err
+	}
+	defer mon.Close()
+
+	// InboxReader may fail to start if the L1 node isn't ready, so keep retrying
+	for {
+		_, _, err = mon.StartInboxReader(
+			ctx,
+			l1Client,
+			rollupAddress,
+			config.Rollup.FromBlock,
+			common.HexToAddress(config.BridgeUtilsAddress),
+			nil,
+			nil,
+			config.Node.InboxReader,
+		)
+		if err == nil {
+			break
+		}
+		logger.Warn().Err(err).Msg("failed to start inbox reader, waiting and retrying")
+		select {
+		case <-ctx.Done():
+			return errors.New("ctx cancelled StartInboxReader retry loop")
+		case <-time.After(5 * time.Second):
+		}
+	}
+
+	audit, err := auditor.NewAuditor(rollup, rollupAddress, l1Client, mon.Core, config.Audit.Filename)
+	if err != nil {
+		return err
+	}
+	for {
+		if err := audit.Update(ctx); err != nil {
+			logger.Warn().Err(err).Msg("error updating audit")
+		} else if err := auditor.WriteTable(os.Stdout, audit.Nodes()); err != nil {
+			return err
+		}
+		if config.Audit.Once {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(config.Audit.Interval):
+		}
+	}
+}
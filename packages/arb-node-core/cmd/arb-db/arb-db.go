@@ -17,9 +17,15 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/offchainlabs/arbitrum/packages/arb-avm-cpp/cmachine"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/backup"
 	"github.com/offchainlabs/arbitrum/packages/arb-node-core/cmdhelp"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/dbmigrate"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/dbverify"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/inboxarchive"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/monitor"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
 	"github.com/pkg/errors"
 	golog "log"
@@ -47,6 +53,7 @@ func main() {
 
 	if err := startup(); err != nil {
 		logger.Error().Err(err).Msg("Error running arb-db")
+		os.Exit(1)
 	}
 }
 
@@ -57,6 +64,15 @@ func startup() error {
 		fmt.Printf("Sample usage: %s --persistent.chain='.arbitrum/mainnet' --core.database.metadata\n", os.Args[0])
 		fmt.Printf("              %s --persistent.chain='.arbitrum/mainnet' --core.database.make-validator\n", os.Args[0])
 		fmt.Printf("              %s --persistent.chain='.arbitrum/mainnet' --core.database.prune-on-startup\n", os.Args[0])
+		fmt.Printf("              %s --persistent.chain='.arbitrum/mainnet' --verify.enable [--verify.checkpoint-samples=10]\n", os.Args[0])
+		fmt.Printf("              %s --persistent.chain='.arbitrum/mainnet' --inbox-archive.export=inbox.arc\n", os.Args[0])
+		fmt.Printf("              %s --persistent.chain='.arbitrum/new' --inbox-archive.import=inbox.arc [--rollup.machine.filename=arbos.mexe]\n", os.Args[0])
+		fmt.Printf("              %s --persistent.chain='.arbitrum/mainnet' --restore.backup='.arbitrum/mainnet/db_checkpoints/1650000000'\n", os.Args[0])
+		fmt.Printf("              echo 'log 100 10' | %s --persistent.chain='.arbitrum/mainnet' --query.enable --query.json\n", os.Args[0])
+		fmt.Printf("              %s --persistent.chain='.arbitrum/mainnet' --migrate.enable [--migrate.dry-run]\n", os.Args[0])
+		fmt.Printf("              %s --persistent.chain='.arbitrum/mainnet' --checkpoints.list\n", os.Args[0])
+		fmt.Printf("              %s --persistent.chain='.arbitrum/mainnet' --checkpoints.estimate --checkpoints.block=1000000\n", os.Args[0])
+		fmt.Printf("              %s --persistent.chain='.arbitrum/mainnet' --checkpoints.prune --checkpoints.to-block=1000000 --checkpoints.max-gap=10000000000 [--checkpoints.dry-run]\n", os.Args[0])
 		if err != nil && !strings.Contains(err.Error(), "help requested") {
 			fmt.Printf("%s\n", err.Error())
 		}
@@ -64,11 +80,28 @@ func startup() error {
 		return nil
 	}
 
-	// Make sure arbcore does not continue to run
-	config.Core.Database.ExitAfter = true
-
 	var databasePath string
 	databasePath = config.GetDatabasePath()
+
+	if len(config.InboxArchive.Import) != 0 {
+		return importInbox(databasePath, config)
+	}
+
+	if len(config.Restore.Backup) != 0 {
+		manifest, oldPath, err := backup.Restore(config.Restore.Backup, databasePath)
+		if err != nil {
+			return err
+		}
+		logger.Info().
+			Uint64("messages", manifest.MessageCount).
+			Uint64("blocks", manifest.BlockCount).
+			Str("previous", oldPath).
+			Msg("restored database backup")
+		return nil
+	}
+
+	// Make sure arbcore does not continue to run
+	config.Core.Database.ExitAfter = true
 	if !configuration.DatabaseInDirectory(databasePath) {
 		if !configuration.DatabaseInDirectory(databasePath) {
 			return errors.New("unable to access database in " + databasePath)
@@ -83,6 +116,26 @@ func startup() error {
 		}
 	}
 
+	if config.Migrate.Enable {
+		return migrateDatabase(databasePath, config)
+	}
+
+	if config.Verify.Enable {
+		return verifyDatabase(databasePath, config)
+	}
+
+	if len(config.InboxArchive.Export) != 0 {
+		return exportInbox(databasePath, config)
+	}
+
+	if config.Query.Enable {
+		return queryDatabase(databasePath, config)
+	}
+
+	if config.Checkpoints.List || config.Checkpoints.Estimate || config.Checkpoints.Prune {
+		return manageCheckpoints(databasePath, config)
+	}
+
 	storage, err := cmachine.NewArbStorage(databasePath, &config.Core)
 	if err != nil {
 		return err
@@ -93,3 +146,133 @@ func startup() error {
 
 	return nil
 }
+
+func migrateDatabase(databasePath string, config *configuration.Config) error {
+	storage, err := cmachine.NewArbStorage(databasePath, &config.Core)
+	if err != nil {
+		return err
+	}
+	defer storage.CloseArbStorage()
+
+	migrations, err := dbmigrate.Run(storage, config.Migrate.DryRun)
+	if err != nil {
+		return err
+	}
+	if config.Migrate.DryRun {
+		return printJSON(migrations)
+	}
+	logger.Info().
+		Int("applied", len(migrations)).
+		Uint64("version", storage.CurrentSchemaVersion()).
+		Msg("database schema up to date")
+	return nil
+}
+
+func verifyDatabase(databasePath string, config *configuration.Config) error {
+	mon, err := monitor.NewMonitor(databasePath, &config.Core)
+	if err != nil {
+		return err
+	}
+	defer mon.Close()
+
+	ctx, cancelFunc, _ := cmdhelp.CreateLaunchContext()
+	defer cancelFunc()
+	verifier := dbverify.NewVerifier(mon.Core, mon.Storage.GetNodeStore())
+	report, err := verifier.Verify(ctx, config.Verify.CheckpointSamples)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	fmt.Println(string(data))
+	if len(report.Corruptions) > 0 {
+		return errors.Errorf("database corrupted, first problem: %v", report.Corruptions[0])
+	}
+	logger.Info().Msg("no corruption found")
+	return nil
+}
+
+func exportInbox(databasePath string, config *configuration.Config) error {
+	mon, err := monitor.NewMonitor(databasePath, &config.Core)
+	if err != nil {
+		return err
+	}
+	defer mon.Close()
+
+	file, err := os.Create(config.InboxArchive.Export)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	trailer, err := inboxarchive.Export(mon.Core, file)
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	logger.Info().
+		Str("file", config.InboxArchive.Export).
+		Str("messages", trailer.MessageCount.String()).
+		Str("delayed", trailer.DelayedCount.String()).
+		Msg("exported inbox")
+	return nil
+}
+
+// importInbox rebuilds the inbox of a new database, executing the imported
+// messages in the background like a normal node would
+func importInbox(databasePath string, config *configuration.Config) error {
+	file, err := os.Open(config.InboxArchive.Import)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer file.Close()
+
+	mon, err := monitor.NewMonitor(databasePath, &config.Core)
+	if err != nil {
+		return err
+	}
+	defer mon.Close()
+	if err := mon.Initialize(config.Rollup.Machine.Filename); err != nil {
+		return err
+	}
+	if err := mon.Start(); err != nil {
+		return err
+	}
+
+	ctx, cancelFunc, _ := cmdhelp.CreateLaunchContext()
+	defer cancelFunc()
+	trailer, err := inboxarchive.Import(ctx, mon.Core, file)
+	if err != nil {
+		return err
+	}
+	logger.Info().
+		Str("file", config.InboxArchive.Import).
+		Str("messages", trailer.MessageCount.String()).
+		Str("delayed", trailer.DelayedCount.String()).
+		Msg("imported inbox")
+	return nil
+}
+
+func queryDatabase(databasePath string, config *configuration.Config) error {
+	mon, err := monitor.NewMonitor(databasePath, &config.Core)
+	if err != nil {
+		return err
+	}
+	defer mon.Close()
+
+	shell := &queryShell{
+		lookup: mon.Core,
+		store:  mon.Storage.GetNodeStore(),
+		json:   config.Query.JSON,
+		out:    os.Stdout,
+	}
+	if !isTerminal(os.Stdin) {
+		return shell.run(os.Stdin)
+	}
+	fmt.Println(queryHelp)
+	shell.runInteractive()
+	return nil
+}
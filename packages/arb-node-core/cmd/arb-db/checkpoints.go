@@ -0,0 +1,122 @@
+/*
+ * Copyright 2022, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/checkpoints"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/monitor"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+)
+
+type checkpointList struct {
+	Summary     checkpoints.Summary   `json:"summary"`
+	Checkpoints []core.CheckpointInfo `json:"checkpoints"`
+}
+
+func printJSON(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+func manageCheckpoints(databasePath string, config *configuration.Config) error {
+	mon, err := monitor.NewMonitor(databasePath, &config.Core)
+	if err != nil {
+		return err
+	}
+	defer mon.Close()
+
+	list, err := mon.Core.ListCheckpoints()
+	if err != nil {
+		return err
+	}
+
+	if config.Checkpoints.List {
+		if err := printJSON(checkpointList{
+			Summary:     checkpoints.Summarize(list),
+			Checkpoints: list,
+		}); err != nil {
+			return err
+		}
+	}
+
+	if config.Checkpoints.Estimate {
+		targetGas, err := mon.Core.GetGasAtEndOfBlock(config.Checkpoints.Block)
+		if err != nil {
+			return err
+		}
+		estimate, err := checkpoints.EstimateQuery(
+			list,
+			config.Checkpoints.Block,
+			targetGas,
+			config.Core.CheckpointLoadGasCost,
+			config.Core.CheckpointMaxExecutionGas,
+		)
+		if err != nil {
+			return err
+		}
+		if err := printJSON(estimate); err != nil {
+			return err
+		}
+	}
+
+	if config.Checkpoints.Prune {
+		return pruneCheckpoints(mon.Core, list, config)
+	}
+	return nil
+}
+
+func pruneCheckpoints(arbCore core.ArbCore, list []core.CheckpointInfo, config *configuration.Config) error {
+	maxGap := config.Checkpoints.MaxGap
+	if maxGap == 0 {
+		maxGap = config.Core.CheckpointMaxExecutionGas
+	}
+	if maxGap <= 0 {
+		return errors.New("checkpoints.max-gap or core.checkpoint-max-execution-gas must be set to prune checkpoints")
+	}
+
+	selected := checkpoints.SelectForPruning(list, config.Checkpoints.FromBlock, config.Checkpoints.ToBlock, big.NewInt(int64(maxGap)))
+	if config.Checkpoints.DryRun {
+		logger.Info().Int("count", len(selected)).Int("maxGap", maxGap).Msg("checkpoints that would be pruned")
+		return printJSON(selected)
+	}
+	if len(selected) == 0 {
+		logger.Info().Msg("no checkpoints to prune")
+		return nil
+	}
+
+	deleted, err := arbCore.DeleteCheckpoints(selected)
+	if err != nil {
+		return err
+	}
+	logger.Info().
+		Uint64("deleted", deleted).
+		Int("selected", len(selected)).
+		Int("remaining", len(list)-int(deleted)).
+		Msg("pruned checkpoints")
+	return nil
+}
@@ -0,0 +1,444 @@
+/*
+ * Copyright 2022, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/c-bata/go-prompt"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/message"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/machine"
+)
+
+const queryHelp = `Commands:
+  counts                      message, log, send and block counts
+  message <index> [count]     inbox messages and their decoded contents
+  log <index> [count]         AVM logs decoded into transaction and block results
+  send <index> [count]        sends decoded into L2 to L1 messages
+  block <height> [count]      block info saved by the node
+  batch-item <seqnum>         sequencer batch item containing a message
+  inbox-acc <index>           inbox accumulator after a message
+  delayed-acc <index>         delayed inbox accumulator after a delayed message
+  help
+  exit`
+
+// queryShell answers read only queries about a node database. Each result is
+// printed as indented JSON, or as a single line in JSON mode for scripting.
+type queryShell struct {
+	lookup core.ArbCoreLookup
+	store  machine.NodeStore
+	json   bool
+	out    io.Writer
+}
+
+type decodedValue struct {
+	Type        string      `json:"type,omitempty"`
+	Value       interface{} `json:"value,omitempty"`
+	DecodeError string      `json:"decodeError,omitempty"`
+}
+
+func decoded(val interface{}, err error) decodedValue {
+	if err != nil {
+		return decodedValue{DecodeError: err.Error()}
+	}
+	return decodedValue{Type: fmt.Sprintf("%T", val), Value: val}
+}
+
+type messageQueryResult struct {
+	Index    uint64             `json:"index"`
+	Message  inbox.InboxMessage `json:"message"`
+	Nested   decodedValue       `json:"nested"`
+	Abstract *decodedValue      `json:"l2,omitempty"`
+}
+
+type logQueryResult struct {
+	Index  uint64       `json:"index"`
+	Result decodedValue `json:"result"`
+}
+
+// txResultQueryResult presents a transaction result with Ethereum types, which
+// are printed in hex
+type txResultQueryResult struct {
+	TxHash          ethcommon.Hash     `json:"txHash"`
+	Kind            inbox.Type         `json:"kind"`
+	Sender          ethcommon.Address  `json:"sender"`
+	L1BlockNumber   *big.Int           `json:"l1BlockNumber"`
+	L2BlockNumber   *big.Int           `json:"l2BlockNumber"`
+	L2Timestamp     *big.Int           `json:"l2Timestamp"`
+	ResultCode      evm.ResultType     `json:"resultCode"`
+	ReturnData      hexutil.Bytes      `json:"returnData"`
+	GasUsed         *big.Int           `json:"gasUsed"`
+	GasPrice        *big.Int           `json:"gasPrice"`
+	CumulativeGas   *big.Int           `json:"cumulativeGas"`
+	TxIndex         *big.Int           `json:"txIndex"`
+	StartLogIndex   *big.Int           `json:"startLogIndex"`
+	ContractAddress *ethcommon.Address `json:"contractAddress,omitempty"`
+	Logs            []*types.Log       `json:"logs"`
+	FeeStats        *evm.FeeStats      `json:"feeStats"`
+}
+
+func decodeLog(val interface{}, err error) decodedValue {
+	res, ok := val.(*evm.TxResult)
+	if err != nil || !ok {
+		return decoded(val, err)
+	}
+	receipt := res.ToEthReceipt(common.Hash{})
+	txRes := txResultQueryResult{
+		TxHash:        res.IncomingRequest.MessageID.ToEthHash(),
+		Kind:          res.IncomingRequest.Kind,
+		Sender:        res.IncomingRequest.Sender.ToEthAddress(),
+		L1BlockNumber: res.IncomingRequest.L1BlockNumber,
+		L2BlockNumber: res.IncomingRequest.L2BlockNumber,
+		L2Timestamp:   res.IncomingRequest.L2Timestamp,
+		ResultCode:    res.ResultCode,
+		ReturnData:    res.ReturnData,
+		GasUsed:       res.GasUsed,
+		GasPrice:      res.GasPrice,
+		CumulativeGas: res.CumulativeGas,
+		TxIndex:       res.TxIndex,
+		StartLogIndex: res.StartLogIndex,
+		Logs:          receipt.Logs,
+		FeeStats:      res.FeeStats,
+	}
+	if receipt.ContractAddress != (ethcommon.Address{}) {
+		txRes.ContractAddress = &receipt.ContractAddress
+	}
+	return decodedValue{Type: fmt.Sprintf("%T", val), Value: txRes}
+}
+
+type batchItemQueryResult struct {
+	LastSeqNum        *big.Int       `json:"lastSequenceNumber"`
+	Accumulator       ethcommon.Hash `json:"accumulator"`
+	TotalDelayedCount *big.Int       `json:"totalDelayedCount"`
+	SequencerMessage  hexutil.Bytes  `json:"sequencerMessage"`
+}
+
+type sendQueryResult struct {
+	Index   uint64        `json:"index"`
+	Data    hexutil.Bytes `json:"data"`
+	Message decodedValue  `json:"message"`
+}
+
+type blockQueryResult struct {
+	Height   uint64         `json:"height"`
+	Hash     ethcommon.Hash `json:"hash"`
+	BlockLog uint64         `json:"blockLog"`
+	LogCount uint64         `json:"logCount"`
+	Header   *types.Header  `json:"header"`
+}
+
+type accQueryResult struct {
+	Index       uint64         `json:"index"`
+	Accumulator ethcommon.Hash `json:"accumulator"`
+}
+
+type countsQueryResult struct {
+	Messages        *big.Int `json:"messages"`
+	DelayedMessages *big.Int `json:"delayedMessages"`
+	Logs            *big.Int `json:"logs"`
+	Sends           *big.Int `json:"sends"`
+	Blocks          uint64   `json:"blocks"`
+	MachineGas      *big.Int `json:"machineGas"`
+}
+
+// queryCommands are suggested by the interactive prompt
+var queryCommands = []prompt.Suggest{
+	{Text: "counts", Description: "message, log, send and block counts"},
+	{Text: "message", Description: "inbox messages and their decoded contents"},
+	{Text: "log", Description: "AVM logs decoded into transaction and block results"},
+	{Text: "send", Description: "sends decoded into L2 to L1 messages"},
+	{Text: "block", Description: "block info saved by the node"},
+	{Text: "batch-item", Description: "sequencer batch item containing a message"},
+	{Text: "inbox-acc", Description: "inbox accumulator after a message"},
+	{Text: "delayed-acc", Description: "delayed inbox accumulator after a delayed message"},
+	{Text: "help"},
+	{Text: "exit"},
+}
+
+// run executes commands from in, one per line, until it's exhausted or exit
+// is entered. Failed commands are reported and don't stop the shell.
+func (q *queryShell) run(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		if !q.execute(scanner.Text()) {
+			return nil
+		}
+	}
+	return errors.WithStack(scanner.Err())
+}
+
+// runInteractive reads commands from the terminal with history and command
+// completion until exit is entered
+func (q *queryShell) runInteractive() {
+	p := prompt.New(
+		func(line string) { q.execute(line) },
+		func(d prompt.Document) []prompt.Suggest {
+			if strings.Contains(d.TextBeforeCursor(), " ") {
+				return nil
+			}
+			return prompt.FilterHasPrefix(queryCommands, d.GetWordBeforeCursor(), true)
+		},
+		prompt.OptionPrefix("> "),
+		prompt.OptionSetExitCheckerOnInput(func(in string, breakline bool) bool {
+			return breakline && strings.TrimSpace(in) == "exit"
+		}),
+	)
+	p.Run()
+}
+
+// execute runs a single command line, returning false if it was exit
+func (q *queryShell) execute(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return true
+	}
+	if fields[0] == "exit" {
+		return false
+	}
+	if err := q.handleCommand(fields); err != nil {
+		q.print(struct {
+			Error string `json:"error"`
+		}{Error: err.Error()})
+	}
+	return true
+}
+
+func (q *queryShell) print(val interface{}) {
+	var data []byte
+	var err error
+	if q.json {
+		data, err = json.Marshal(val)
+	} else {
+		data, err = json.MarshalIndent(val, "", "  ")
+	}
+	if err != nil {
+		fmt.Fprintf(q.out, "{\"error\":%q}\n", err.Error())
+		return
+	}
+	fmt.Fprintln(q.out, string(data))
+}
+
+func (q *queryShell) handleCommand(fields []string) error {
+	switch fields[0] {
+	case "help":
+		fmt.Fprintln(q.out, queryHelp)
+		return nil
+	case "counts":
+		return q.counts()
+	case "message":
+		index, count, err := parseRange(fields)
+		if err != nil {
+			return err
+		}
+		return q.messages(index, count)
+	case "log":
+		index, count, err := parseRange(fields)
+		if err != nil {
+			return err
+		}
+		return q.logs(index, count)
+	case "send":
+		index, count, err := parseRange(fields)
+		if err != nil {
+			return err
+		}
+		return q.sends(index, count)
+	case "block":
+		index, count, err := parseRange(fields)
+		if err != nil {
+			return err
+		}
+		return q.blocks(index, count)
+	case "batch-item":
+		index, _, err := parseRange(fields)
+		if err != nil {
+			return err
+		}
+		return q.batchItem(index)
+	case "inbox-acc":
+		index, _, err := parseRange(fields)
+		if err != nil {
+			return err
+		}
+		acc, err := q.lookup.GetInboxAcc(new(big.Int).SetUint64(index))
+		if err != nil {
+			return err
+		}
+		q.print(accQueryResult{Index: index, Accumulator: acc.ToEthHash()})
+		return nil
+	case "delayed-acc":
+		index, _, err := parseRange(fields)
+		if err != nil {
+			return err
+		}
+		acc, err := q.lookup.GetDelayedInboxAcc(new(big.Int).SetUint64(index))
+		if err != nil {
+			return err
+		}
+		q.print(accQueryResult{Index: index, Accumulator: acc.ToEthHash()})
+		return nil
+	default:
+		return errors.Errorf("unknown command %v, try help", fields[0])
+	}
+}
+
+// parseRange reads the index and optional count arguments of a command
+func parseRange(fields []string) (uint64, uint64, error) {
+	if len(fields) < 2 || len(fields) > 3 {
+		return 0, 0, errors.Errorf("usage: %v <index> [count]", fields[0])
+	}
+	index, err := strconv.ParseUint(fields[1], 0, 64)
+	if err != nil {
+		return 0, 0, errors.Errorf("invalid index %v", fields[1])
+	}
+	count := uint64(1)
+	if len(fields) == 3 {
+		count, err = strconv.ParseUint(fields[2], 0, 64)
+		if err != nil {
+			return 0, 0, errors.Errorf("invalid count %v", fields[2])
+		}
+	}
+	return index, count, nil
+}
+
+func (q *queryShell) counts() error {
+	res := countsQueryResult{}
+	var err error
+	if res.Messages, err = q.lookup.GetMessageCount(); err != nil {
+		return err
+	}
+	if res.DelayedMessages, err = q.lookup.GetDelayedMessageCount(); err != nil {
+		return err
+	}
+	if res.Logs, err = q.lookup.GetLogCount(); err != nil {
+		return err
+	}
+	if res.Sends, err = q.lookup.GetSendCount(); err != nil {
+		return err
+	}
+	if res.Blocks, err = q.store.BlockCount(); err != nil {
+		return err
+	}
+	if res.MachineGas, err = q.lookup.GetLastMachineTotalGas(); err != nil {
+		return err
+	}
+	q.print(res)
+	return nil
+}
+
+func (q *queryShell) messages(index, count uint64) error {
+	messages, err := q.lookup.GetMessages(new(big.Int).SetUint64(index), new(big.Int).SetUint64(count))
+	if err != nil {
+		return err
+	}
+	for i, msg := range messages {
+		res := messageQueryResult{Index: index + uint64(i), Message: msg}
+		nested, err := message.NestedMessage(msg.Data, msg.Kind)
+		res.Nested = decoded(nested, err)
+		if l2, ok := nested.(message.L2Message); ok {
+			abstract := decoded(l2.AbstractMessage())
+			res.Abstract = &abstract
+		}
+		q.print(res)
+	}
+	return nil
+}
+
+func (q *queryShell) logs(index, count uint64) error {
+	logs, err := q.lookup.GetLogs(new(big.Int).SetUint64(index), new(big.Int).SetUint64(count))
+	if err != nil {
+		return err
+	}
+	for i, log := range logs {
+		q.print(logQueryResult{Index: index + uint64(i), Result: decodeLog(evm.NewResultFromValue(log.Value))})
+	}
+	return nil
+}
+
+func (q *queryShell) sends(index, count uint64) error {
+	sends, err := q.lookup.GetSends(new(big.Int).SetUint64(index), new(big.Int).SetUint64(count))
+	if err != nil {
+		return err
+	}
+	for i, send := range sends {
+		q.print(sendQueryResult{
+			Index:   index + uint64(i),
+			Data:    send,
+			Message: decoded(evm.NewVirtualSendResultFromData(send)),
+		})
+	}
+	return nil
+}
+
+func (q *queryShell) blocks(height, count uint64) error {
+	for i := uint64(0); i < count; i++ {
+		info, err := q.store.GetBlockInfo(height + i)
+		if err != nil {
+			return err
+		}
+		if info == nil {
+			return errors.Errorf("block %v not found", height+i)
+		}
+		q.print(blockQueryResult{
+			Height:   height + i,
+			Hash:     info.Header.Hash(),
+			BlockLog: info.BlockLog,
+			LogCount: info.LogCount,
+			Header:   info.Header,
+		})
+	}
+	return nil
+}
+
+func (q *queryShell) batchItem(seqNum uint64) error {
+	items, err := q.lookup.GetSequencerBatchItemsPage(new(big.Int).SetUint64(seqNum), 1)
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return errors.Errorf("no batch item for message %v", seqNum)
+	}
+	item := items[0]
+	q.print(batchItemQueryResult{
+		LastSeqNum:        item.LastSeqNum,
+		Accumulator:       item.Accumulator.ToEthHash(),
+		TotalDelayedCount: item.TotalDelayedCount,
+		SequencerMessage:  item.SequencerMessage,
+	})
+	return nil
+}
+
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
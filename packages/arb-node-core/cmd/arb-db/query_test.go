@@ -0,0 +1,146 @@
+/*
+ * Copyright 2022, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+)
+
+func TestParseRange(t *testing.T) {
+	cases := []struct {
+		line  string
+		index uint64
+		count uint64
+		err   bool
+	}{
+		{line: "message 5", index: 5, count: 1},
+		{line: "message 0x10 3", index: 16, count: 3},
+		{line: "message", err: true},
+		{line: "message 1 2 3", err: true},
+		{line: "message -1", err: true},
+		{line: "message 1 many", err: true},
+	}
+	for _, c := range cases {
+		index, count, err := parseRange(strings.Fields(c.line))
+		if c.err {
+			if err == nil {
+				t.Error("expected error parsing", c.line)
+			}
+			continue
+		}
+		test.FailIfError(t, err)
+		if index != c.index || count != c.count {
+			t.Error("parsed", c.line, "as", index, count)
+		}
+	}
+}
+
+// batchItemLookup serves sequencer batch items, every other lookup panics
+type batchItemLookup struct {
+	core.ArbCoreLookup
+	items     []inbox.SequencerBatchItem
+	maxCounts []uint64
+}
+
+func (l *batchItemLookup) GetSequencerBatchItemsPage(startIndex *big.Int, maxCount uint64) ([]inbox.SequencerBatchItem, error) {
+	l.maxCounts = append(l.maxCounts, maxCount)
+	var items []inbox.SequencerBatchItem
+	for _, item := range l.items {
+		if item.LastSeqNum.Cmp(startIndex) >= 0 && uint64(len(items)) < maxCount {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+func (l *batchItemLookup) GetInboxAcc(index *big.Int) (common.Hash, error) {
+	for _, item := range l.items {
+		if item.LastSeqNum.Cmp(index) == 0 {
+			return item.Accumulator, nil
+		}
+	}
+	return common.Hash{}, errors.Errorf("no accumulator for message %v", index)
+}
+
+func TestQueryShell(t *testing.T) {
+	delayed := inbox.NewDelayedItem(big.NewInt(2), big.NewInt(3), common.Hash{}, big.NewInt(0), common.RandHash())
+	msg := inbox.NewRandomInboxMessage()
+	msg.InboxSeqNum = big.NewInt(3)
+	seqItem := inbox.NewSequencerItem(big.NewInt(3), msg, delayed.Accumulator)
+	lookup := &batchItemLookup{items: []inbox.SequencerBatchItem{delayed, seqItem}}
+
+	var out bytes.Buffer
+	shell := &queryShell{lookup: lookup, json: true, out: &out}
+	script := strings.Join([]string{
+		"batch-item 1",
+		"",
+		"batch-item 3",
+		"inbox-acc 2",
+		"inbox-acc 7",
+		"batch-item 4",
+		"batch-item",
+		"bogus",
+		"exit",
+		"counts",
+	}, "\n")
+	test.FailIfError(t, shell.run(strings.NewReader(script)))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 7 {
+		t.Fatal("expected 7 results but got", lines)
+	}
+	var item batchItemQueryResult
+	test.FailIfError(t, json.Unmarshal([]byte(lines[0]), &item))
+	if item.LastSeqNum.Cmp(delayed.LastSeqNum) != 0 || item.Accumulator != delayed.Accumulator.ToEthHash() {
+		t.Error("wrong batch item containing message 1", lines[0])
+	}
+	test.FailIfError(t, json.Unmarshal([]byte(lines[1]), &item))
+	if !bytes.Equal(item.SequencerMessage, seqItem.SequencerMessage) || item.TotalDelayedCount.Cmp(big.NewInt(3)) != 0 {
+		t.Error("wrong batch item for message 3", lines[1])
+	}
+	var acc accQueryResult
+	test.FailIfError(t, json.Unmarshal([]byte(lines[2]), &acc))
+	if acc.Index != 2 || acc.Accumulator != delayed.Accumulator.ToEthHash() {
+		t.Error("wrong inbox accumulator", lines[2])
+	}
+	expectedErrors := []string{"no accumulator", "no batch item", "usage", "unknown command"}
+	for i, expected := range expectedErrors {
+		var res struct {
+			Error string `json:"error"`
+		}
+		test.FailIfError(t, json.Unmarshal([]byte(lines[3+i]), &res))
+		if !strings.Contains(res.Error, expected) {
+			t.Errorf("expected error containing %q but got %v", expected, lines[3+i])
+		}
+	}
+	for _, maxCount := range lookup.maxCounts {
+		if maxCount != 1 {
+			t.Error("batch item query read", maxCount, "items")
+		}
+	}
+}
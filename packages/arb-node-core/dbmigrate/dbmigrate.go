@@ -0,0 +1,89 @@
+/*
+ * Copyright 2022, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dbmigrate
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/arblog"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/machine"
+)
+
+var logger = arblog.Logger.With().Str("component", "dbmigrate").Logger()
+
+// Migration upgrades a database from schema version From to From+1
+type Migration struct {
+	From        uint64                         `json:"from"`
+	Description string                         `json:"description"`
+	Apply       func(machine.ArbStorage) error `json:"-"`
+}
+
+// Migrations holds the in place upgrades between ArbCore schema versions,
+// indexed by the version they start from. Databases older than the first
+// migration have to be recreated.
+var Migrations []Migration
+
+// Pending returns the migrations needed to bring a database at version up to
+// latest, in the order they must be applied
+func Pending(migrations []Migration, version uint64, latest uint64) ([]Migration, error) {
+	if version > latest {
+		return nil, errors.Errorf("unknown database schema version %v, newest supported is %v", version, latest)
+	}
+	byVersion := make(map[uint64]Migration)
+	for _, migration := range migrations {
+		byVersion[migration.From] = migration
+	}
+	var pending []Migration
+	for v := version; v < latest; v++ {
+		migration, ok := byVersion[v]
+		if !ok {
+			return nil, errors.Errorf("no migration from database schema version %v", v)
+		}
+		pending = append(pending, migration)
+	}
+	return pending, nil
+}
+
+// Run upgrades storage to the ArbCore schema version of this binary,
+// recording the new version after each migration so an interrupted run can be
+// resumed. It returns the migrations applied, or the ones that would be if
+// dryRun is set. New databases have nothing to migrate since ArbCore records
+// the schema version when initializing them.
+func Run(storage machine.ArbStorage, dryRun bool) ([]Migration, error) {
+	version, found, err := storage.SchemaVersion()
+	if err != nil || !found {
+		return nil, err
+	}
+
+	pending, err := Pending(Migrations, version, storage.CurrentSchemaVersion())
+	if err != nil || dryRun {
+		return pending, err
+	}
+	for _, migration := range pending {
+		logger.Info().
+			Uint64("from", migration.From).
+			Str("description", migration.Description).
+			Msg("migrating database")
+		if err := migration.Apply(storage); err != nil {
+			return nil, errors.Wrapf(err, "error migrating database from schema version %v", migration.From)
+		}
+		if err := storage.SetSchemaVersion(migration.From + 1); err != nil {
+			return nil, err
+		}
+	}
+	return pending, nil
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright 2022, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dbmigrate
+
+import (
+	"testing"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/machine"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+)
+
+type versionedStorage struct {
+	machine.ArbStorage
+	version *uint64
+	applied []uint64
+}
+
+func (s *versionedStorage) CurrentSchemaVersion() uint64 {
+	return 3
+}
+
+func (s *versionedStorage) SchemaVersion() (uint64, bool, error) {
+	if s.version == nil {
+		return 0, false, nil
+	}
+	return *s.version, true, nil
+}
+
+func (s *versionedStorage) SetSchemaVersion(version uint64) error {
+	s.version = &version
+	return nil
+}
+
+func TestPending(t *testing.T) {
+	migrations := []Migration{{From: 0}, {From: 1}, {From: 3}}
+	pending, err := Pending(migrations, 1, 2)
+	test.FailIfError(t, err)
+	if len(pending) != 1 || pending[0].From != 1 {
+		t.Errorf("unexpected pending migrations %v", pending)
+	}
+	if _, err := Pending(migrations, 0, 4); err == nil {
+		t.Error("expected error for missing migration")
+	}
+	if _, err := Pending(migrations, 5, 4); err == nil {
+		t.Error("expected error for unknown version")
+	}
+}
+
+func TestRun(t *testing.T) {
+	storage := &versionedStorage{}
+	migrations := Migrations
+	defer func() { Migrations = migrations }()
+	for _, from := range []uint64{1, 2} {
+		from := from
+		Migrations = append(Migrations, Migration{
+			From: from,
+			Apply: func(machine.ArbStorage) error {
+				storage.applied = append(storage.applied, from)
+				return nil
+			},
+		})
+	}
+
+	applied, err := Run(storage, false)
+	test.FailIfError(t, err)
+	if len(applied) != 0 || storage.version != nil {
+		t.Fatal("migrated new database")
+	}
+
+	current := uint64(3)
+	storage.version = &current
+	applied, err = Run(storage, false)
+	test.FailIfError(t, err)
+	if len(applied) != 0 {
+		t.Fatal("migrated current database")
+	}
+
+	old := uint64(1)
+	storage.version = &old
+	applied, err = Run(storage, true)
+	test.FailIfError(t, err)
+	if len(applied) != 2 || *storage.version != 1 || len(storage.applied) != 0 {
+		t.Fatal("dry run changed database")
+	}
+	_, err = Run(storage, false)
+	test.FailIfError(t, err)
+	if *storage.version != 3 || len(storage.applied) != 2 || storage.applied[0] != 1 {
+		t.Errorf("database not migrated in order: version %v applied %v", *storage.version, storage.applied)
+	}
+
+	tooOld := uint64(0)
+	storage.version = &tooOld
+	if _, err := Run(storage, false); err == nil {
+		t.Error("expected error for version without migration")
+	}
+	newer := uint64(4)
+	storage.version = &newer
+	if _, err := Run(storage, false); err == nil {
+		t.Error("expected error for unknown version")
+	}
+}
@@ -0,0 +1,554 @@
+/*
+ * Copyright 2022, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dbverify
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/arblog"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/machine"
+)
+
+var logger = arblog.Logger.With().Str("component", "dbverify").Logger()
+
+const (
+	progressInterval = 100000
+	messageBatchSize = 1000
+)
+
+const (
+	CheckSequencerBatch = "sequencer-batch"
+	CheckDelayedInbox   = "delayed-inbox"
+	CheckBlocks         = "blocks"
+	CheckCheckpoints    = "checkpoints"
+)
+
+// Corruption is the first inconsistency found by a check
+type Corruption struct {
+	Check  string `json:"check"`
+	Index  uint64 `json:"index"`
+	Reason string `json:"reason"`
+}
+
+func (c *Corruption) String() string {
+	return fmt.Sprintf("%v corrupted at index %v: %v", c.Check, c.Index, c.Reason)
+}
+
+type Report struct {
+	BatchItems  uint64        `json:"batchItems"`
+	Delayed     uint64        `json:"delayedMessages"`
+	Blocks      uint64        `json:"blocks"`
+	Checkpoints int           `json:"checkpoints"`
+	Corruptions []*Corruption `json:"corruptions"`
+}
+
+func (r *Report) add(corruption *Corruption) {
+	if corruption != nil {
+		r.Corruptions = append(r.Corruptions, corruption)
+	}
+}
+
+// Verifier checks that the data in a node database is consistent with itself
+type Verifier struct {
+	lookup core.ArbCoreLookup
+	store  machine.NodeStore
+}
+
+func NewVerifier(lookup core.ArbCoreLookup, store machine.NodeStore) *Verifier {
+	return &Verifier{
+		lookup: lookup,
+		store:  store,
+	}
+}
+
+// Verify runs every check, re-executing from checkpointSamples checkpoints.
+// An error is only returned if the database couldn't be read at all, any
+// inconsistency is recorded in the report.
+func (v *Verifier) Verify(ctx context.Context, checkpointSamples int) (*Report, error) {
+	report := &Report{}
+	var corruption *Corruption
+	var err error
+
+	logger.Info().Msg("verifying sequencer batch items")
+	report.BatchItems, corruption, err = v.VerifySequencerBatchItems()
+	if err != nil {
+		return nil, err
+	}
+	report.add(corruption)
+
+	logger.Info().Msg("verifying delayed inbox")
+	report.Delayed, corruption, err = v.VerifyDelayedInbox()
+	if err != nil {
+		return nil, err
+	}
+	report.add(corruption)
+
+	logger.Info().Msg("verifying blocks")
+	report.Blocks, corruption, err = v.VerifyBlocks()
+	if err != nil {
+		return nil, err
+	}
+	report.add(corruption)
+
+	logger.Info().Int("samples", checkpointSamples).Msg("verifying checkpoints")
+	report.Checkpoints, corruption, err = v.VerifyCheckpoints(ctx, checkpointSamples)
+	if err != nil {
+		return nil, err
+	}
+	report.add(corruption)
+
+	return report, nil
+}
+
+// errStopVerifying ends iteration over batch items once corruption is found
+var errStopVerifying = errors.New("corruption found")
+
+// VerifySequencerBatchItems recomputes the accumulator of every sequencer
+// batch item from the one before it and checks it against the stored item
+// and inbox accumulator. Items are read from the database in pages.
+func (v *Verifier) VerifySequencerBatchItems() (uint64, *Corruption, error) {
+	chain := newBatchItemChain(v.lookup.GetDelayedInboxAcc)
+	var corruption *Corruption
+	err := core.ForEachSequencerBatchItem(v.lookup, big.NewInt(0), func(item inbox.SequencerBatchItem) error {
+		var err error
+		corruption, err = chain.add(item)
+		if err != nil {
+			return err
+		}
+		if corruption != nil {
+			return errStopVerifying
+		}
+		inboxAcc, err := v.lookup.GetInboxAcc(item.LastSeqNum)
+		if err != nil {
+			return err
+		}
+		if inboxAcc != item.Accumulator {
+			corruption = &Corruption{
+				Check:  CheckSequencerBatch,
+				Index:  chain.count - 1,
+				Reason: fmt.Sprintf("inbox accumulator %v doesn't match batch item accumulator %v", inboxAcc, item.Accumulator),
+			}
+			return errStopVerifying
+		}
+		return nil
+	})
+	if corruption != nil {
+		return chain.count, corruption, nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	msgCount, err := v.lookup.GetMessageCount()
+	if err != nil {
+		return 0, nil, err
+	}
+	totalDelayed, err := v.lookup.GetTotalDelayedMessagesSequenced()
+	if err != nil {
+		return 0, nil, err
+	}
+	if new(big.Int).Add(chain.prevSeqNum, big.NewInt(1)).Cmp(msgCount) != 0 {
+		return chain.count, &Corruption{
+			Check:  CheckSequencerBatch,
+			Index:  chain.count,
+			Reason: fmt.Sprintf("batch items end at message %v but message count is %v", chain.prevSeqNum, msgCount),
+		}, nil
+	}
+	if chain.prevDelayedCount.Cmp(totalDelayed) != 0 {
+		return chain.count, &Corruption{
+			Check:  CheckSequencerBatch,
+			Index:  chain.count,
+			Reason: fmt.Sprintf("batch items sequence %v delayed messages but %v were recorded", chain.prevDelayedCount, totalDelayed),
+		}, nil
+	}
+	return chain.count, nil, nil
+}
+
+// CheckBatchItemChain checks that each batch item follows from the previous
+// one, starting from the beginning of the inbox
+func CheckBatchItemChain(items []inbox.SequencerBatchItem, delayedAcc func(*big.Int) (common.Hash, error)) (*Corruption, error) {
+	chain := newBatchItemChain(delayedAcc)
+	for _, item := range items {
+		corruption, err := chain.add(item)
+		if err != nil || corruption != nil {
+			return corruption, err
+		}
+	}
+	return nil, nil
+}
+
+// batchItemChain checks batch items one at a time, starting from the
+// beginning of the inbox
+type batchItemChain struct {
+	delayedAcc       func(*big.Int) (common.Hash, error)
+	count            uint64
+	prevAcc          common.Hash
+	prevSeqNum       *big.Int
+	prevDelayedCount *big.Int
+}
+
+func newBatchItemChain(delayedAcc func(*big.Int) (common.Hash, error)) *batchItemChain {
+	return &batchItemChain{
+		delayedAcc:       delayedAcc,
+		prevSeqNum:       big.NewInt(-1),
+		prevDelayedCount: big.NewInt(0),
+	}
+}
+
+// add checks that item follows from the items added before it
+func (c *batchItemChain) add(item inbox.SequencerBatchItem) (*Corruption, error) {
+	index := c.count
+	c.count++
+	corrupted := func(reason string, args ...interface{}) (*Corruption, error) {
+		return &Corruption{
+			Check:  CheckSequencerBatch,
+			Index:  index,
+			Reason: fmt.Sprintf(reason, args...),
+		}, nil
+	}
+	var expected inbox.SequencerBatchItem
+	if len(item.SequencerMessage) > 0 {
+		msg, err := inbox.NewInboxMessageFromData(item.SequencerMessage)
+		if err != nil {
+			return corrupted("invalid sequencer message: %v", err)
+		}
+		if item.TotalDelayedCount.Cmp(c.prevDelayedCount) != 0 {
+			return corrupted("sequencer item changed delayed count from %v to %v", c.prevDelayedCount, item.TotalDelayedCount)
+		}
+		if item.LastSeqNum.Cmp(new(big.Int).Add(c.prevSeqNum, big.NewInt(1))) != 0 {
+			return corrupted("sequencer item has sequence number %v after %v", item.LastSeqNum, c.prevSeqNum)
+		}
+		expected = inbox.NewSequencerItem(item.TotalDelayedCount, msg, c.prevAcc)
+	} else {
+		newDelayed := new(big.Int).Sub(item.TotalDelayedCount, c.prevDelayedCount)
+		if newDelayed.Sign() <= 0 {
+			return corrupted("delayed item changed delayed count from %v to %v", c.prevDelayedCount, item.TotalDelayedCount)
+		}
+		if item.LastSeqNum.Cmp(new(big.Int).Add(c.prevSeqNum, newDelayed)) != 0 {
+			return corrupted("delayed item of %v messages has sequence number %v after %v", newDelayed, item.LastSeqNum, c.prevSeqNum)
+		}
+		acc, err := c.delayedAcc(new(big.Int).Sub(item.TotalDelayedCount, big.NewInt(1)))
+		if err != nil {
+			return nil, err
+		}
+		expected = inbox.NewDelayedItem(item.LastSeqNum, item.TotalDelayedCount, c.prevAcc, c.prevDelayedCount, acc)
+	}
+	if expected.Accumulator != item.Accumulator {
+		return corrupted("accumulator %v doesn't match computed %v", item.Accumulator, expected.Accumulator)
+	}
+	c.prevAcc = item.Accumulator
+	c.prevSeqNum = item.LastSeqNum
+	c.prevDelayedCount = item.TotalDelayedCount
+	if c.count%progressInterval == 0 {
+		logger.Info().Uint64("items", c.count).Msg("batch item progress")
+	}
+	return nil, nil
+}
+
+// VerifyDelayedInbox walks the delayed accumulator chain, recomputing the
+// accumulator of each sequenced delayed message from the message and the
+// accumulator before it, and checks it against the stored one. Messages that
+// haven't been sequenced yet aren't stored as inbox messages, so only their
+// accumulator is checked to exist.
+func (v *Verifier) VerifyDelayedInbox() (uint64, *Corruption, error) {
+	delayedCount, err := v.lookup.GetDelayedMessageCount()
+	if err != nil {
+		return 0, nil, err
+	}
+	count := delayedCount.Uint64()
+	corrupted := func(index *big.Int, reason string, args ...interface{}) *Corruption {
+		return &Corruption{
+			Check:  CheckDelayedInbox,
+			Index:  index.Uint64(),
+			Reason: fmt.Sprintf(reason, args...),
+		}
+	}
+
+	var corruption *Corruption
+	var delayedAcc common.Hash
+	delayedIndex := big.NewInt(0)
+	nextSeqNum := big.NewInt(0)
+	err = core.ForEachSequencerBatchItem(v.lookup, big.NewInt(0), func(item inbox.SequencerBatchItem) error {
+		if len(item.SequencerMessage) > 0 {
+			nextSeqNum = new(big.Int).Add(item.LastSeqNum, big.NewInt(1))
+			return nil
+		}
+		messageCount := new(big.Int).Sub(item.LastSeqNum, nextSeqNum)
+		messages, err := v.lookup.GetMessages(nextSeqNum, messageCount.Add(messageCount, big.NewInt(1)))
+		if err != nil {
+			return err
+		}
+		nextSeqNum = new(big.Int).Add(item.LastSeqNum, big.NewInt(1))
+		for _, msg := range messages {
+			if delayedIndex.Cmp(item.TotalDelayedCount) >= 0 {
+				break
+			}
+			delayed := inbox.NewDelayedMessage(delayedAcc, msg)
+			storedAcc, err := v.lookup.GetDelayedInboxAcc(delayedIndex)
+			if err != nil {
+				corruption = corrupted(delayedIndex, "%v", err)
+				return errStopVerifying
+			}
+			if delayed.DelayedSequenceNumber.Cmp(delayedIndex) != 0 {
+				corruption = corrupted(delayedIndex, "sequenced message has delayed sequence number %v", delayed.DelayedSequenceNumber)
+				return errStopVerifying
+			}
+			if delayed.DelayedAccumulator != storedAcc {
+				corruption = corrupted(delayedIndex, "accumulator %v doesn't match computed %v", storedAcc, delayed.DelayedAccumulator)
+				return errStopVerifying
+			}
+			delayedAcc = delayed.DelayedAccumulator
+			delayedIndex = new(big.Int).Add(delayedIndex, big.NewInt(1))
+			if delayedIndex.Uint64()%progressInterval == 0 {
+				logger.Info().Uint64("delayed", delayedIndex.Uint64()).Msg("delayed inbox progress")
+			}
+		}
+		return nil
+	})
+	if corruption != nil {
+		return count, corruption, nil
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for ; delayedIndex.Cmp(delayedCount) < 0; delayedIndex = new(big.Int).Add(delayedIndex, big.NewInt(1)) {
+		acc, err := v.lookup.GetDelayedInboxAcc(delayedIndex)
+		if err != nil {
+			return count, corrupted(delayedIndex, "%v", err), nil
+		}
+		if acc == (common.Hash{}) {
+			return count, corrupted(delayedIndex, "missing accumulator"), nil
+		}
+	}
+	return count, nil, nil
+}
+
+// VerifyBlocks checks the block info saved by the node against the block
+// logs it was created from
+func (v *Verifier) VerifyBlocks() (uint64, *Corruption, error) {
+	blockCount, err := v.store.BlockCount()
+	if err != nil {
+		return 0, nil, err
+	}
+	var prevHash ethcommon.Hash
+	nextLog := uint64(0)
+	for height := uint64(0); height < blockCount; height++ {
+		corrupted := func(reason string, args ...interface{}) (uint64, *Corruption, error) {
+			return blockCount, &Corruption{
+				Check:  CheckBlocks,
+				Index:  height,
+				Reason: fmt.Sprintf(reason, args...),
+			}, nil
+		}
+		info, err := v.store.GetBlockInfo(height)
+		if err != nil {
+			return corrupted("error reading block info: %v", err)
+		}
+		if info == nil || info.Header == nil {
+			return corrupted("missing block info")
+		}
+		if info.Header.Number.Uint64() != height {
+			return corrupted("header has number %v", info.Header.Number)
+		}
+		if info.Header.ParentHash != prevHash {
+			return corrupted("parent hash %v doesn't match previous block hash %v", info.Header.ParentHash, prevHash)
+		}
+		if info.LogCount == 0 || info.BlockLog+1 < info.LogCount {
+			return corrupted("invalid log count %v for block log %v", info.LogCount, info.BlockLog)
+		}
+		if height > 0 && info.InitialLogIndex() != nextLog {
+			return corrupted("block starts at log %v but previous block ended before log %v", info.InitialLogIndex(), nextLog)
+		}
+		if possible := v.store.GetPossibleBlock(common.NewHashFromEth(info.Header.Hash())); possible == nil || *possible != height {
+			return corrupted("block hash %v not indexed", info.Header.Hash())
+		}
+
+		blockLog, err := core.GetZeroOrOneLog(v.lookup, new(big.Int).SetUint64(info.BlockLog))
+		if err != nil {
+			return 0, nil, err
+		}
+		if blockLog.Value == nil {
+			return corrupted("missing block log %v", info.BlockLog)
+		}
+		res, err := evm.NewResultFromValue(blockLog.Value)
+		if err != nil {
+			return corrupted("invalid block log %v: %v", info.BlockLog, err)
+		}
+		blockRes, ok := res.(*evm.BlockInfo)
+		if !ok {
+			return corrupted("log %v is not a block log", info.BlockLog)
+		}
+		if blockRes.BlockNum.Uint64() != height {
+			return corrupted("block log %v is for block %v", info.BlockLog, blockRes.BlockNum)
+		}
+		if blockRes.LastAVMLog().Uint64() != info.BlockLog {
+			return corrupted("block log is at %v but reports %v", info.BlockLog, blockRes.LastAVMLog())
+		}
+		if blockRes.BlockStats.AVMLogCount.Uint64() != info.LogCount {
+			return corrupted("block info has %v logs but block log reports %v", info.LogCount, blockRes.BlockStats.AVMLogCount)
+		}
+		if info.Header.GasUsed != blockRes.BlockStats.GasUsed.Uint64() {
+			return corrupted("header used %v gas but block log reports %v", info.Header.GasUsed, blockRes.BlockStats.GasUsed)
+		}
+		if info.Header.Time != blockRes.Timestamp.Uint64() {
+			return corrupted("header has timestamp %v but block log reports %v", info.Header.Time, blockRes.Timestamp)
+		}
+
+		prevHash = info.Header.Hash()
+		nextLog = info.BlockLog + 1
+		if (height+1)%progressInterval == 0 {
+			logger.Info().Uint64("block", height).Uint64("blockCount", blockCount).Msg("block progress")
+		}
+	}
+	return blockCount, nil, nil
+}
+
+// VerifyCheckpoints re-executes from samples machine checkpoints spread
+// across the database up to the checkpoint following each, and compares the
+// resulting machine with the one loaded from that checkpoint. The machine is
+// taken from the earlier checkpoint and run directly so no saved or cached
+// machine can stand in for execution. Index is the total gas used at the
+// checkpoint that didn't match.
+func (v *Verifier) VerifyCheckpoints(ctx context.Context, samples int) (int, *Corruption, error) {
+	if samples <= 0 {
+		return 0, nil, nil
+	}
+	checkpoints, err := v.lookup.ListCheckpoints()
+	if err != nil {
+		return 0, nil, err
+	}
+	var machines []core.CheckpointInfo
+	for _, checkpoint := range checkpoints {
+		if checkpoint.HasMachine {
+			machines = append(machines, checkpoint)
+		}
+	}
+	pairs := len(machines) - 1
+	if pairs <= 0 {
+		return 0, nil, nil
+	}
+	if samples > pairs {
+		samples = pairs
+	}
+	checked := 0
+	for i := 0; i < samples; i++ {
+		index := i * pairs / samples
+		corruption, err := v.verifyCheckpoint(ctx, machines[index], machines[index+1])
+		if err != nil {
+			return checked, nil, err
+		}
+		checked++
+		if corruption != nil {
+			return checked, corruption, nil
+		}
+		logger.Info().Int("sample", i+1).Str("gas", machines[index+1].TotalGasUsed.String()).Msg("checkpoint matches")
+	}
+	return checked, nil, nil
+}
+
+// verifyCheckpoint executes the machine saved at start until it reaches the
+// gas used at end and compares it to the machine saved at end
+func (v *Verifier) verifyCheckpoint(ctx context.Context, start, end core.CheckpointInfo) (*Corruption, error) {
+	corrupted := func(checkpoint core.CheckpointInfo, reason string, args ...interface{}) (*Corruption, error) {
+		return &Corruption{
+			Check:  CheckCheckpoints,
+			Index:  checkpoint.TotalGasUsed.Uint64(),
+			Reason: fmt.Sprintf(reason, args...),
+		}, nil
+	}
+	cursor, err := v.lookup.GetExecutionCursor(start.TotalGasUsed, true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error loading checkpoint at gas %v", start.TotalGasUsed)
+	}
+	if cursor.TotalGasConsumed().Cmp(start.TotalGasUsed) != 0 {
+		return corrupted(start, "loaded machine at gas %v", cursor.TotalGasConsumed())
+	}
+	messagesRead := cursor.TotalMessagesRead()
+	mach, err := v.lookup.TakeMachine(cursor)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error taking machine at gas %v", start.TotalGasUsed)
+	}
+
+	gasToRun := new(big.Int).Sub(end.TotalGasUsed, start.TotalGasUsed)
+	gasUsed, messagesRead, err := v.execute(ctx, mach, messagesRead, end.MessageCount, gasToRun.Uint64())
+	if err != nil {
+		return nil, err
+	}
+
+	loaded, err := v.lookup.GetExecutionCursor(end.TotalGasUsed, true)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error loading checkpoint at gas %v", end.TotalGasUsed)
+	}
+	executedGas := new(big.Int).Add(start.TotalGasUsed, new(big.Int).SetUint64(gasUsed))
+	switch {
+	case executedGas.Cmp(end.TotalGasUsed) != 0:
+		return corrupted(end, "execution stopped at gas %v", executedGas)
+	case loaded.TotalGasConsumed().Cmp(end.TotalGasUsed) != 0:
+		return corrupted(end, "loaded machine at gas %v", loaded.TotalGasConsumed())
+	case mach.Hash() != loaded.MachineHash():
+		return corrupted(end, "executed machine hash %v doesn't match stored %v", mach.Hash(), loaded.MachineHash())
+	case messagesRead.Cmp(loaded.TotalMessagesRead()) != 0:
+		return corrupted(end, "executed machine read %v messages but stored read %v", messagesRead, loaded.TotalMessagesRead())
+	default:
+		return nil, nil
+	}
+}
+
+// execute runs mach, which has read messagesRead messages, for up to maxGas
+// without reading past messageCount. It returns the gas used and the total
+// messages read afterwards.
+func (v *Verifier) execute(ctx context.Context, mach machine.Machine, messagesRead, messageCount *big.Int, maxGas uint64) (uint64, *big.Int, error) {
+	read := new(big.Int).Set(messagesRead)
+	next := new(big.Int).Set(messagesRead)
+	gasUsed := uint64(0)
+	var pending []inbox.InboxMessage
+	for gasUsed < maxGas {
+		if len(pending) == 0 && next.Cmp(messageCount) < 0 {
+			count := new(big.Int).Sub(messageCount, next)
+			if count.Cmp(big.NewInt(messageBatchSize)) > 0 {
+				count = big.NewInt(messageBatchSize)
+			}
+			var err error
+			pending, err = v.lookup.GetMessages(next, count)
+			if err != nil {
+				return 0, nil, err
+			}
+			next = next.Add(next, count)
+		}
+		assertion, _, _, err := mach.ExecuteAssertionAdvanced(ctx, maxGas-gasUsed, false, pending, nil, false, false)
+		if err != nil {
+			return 0, nil, err
+		}
+		gasUsed += assertion.NumGas
+		read = read.Add(read, new(big.Int).SetUint64(assertion.InboxMessagesConsumed))
+		pending = pending[assertion.InboxMessagesConsumed:]
+		if assertion.NumGas == 0 && assertion.InboxMessagesConsumed == 0 && (len(pending) > 0 || next.Cmp(messageCount) >= 0) {
+			break
+		}
+	}
+	return gasUsed, read, nil
+}
@@ -0,0 +1,268 @@
+/*
+ * Copyright 2022, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dbverify
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/arbos"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/message"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/monitor"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/protocol"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+)
+
+// testBatchItems builds a delayed item sequencing two messages followed by
+// two sequencer messages
+func testBatchItems() ([]inbox.SequencerBatchItem, func(*big.Int) (common.Hash, error)) {
+	delayedAccs := []common.Hash{common.RandHash(), common.RandHash()}
+	delayedAcc := func(index *big.Int) (common.Hash, error) {
+		return delayedAccs[index.Uint64()], nil
+	}
+	items := []inbox.SequencerBatchItem{
+		inbox.NewDelayedItem(big.NewInt(1), big.NewInt(2), common.Hash{}, big.NewInt(0), delayedAccs[1]),
+	}
+	for seqNum := int64(2); seqNum < 4; seqNum++ {
+		msg := inbox.NewRandomInboxMessage()
+		msg.InboxSeqNum = big.NewInt(seqNum)
+		items = append(items, inbox.NewSequencerItem(big.NewInt(2), msg, items[len(items)-1].Accumulator))
+	}
+	return items, delayedAcc
+}
+
+func TestCheckBatchItemChain(t *testing.T) {
+	items, delayedAcc := testBatchItems()
+	corruption, err := CheckBatchItemChain(items, delayedAcc)
+	test.FailIfError(t, err)
+	if corruption != nil {
+		t.Fatal("valid chain reported as corrupt:", corruption)
+	}
+
+	items[1].SequencerMessage[len(items[1].SequencerMessage)-1] ^= 1
+	corruption, err = CheckBatchItemChain(items, delayedAcc)
+	test.FailIfError(t, err)
+	if corruption == nil || corruption.Index != 1 {
+		t.Fatal("expected corruption at item 1, got", corruption)
+	}
+
+	items, delayedAcc = testBatchItems()
+	items[2].LastSeqNum = big.NewInt(4)
+	corruption, err = CheckBatchItemChain(items, delayedAcc)
+	test.FailIfError(t, err)
+	if corruption == nil || corruption.Index != 2 {
+		t.Fatal("expected corruption at item 2, got", corruption)
+	}
+}
+
+// newTestCore creates a database that executed an init message followed by
+// batches of transactions, saving checkpoints between them
+func newTestCore(t *testing.T) *monitor.Monitor {
+	ctx := context.Background()
+	arbosPath, err := arbos.Path(false)
+	test.FailIfError(t, err)
+	coreConfig := configuration.DefaultCoreSettingsNoMaxExecution()
+	coreConfig.CheckpointGasFrequency = 100_000
+	coreConfig.MessageProcessCount = 1
+	mon, err := monitor.NewInitializedMonitor(t.TempDir(), arbosPath, coreConfig)
+	test.FailIfError(t, err)
+	t.Cleanup(mon.Close)
+
+	chainTime := inbox.ChainTime{
+		BlockNum:  common.NewTimeBlocksInt(1),
+		Timestamp: big.NewInt(1000),
+	}
+	initMsg, err := message.NewInitMessage(protocol.NewRandomChainParams(), common.RandAddress(), nil)
+	test.FailIfError(t, err)
+	batches := [][]message.Message{{initMsg, message.EndBlockMessage{}}}
+	for i := 0; i < 5; i++ {
+		batches = append(batches, []message.Message{
+			message.NewSafeL2Message(message.NewRandomTransaction()),
+			message.NewSafeL2Message(message.NewRandomTransaction()),
+			message.EndBlockMessage{},
+		})
+	}
+
+	var prevAcc common.Hash
+	msgCount := big.NewInt(0)
+	for _, batch := range batches {
+		messages := make([]inbox.InboxMessage, 0, len(batch))
+		for i, msg := range batch {
+			seqNum := new(big.Int).Add(msgCount, big.NewInt(int64(i)))
+			messages = append(messages, message.NewInboxMessage(msg, common.RandAddress(), seqNum, big.NewInt(0), chainTime))
+		}
+		monitor.DeliverMessagesToCore(ctx, t, mon.Core, big.NewInt(0), msgCount, prevAcc, messages)
+		msgCount = msgCount.Add(msgCount, big.NewInt(int64(len(batch))))
+		prevAcc, err = mon.Core.GetInboxAcc(new(big.Int).Sub(msgCount, big.NewInt(1)))
+		test.FailIfError(t, err)
+	}
+	return mon
+}
+
+func machineCheckpoints(t *testing.T, lookup core.ArbCoreLookup) []core.CheckpointInfo {
+	t.Helper()
+	checkpoints, err := lookup.ListCheckpoints()
+	test.FailIfError(t, err)
+	var machines []core.CheckpointInfo
+	for _, checkpoint := range checkpoints {
+		if checkpoint.HasMachine {
+			machines = append(machines, checkpoint)
+		}
+	}
+	if len(machines) < 3 {
+		t.Fatal("only", len(machines), "machine checkpoints saved")
+	}
+	return machines
+}
+
+func TestVerifyDatabase(t *testing.T) {
+	mon := newTestCore(t)
+	machines := machineCheckpoints(t, mon.Core)
+	verifier := NewVerifier(mon.Core, mon.Storage.GetNodeStore())
+	report, err := verifier.Verify(context.Background(), len(machines))
+	test.FailIfError(t, err)
+	if len(report.Corruptions) > 0 {
+		t.Fatal("valid database reported as corrupt:", report.Corruptions[0])
+	}
+	if report.BatchItems != 17 {
+		t.Error("verified", report.BatchItems, "batch items")
+	}
+	if report.Checkpoints != len(machines)-1 {
+		t.Error("verified", report.Checkpoints, "of", len(machines)-1, "checkpoints")
+	}
+}
+
+// corruptCheckpoint loads a machine with a different hash for the checkpoint
+// saved at gas
+type corruptCheckpoint struct {
+	core.ArbCoreLookup
+	gas *big.Int
+}
+
+func (c corruptCheckpoint) GetExecutionCursor(totalGasUsed *big.Int, allowSlowLookup bool) (core.ExecutionCursor, error) {
+	cursor, err := c.ArbCoreLookup.GetExecutionCursor(totalGasUsed, allowSlowLookup)
+	if err != nil || totalGasUsed.Cmp(c.gas) != 0 {
+		return cursor, err
+	}
+	return corruptCursor{cursor}, nil
+}
+
+type corruptCursor struct {
+	core.ExecutionCursor
+}
+
+func (c corruptCursor) MachineHash() common.Hash {
+	hash := c.ExecutionCursor.MachineHash()
+	hash[0] ^= 1
+	return hash
+}
+
+func TestVerifyCorruptCheckpoint(t *testing.T) {
+	mon := newTestCore(t)
+	machines := machineCheckpoints(t, mon.Core)
+	corrupt := machines[len(machines)-1]
+	lookup := corruptCheckpoint{ArbCoreLookup: mon.Core, gas: corrupt.TotalGasUsed}
+	verifier := NewVerifier(lookup, mon.Storage.GetNodeStore())
+
+	// Samples are spread from the first checkpoint, so the corrupt last
+	// checkpoint is only reached when every checkpoint is checked
+	checked, corruption, err := verifier.VerifyCheckpoints(context.Background(), 1)
+	test.FailIfError(t, err)
+	if checked != 1 || corruption != nil {
+		t.Error("checked", checked, "checkpoints and found", corruption)
+	}
+
+	checked, corruption, err = verifier.VerifyCheckpoints(context.Background(), len(machines))
+	test.FailIfError(t, err)
+	if corruption == nil {
+		t.Fatal("corrupt checkpoint not reported")
+	}
+	if checked != len(machines)-1 || corruption.Check != CheckCheckpoints || corruption.Index != corrupt.TotalGasUsed.Uint64() {
+		t.Error("wrong corruption after", checked, "checkpoints:", corruption)
+	}
+	if !strings.Contains(corruption.Reason, "machine hash") {
+		t.Error("wrong reason", corruption.Reason)
+	}
+}
+
+// addDelayedMessages sequences count delayed messages at the end of the
+// database
+func addDelayedMessages(t *testing.T, mon *monitor.Monitor, count int64) {
+	t.Helper()
+	msgCount, err := mon.Core.GetMessageCount()
+	test.FailIfError(t, err)
+	prevAcc, err := mon.Core.GetInboxAcc(new(big.Int).Sub(msgCount, big.NewInt(1)))
+	test.FailIfError(t, err)
+	chainTime := inbox.ChainTime{
+		BlockNum:  common.NewTimeBlocksInt(2),
+		Timestamp: big.NewInt(2000),
+	}
+	var delayedAcc common.Hash
+	var delayedMessages []inbox.DelayedMessage
+	for i := int64(0); i < count; i++ {
+		msg := message.NewInboxMessage(message.NewSafeL2Message(message.NewRandomTransaction()), common.RandAddress(), big.NewInt(i), big.NewInt(0), chainTime)
+		delayed := inbox.NewDelayedMessage(delayedAcc, msg)
+		delayedMessages = append(delayedMessages, delayed)
+		delayedAcc = delayed.DelayedAccumulator
+	}
+	lastSeqNum := new(big.Int).Add(msgCount, big.NewInt(count-1))
+	item := inbox.NewDelayedItem(lastSeqNum, big.NewInt(count), prevAcc, big.NewInt(0), delayedAcc)
+	err = core.DeliverMessagesAndWait(context.Background(), mon.Core, msgCount, prevAcc, []inbox.SequencerBatchItem{item}, delayedMessages, nil)
+	test.FailIfError(t, err)
+}
+
+// corruptDelayedAcc returns a different accumulator for the delayed message
+// at index
+type corruptDelayedAcc struct {
+	core.ArbCoreLookup
+	index *big.Int
+}
+
+func (c corruptDelayedAcc) GetDelayedInboxAcc(index *big.Int) (common.Hash, error) {
+	acc, err := c.ArbCoreLookup.GetDelayedInboxAcc(index)
+	if err == nil && index.Cmp(c.index) == 0 {
+		acc[0] ^= 1
+	}
+	return acc, err
+}
+
+func TestVerifyDelayedInbox(t *testing.T) {
+	mon := newTestCore(t)
+	addDelayedMessages(t, mon, 3)
+	verifier := NewVerifier(mon.Core, mon.Storage.GetNodeStore())
+	count, corruption, err := verifier.VerifyDelayedInbox()
+	test.FailIfError(t, err)
+	if count != 3 || corruption != nil {
+		t.Fatal("verified", count, "delayed messages and found", corruption)
+	}
+
+	verifier = NewVerifier(corruptDelayedAcc{ArbCoreLookup: mon.Core, index: big.NewInt(1)}, mon.Storage.GetNodeStore())
+	_, corruption, err = verifier.VerifyDelayedInbox()
+	test.FailIfError(t, err)
+	if corruption == nil {
+		t.Fatal("corrupt delayed accumulator not reported")
+	}
+	if corruption.Check != CheckDelayedInbox || corruption.Index != 1 || !strings.Contains(corruption.Reason, "doesn't match") {
+		t.Error("wrong corruption", corruption)
+	}
+}
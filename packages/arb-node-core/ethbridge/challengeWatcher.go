@@ -125,21 +125,31 @@ func (c *ChallengeWatcher) ChallengeState(ctx context.Context) (common.Hash, err
 }
 
 func (c *ChallengeWatcher) IsTimedOut(ctx context.Context) (bool, error) {
+	blocksLeft, err := c.CurrentResponderBlocksLeft(ctx)
+	if err != nil {
+		return false, err
+	}
+	return blocksLeft.Sign() < 0, nil
+}
+
+// CurrentResponderBlocksLeft returns the number of L1 blocks the current
+// responder has left before timing out, which is negative once they have
+func (c *ChallengeWatcher) CurrentResponderBlocksLeft(ctx context.Context) (*big.Int, error) {
 	currentBlock, err := c.client.BlockInfoByNumber(ctx, nil)
 	if err != nil {
-		return false, errors.WithStack(err)
+		return nil, errors.WithStack(err)
 	}
 	lastMoveBlock, err := c.con.LastMoveBlock(c.getCallOpts(ctx))
 	if err != nil {
-		return false, errors.WithStack(err)
+		return nil, errors.WithStack(err)
 	}
 	timeLeft, err := c.con.CurrentResponderTimeLeft(c.getCallOpts(ctx))
 	if err != nil {
-		return false, errors.WithStack(err)
+		return nil, errors.WithStack(err)
 	}
 
 	timeSinceLastMove := new(big.Int).Sub((*big.Int)(currentBlock.Number), lastMoveBlock)
-	return timeSinceLastMove.Cmp(timeLeft) > 0, nil
+	return timeLeft.Sub(timeLeft, timeSinceLastMove), nil
 }
 
 func (c *ChallengeWatcher) LookupBisection(ctx context.Context, challengeState common.Hash) (*core.Bisection, error) {
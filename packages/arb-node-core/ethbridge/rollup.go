@@ -132,8 +132,8 @@ func (r *Rollup) AddToDeposit(ctx context.Context, address common.Address, amoun
 	return err
 }
 
-func (r *Rollup) ReduceDeposit(ctx context.Context, amount *big.Int) error {
-	_, err := r.builderCon.ReduceDeposit(authWithContext(ctx, r.builderAuth), amount)
+func (r *Rollup) ReduceDeposit(ctx context.Context, target *big.Int) error {
+	_, err := r.builderCon.ReduceDeposit(authWithContext(ctx, r.builderAuth), target)
 	return errors.WithStack(err)
 }
 
@@ -167,6 +167,7 @@ func (r *RollupWatcher) LookupNode(ctx context.Context, number *big.Int) (*core.
 		AfterInboxBatchEndCount: parsedLog.AfterInboxBatchEndCount,
 		AfterInboxBatchAcc:      parsedLog.AfterInboxBatchAcc,
 		NodeHash:                parsedLog.NodeHash,
+		CreationTxHash:          common.NewHashFromEth(ethLog.TxHash),
 	}, nil
 }
 
@@ -206,6 +207,7 @@ func (r *RollupWatcher) LookupNodeChildren(ctx context.Context, parentHash [32]b
 			AfterInboxBatchEndCount: parsedLog.AfterInboxBatchEndCount,
 			AfterInboxBatchAcc:      parsedLog.AfterInboxBatchAcc,
 			NodeHash:                lastHash,
+			CreationTxHash:          common.NewHashFromEth(ethLog.TxHash),
 		})
 	}
 	return infos, nil
@@ -243,6 +245,30 @@ func (r *RollupWatcher) LookupChallengedNode(ctx context.Context, address common
 	return challenge.ChallengedNode, nil
 }
 
+// LookupChallenges returns all challenges started from the given block onwards
+func (r *RollupWatcher) LookupChallenges(ctx context.Context, fromBlock *big.Int) ([]*ethbridgecontracts.RollupUserFacetRollupChallengeStarted, error) {
+	query := ethereum.FilterQuery{
+		BlockHash: nil,
+		FromBlock: fromBlock,
+		ToBlock:   nil,
+		Addresses: []ethcommon.Address{r.address},
+		Topics:    [][]ethcommon.Hash{{challengeCreatedID}},
+	}
+	logs, err := r.client.FilterLogs(ctx, query)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	challenges := make([]*ethbridgecontracts.RollupUserFacetRollupChallengeStarted, 0, len(logs))
+	for _, ethLog := range logs {
+		challenge, err := r.con.ParseRollupChallengeStarted(ethLog)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		challenges = append(challenges, challenge)
+	}
+	return challenges, nil
+}
+
 func (r *RollupWatcher) GetNodeStakerCount(ctx context.Context, nodeNum *big.Int) (*big.Int, error) {
 	callOpts := r.getCallOpts(ctx)
 	nodeAddr, err := r.con.GetNode(callOpts, nodeNum)
@@ -331,6 +357,11 @@ func (r *RollupWatcher) LatestNodeCreated(ctx context.Context) (*big.Int, error)
 	return node, errors.WithStack(err)
 }
 
+func (r *RollupWatcher) NodeHash(ctx context.Context, node core.NodeID) (common.Hash, error) {
+	hash, err := r.con.GetNodeHash(r.getCallOpts(ctx), node)
+	return hash, errors.WithStack(err)
+}
+
 func (r *RollupWatcher) ConfirmPeriodBlocks(ctx context.Context) (*big.Int, error) {
 	blocks, err := r.con.ConfirmPeriodBlocks(r.getCallOpts(ctx))
 	return blocks, errors.WithStack(err)
@@ -3,6 +3,7 @@ module github.com/offchainlabs/arbitrum/packages/arb-node-core
 go 1.13
 
 require (
+	github.com/c-bata/go-prompt v0.2.6
 	github.com/ethereum/go-ethereum v1.10.18
 	github.com/heptiolabs/healthcheck v0.0.0-20180807145615-6ff867650f40
 	github.com/offchainlabs/arbitrum/packages/arb-avm-cpp v0.8.0
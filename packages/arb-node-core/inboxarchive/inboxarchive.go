@@ -0,0 +1,187 @@
+/*
+ * Copyright 2022, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package inboxarchive reads and writes the inbox of a node as a portable
+// file, independent of the database format.
+//
+// An archive starts with an 8 byte magic string and a big endian uint32
+// version. It is followed by chunks, each made of a type byte, a big endian
+// uint32 payload length, the RLP encoded payload and the keccak256 hash of
+// the payload. Data chunks hold consecutive sequencer batch items along with
+// the delayed messages they sequence. The archive ends with a single trailer
+// chunk describing the state of the inbox after the last item.
+package inboxarchive
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/hashing"
+)
+
+const (
+	magic   = "ARBINBOX"
+	Version = 1
+
+	dataChunk    = 1
+	trailerChunk = 2
+
+	maxChunkSize = 1 << 30
+)
+
+// Chunk is a run of consecutive sequencer batch items. Items are stored as
+// returned by SequencerBatchItem.ToBytesWithSeqNum and delayed messages as
+// returned by InboxMessage.ToBytes, in the order they're sequenced.
+type Chunk struct {
+	Items           [][]byte
+	DelayedMessages [][]byte
+}
+
+// Trailer describes the inbox after the last item in the archive
+type Trailer struct {
+	MessageCount *big.Int
+	DelayedCount *big.Int
+	Accumulator  common.Hash
+	Chunks       uint64
+}
+
+type Writer struct {
+	w      *bufio.Writer
+	chunks uint64
+}
+
+func NewWriter(w io.Writer) (*Writer, error) {
+	out := bufio.NewWriter(w)
+	header := make([]byte, len(magic)+4)
+	copy(header, magic)
+	binary.BigEndian.PutUint32(header[len(magic):], Version)
+	if _, err := out.Write(header); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &Writer{w: out}, nil
+}
+
+func (w *Writer) writeChunk(kind byte, val interface{}) error {
+	payload, err := rlp.EncodeToBytes(val)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	header := make([]byte, 5)
+	header[0] = kind
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)))
+	for _, data := range [][]byte{header, payload, hashing.SoliditySHA3(payload).Bytes()} {
+		if _, err := w.w.Write(data); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+func (w *Writer) WriteChunk(chunk *Chunk) error {
+	w.chunks++
+	return w.writeChunk(dataChunk, chunk)
+}
+
+// Close writes the trailer and flushes the archive. The underlying writer
+// isn't closed.
+func (w *Writer) Close(trailer Trailer) error {
+	trailer.Chunks = w.chunks
+	if err := w.writeChunk(trailerChunk, &trailer); err != nil {
+		return err
+	}
+	return errors.WithStack(w.w.Flush())
+}
+
+type Reader struct {
+	r       *bufio.Reader
+	chunks  uint64
+	trailer *Trailer
+}
+
+func NewReader(r io.Reader) (*Reader, error) {
+	in := bufio.NewReader(r)
+	header := make([]byte, len(magic)+4)
+	if _, err := io.ReadFull(in, header); err != nil {
+		return nil, errors.Wrap(err, "error reading archive header")
+	}
+	if !bytes.Equal(header[:len(magic)], []byte(magic)) {
+		return nil, errors.New("not an inbox archive")
+	}
+	if version := binary.BigEndian.Uint32(header[len(magic):]); version != Version {
+		return nil, errors.Errorf("unsupported inbox archive version %v", version)
+	}
+	return &Reader{r: in}, nil
+}
+
+// Next returns the next data chunk, or nil once the trailer is reached
+func (r *Reader) Next() (*Chunk, error) {
+	if r.trailer != nil {
+		return nil, nil
+	}
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(r.r, header); err != nil {
+		return nil, errors.Wrapf(err, "error reading chunk %v, archive may be truncated", r.chunks)
+	}
+	length := binary.BigEndian.Uint32(header[1:])
+	if length > maxChunkSize {
+		return nil, errors.Errorf("chunk %v has invalid size %v", r.chunks, length)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r.r, payload); err != nil {
+		return nil, errors.Wrapf(err, "error reading chunk %v", r.chunks)
+	}
+	var checksum common.Hash
+	if _, err := io.ReadFull(r.r, checksum[:]); err != nil {
+		return nil, errors.Wrapf(err, "error reading chunk %v checksum", r.chunks)
+	}
+	if hashing.SoliditySHA3(payload) != checksum {
+		return nil, errors.Errorf("checksum mismatch in chunk %v", r.chunks)
+	}
+
+	switch header[0] {
+	case dataChunk:
+		chunk := &Chunk{}
+		if err := rlp.DecodeBytes(payload, chunk); err != nil {
+			return nil, errors.Wrapf(err, "error decoding chunk %v", r.chunks)
+		}
+		r.chunks++
+		return chunk, nil
+	case trailerChunk:
+		trailer := &Trailer{}
+		if err := rlp.DecodeBytes(payload, trailer); err != nil {
+			return nil, errors.Wrap(err, "error decoding trailer")
+		}
+		if trailer.Chunks != r.chunks {
+			return nil, errors.Errorf("archive has %v chunks but trailer expects %v", r.chunks, trailer.Chunks)
+		}
+		r.trailer = trailer
+		return nil, nil
+	default:
+		return nil, errors.Errorf("chunk %v has unknown type %v", r.chunks, header[0])
+	}
+}
+
+// Trailer returns the archive trailer once every chunk has been read
+func (r *Reader) Trailer() *Trailer {
+	return r.trailer
+}
@@ -0,0 +1,208 @@
+/*
+ * Copyright 2022, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inboxarchive
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/message"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/monitor"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/protocol"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+)
+
+func writeTestArchive(t *testing.T, chunks []*Chunk, trailer Trailer) []byte {
+	var buf bytes.Buffer
+	writer, err := NewWriter(&buf)
+	test.FailIfError(t, err)
+	for _, chunk := range chunks {
+		test.FailIfError(t, writer.WriteChunk(chunk))
+	}
+	test.FailIfError(t, writer.Close(trailer))
+	return buf.Bytes()
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	chunks := []*Chunk{
+		{Items: [][]byte{common.RandBytes(100), common.RandBytes(50)}, DelayedMessages: [][]byte{common.RandBytes(80)}},
+		{Items: [][]byte{common.RandBytes(10)}},
+	}
+	trailer := Trailer{MessageCount: big.NewInt(4), DelayedCount: big.NewInt(1), Accumulator: common.RandHash()}
+	data := writeTestArchive(t, chunks, trailer)
+
+	reader, err := NewReader(bytes.NewReader(data))
+	test.FailIfError(t, err)
+	for i, expected := range chunks {
+		chunk, err := reader.Next()
+		test.FailIfError(t, err)
+		if chunk == nil || len(chunk.Items) != len(expected.Items) || len(chunk.DelayedMessages) != len(expected.DelayedMessages) {
+			t.Fatal("wrong chunk", i)
+		}
+		for j := range expected.Items {
+			if !bytes.Equal(chunk.Items[j], expected.Items[j]) {
+				t.Error("wrong item", j, "in chunk", i)
+			}
+		}
+	}
+	chunk, err := reader.Next()
+	test.FailIfError(t, err)
+	if chunk != nil {
+		t.Fatal("expected trailer")
+	}
+	read := reader.Trailer()
+	if read.Chunks != 2 || read.MessageCount.Cmp(trailer.MessageCount) != 0 || read.Accumulator != trailer.Accumulator {
+		t.Error("wrong trailer", read)
+	}
+}
+
+func TestArchiveCorruption(t *testing.T) {
+	chunks := []*Chunk{{Items: [][]byte{common.RandBytes(100)}}}
+	data := writeTestArchive(t, chunks, Trailer{MessageCount: big.NewInt(1), DelayedCount: big.NewInt(0)})
+
+	corrupted := append([]byte(nil), data...)
+	corrupted[len(magic)+4+5+10] ^= 1
+	reader, err := NewReader(bytes.NewReader(corrupted))
+	test.FailIfError(t, err)
+	if _, err := reader.Next(); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Error("expected checksum error, got", err)
+	}
+
+	reader, err = NewReader(bytes.NewReader(data[:len(data)-40]))
+	test.FailIfError(t, err)
+	_, err = reader.Next()
+	test.FailIfError(t, err)
+	if _, err := reader.Next(); err == nil {
+		t.Error("expected error reading truncated archive")
+	}
+}
+
+// deliverTestInbox delivers two batches, each starting with a delayed item
+// followed by a transaction and an end of block message
+func deliverTestInbox(t *testing.T, arbCore core.ArbCore) {
+	ctx := context.Background()
+	chainTime := inbox.ChainTime{
+		BlockNum:  common.NewTimeBlocksInt(1),
+		Timestamp: big.NewInt(1000),
+	}
+	initMsg, err := message.NewInitMessage(protocol.NewRandomChainParams(), common.RandAddress(), nil)
+	test.FailIfError(t, err)
+	delayedBatches := [][]message.Message{
+		{initMsg},
+		{message.NewSafeL2Message(message.NewRandomTransaction()), message.NewSafeL2Message(message.NewRandomTransaction())},
+	}
+
+	msgCount := big.NewInt(0)
+	delayedCount := big.NewInt(0)
+	var prevAcc common.Hash
+	var delayedAcc common.Hash
+	for _, delayedBatch := range delayedBatches {
+		prevDelayedCount := delayedCount
+		var delayedMessages []inbox.DelayedMessage
+		for _, msg := range delayedBatch {
+			inboxMsg := message.NewInboxMessage(msg, common.RandAddress(), delayedCount, big.NewInt(0), chainTime)
+			delayed := inbox.NewDelayedMessage(delayedAcc, inboxMsg)
+			delayedMessages = append(delayedMessages, delayed)
+			delayedAcc = delayed.DelayedAccumulator
+			delayedCount = new(big.Int).Add(delayedCount, big.NewInt(1))
+		}
+		seqNum := new(big.Int).Add(msgCount, big.NewInt(int64(len(delayedBatch))))
+		items := []inbox.SequencerBatchItem{
+			inbox.NewDelayedItem(new(big.Int).Sub(seqNum, big.NewInt(1)), delayedCount, prevAcc, prevDelayedCount, delayedAcc),
+		}
+		for _, msg := range []message.Message{message.NewSafeL2Message(message.NewRandomTransaction()), message.EndBlockMessage{}} {
+			inboxMsg := message.NewInboxMessage(msg, common.RandAddress(), seqNum, big.NewInt(0), chainTime)
+			items = append(items, inbox.NewSequencerItem(delayedCount, inboxMsg, items[len(items)-1].Accumulator))
+			seqNum = new(big.Int).Add(seqNum, big.NewInt(1))
+		}
+		test.FailIfError(t, core.DeliverMessagesAndWait(ctx, arbCore, msgCount, prevAcc, items, delayedMessages, nil))
+		msgCount = seqNum
+		prevAcc = items[len(items)-1].Accumulator
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	defer func(count uint64) { itemsPerChunk = count }(itemsPerChunk)
+	itemsPerChunk = 2
+
+	source, shutdown := monitor.PrepareArbCore(t)
+	defer shutdown()
+	deliverTestInbox(t, source.Core)
+
+	var buf bytes.Buffer
+	trailer, err := Export(source.Core, &buf)
+	test.FailIfError(t, err)
+	msgCount, err := source.Core.GetMessageCount()
+	test.FailIfError(t, err)
+	lastAcc, err := source.Core.GetInboxAcc(new(big.Int).Sub(msgCount, big.NewInt(1)))
+	test.FailIfError(t, err)
+	if trailer.MessageCount.Cmp(msgCount) != 0 || trailer.DelayedCount.Cmp(big.NewInt(3)) != 0 || trailer.Accumulator != lastAcc {
+		t.Fatal("wrong trailer", trailer)
+	}
+	if trailer.Chunks != 3 {
+		t.Error("expected 6 items in 3 chunks but got", trailer.Chunks)
+	}
+
+	if _, err := Import(context.Background(), source.Core, bytes.NewReader(buf.Bytes())); err == nil {
+		t.Error("imported into a non-empty inbox")
+	}
+
+	dest, shutdown := monitor.PrepareArbCore(t)
+	defer shutdown()
+	imported, err := Import(context.Background(), dest.Core, bytes.NewReader(buf.Bytes()))
+	test.FailIfError(t, err)
+	if imported.MessageCount.Cmp(msgCount) != 0 || imported.Accumulator != lastAcc {
+		t.Fatal("wrong imported trailer", imported)
+	}
+
+	for i := int64(0); i < msgCount.Int64(); i++ {
+		expected, err := source.Core.GetInboxAcc(big.NewInt(i))
+		test.FailIfError(t, err)
+		acc, err := dest.Core.GetInboxAcc(big.NewInt(i))
+		test.FailIfError(t, err)
+		if acc != expected {
+			t.Error("wrong inbox accumulator for message", i)
+		}
+	}
+	for i := int64(0); i < 3; i++ {
+		expected, err := source.Core.GetDelayedInboxAcc(big.NewInt(i))
+		test.FailIfError(t, err)
+		acc, err := dest.Core.GetDelayedInboxAcc(big.NewInt(i))
+		test.FailIfError(t, err)
+		if acc != expected {
+			t.Error("wrong delayed accumulator for message", i)
+		}
+	}
+	expectedItems, err := source.Core.GetSequencerBatchItems(big.NewInt(0))
+	test.FailIfError(t, err)
+	items, err := dest.Core.GetSequencerBatchItems(big.NewInt(0))
+	test.FailIfError(t, err)
+	if len(items) != len(expectedItems) {
+		t.Fatal("imported", len(items), "batch items but exported", len(expectedItems))
+	}
+	for i := range items {
+		if !bytes.Equal(items[i].ToBytesWithSeqNum(), expectedItems[i].ToBytesWithSeqNum()) {
+			t.Error("wrong batch item", i)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+/*
+ * Copyright 2022, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inboxarchive
+
+import (
+	"context"
+	"io"
+	"math/big"
+
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/arblog"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+)
+
+var logger = arblog.Logger.With().Str("component", "inboxarchive").Logger()
+
+// itemsPerChunk is how many batch items are read from the database and
+// written to the archive at a time
+var itemsPerChunk uint64 = 1000
+
+// Export writes every sequenced inbox message to w. Delayed messages which
+// haven't been sequenced yet aren't included and will be read from L1 by
+// the importing node.
+func Export(lookup core.ArbCoreLookup, w io.Writer) (*Trailer, error) {
+	msgCount, err := lookup.GetMessageCount()
+	if err != nil {
+		return nil, err
+	}
+	writer, err := NewWriter(w)
+	if err != nil {
+		return nil, err
+	}
+
+	nextSeqNum := big.NewInt(0)
+	delayedCount := big.NewInt(0)
+	var delayedAcc common.Hash
+	var lastAcc common.Hash
+	for {
+		items, err := lookup.GetSequencerBatchItemsPage(nextSeqNum, itemsPerChunk)
+		if err != nil {
+			return nil, err
+		}
+		if len(items) == 0 {
+			break
+		}
+		chunkStart := nextSeqNum.Uint64()
+		count := new(big.Int).Sub(items[len(items)-1].LastSeqNum, nextSeqNum)
+		messages, err := lookup.GetMessages(nextSeqNum, count.Add(count, big.NewInt(1)))
+		if err != nil {
+			return nil, err
+		}
+
+		chunk := &Chunk{}
+		for _, item := range items {
+			chunk.Items = append(chunk.Items, item.ToBytesWithSeqNum())
+			if len(item.SequencerMessage) > 0 {
+				nextSeqNum = new(big.Int).Add(nextSeqNum, big.NewInt(1))
+				continue
+			}
+			for delayedCount.Cmp(item.TotalDelayedCount) < 0 {
+				position := nextSeqNum.Uint64() - chunkStart
+				if position >= uint64(len(messages)) {
+					return nil, errors.Errorf("missing message %v sequenced by delayed item", nextSeqNum)
+				}
+				delayed := inbox.NewDelayedMessage(delayedAcc, messages[position])
+				storedAcc, err := lookup.GetDelayedInboxAcc(delayedCount)
+				if err != nil {
+					return nil, err
+				}
+				if delayed.DelayedSequenceNumber.Cmp(delayedCount) != 0 || delayed.DelayedAccumulator != storedAcc {
+					return nil, errors.Errorf("delayed message %v doesn't match its stored accumulator", delayedCount)
+				}
+				chunk.DelayedMessages = append(chunk.DelayedMessages, delayed.Message)
+				delayedAcc = delayed.DelayedAccumulator
+				delayedCount = new(big.Int).Add(delayedCount, big.NewInt(1))
+				nextSeqNum = new(big.Int).Add(nextSeqNum, big.NewInt(1))
+			}
+		}
+		last := items[len(items)-1]
+		if nextSeqNum.Cmp(new(big.Int).Add(last.LastSeqNum, big.NewInt(1))) != 0 {
+			return nil, errors.Errorf("batch items end at message %v but sequenced %v messages", last.LastSeqNum, nextSeqNum)
+		}
+		if err := writer.WriteChunk(chunk); err != nil {
+			return nil, err
+		}
+		lastAcc = last.Accumulator
+		logger.Info().Str("messages", nextSeqNum.String()).Str("total", msgCount.String()).Msg("exported inbox chunk")
+		if uint64(len(items)) < itemsPerChunk {
+			break
+		}
+	}
+
+	trailer := Trailer{
+		MessageCount: nextSeqNum,
+		DelayedCount: delayedCount,
+		Accumulator:  lastAcc,
+	}
+	if err := writer.Close(trailer); err != nil {
+		return nil, err
+	}
+	trailer.Chunks = writer.chunks
+	return &trailer, nil
+}
+
+// Import delivers the messages in an archive to an empty inbox and waits for
+// each chunk to be accepted. Batch items keep their accumulators so the
+// imported inbox matches the one exported.
+func Import(ctx context.Context, arbCore core.ArbCore, r io.Reader) (*Trailer, error) {
+	msgCount, err := arbCore.GetMessageCount()
+	if err != nil {
+		return nil, err
+	}
+	if msgCount.Sign() != 0 {
+		return nil, errors.Errorf("can only import into an empty inbox, found %v messages", msgCount)
+	}
+	reader, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	prevMsgCount := big.NewInt(0)
+	var prevAcc common.Hash
+	delayedCount := big.NewInt(0)
+	var delayedAcc common.Hash
+	for {
+		chunk, err := reader.Next()
+		if err != nil {
+			return nil, err
+		}
+		if chunk == nil {
+			break
+		}
+		items := make([]inbox.SequencerBatchItem, 0, len(chunk.Items))
+		for _, data := range chunk.Items {
+			item, err := inbox.NewSequencerBatchItemFromData(data)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		delayedMessages := make([]inbox.DelayedMessage, 0, len(chunk.DelayedMessages))
+		for _, data := range chunk.DelayedMessages {
+			msg, err := inbox.NewInboxMessageFromData(data)
+			if err != nil {
+				return nil, err
+			}
+			if msg.InboxSeqNum.Cmp(delayedCount) != 0 {
+				return nil, errors.Errorf("expected delayed message %v but got %v", delayedCount, msg.InboxSeqNum)
+			}
+			delayed := inbox.NewDelayedMessage(delayedAcc, msg)
+			delayedMessages = append(delayedMessages, delayed)
+			delayedAcc = delayed.DelayedAccumulator
+			delayedCount = new(big.Int).Add(delayedCount, big.NewInt(1))
+		}
+		if len(items) == 0 {
+			continue
+		}
+		if err := core.DeliverMessagesAndWait(ctx, arbCore, prevMsgCount, prevAcc, items, delayedMessages, nil); err != nil {
+			return nil, err
+		}
+		last := items[len(items)-1]
+		prevMsgCount = new(big.Int).Add(last.LastSeqNum, big.NewInt(1))
+		prevAcc = last.Accumulator
+		logger.Info().Str("messages", prevMsgCount.String()).Msg("imported inbox chunk")
+	}
+
+	trailer := reader.Trailer()
+	msgCount, err = arbCore.GetMessageCount()
+	if err != nil {
+		return nil, err
+	}
+	if msgCount.Cmp(trailer.MessageCount) != 0 {
+		return nil, errors.Errorf("imported %v messages but archive has %v", msgCount, trailer.MessageCount)
+	}
+	if msgCount.Sign() > 0 {
+		acc, err := arbCore.GetInboxAcc(new(big.Int).Sub(msgCount, big.NewInt(1)))
+		if err != nil {
+			return nil, err
+		}
+		if acc != trailer.Accumulator {
+			return nil, errors.Errorf("imported inbox accumulator %v doesn't match archive %v", acc, trailer.Accumulator)
+		}
+	}
+	return trailer, nil
+}
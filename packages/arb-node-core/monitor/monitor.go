@@ -28,6 +28,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 
 	"github.com/offchainlabs/arbitrum/packages/arb-avm-cpp/cmachine"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/dbmigrate"
 	"github.com/offchainlabs/arbitrum/packages/arb-node-core/ethbridge"
 	"github.com/offchainlabs/arbitrum/packages/arb-node-core/nodehealth"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/broadcaster"
@@ -76,6 +77,9 @@ func NewMonitor(dbDir string, coreConfig *configuration.Core) (*Monitor, error)
 }
 
 func (m *Monitor) Initialize(contractFile string) error {
+	if _, err := dbmigrate.Run(m.Storage, false); err != nil {
+		return err
+	}
 	err := m.Storage.Initialize(contractFile)
 	if err != nil {
 		return err
@@ -85,6 +89,9 @@ func (m *Monitor) Initialize(contractFile string) error {
 }
 
 func (m *Monitor) ApplyConfig() error {
+	if _, err := dbmigrate.Run(m.Storage, false); err != nil {
+		return err
+	}
 	err := m.Storage.ApplyConfig()
 	if err != nil {
 		return err
@@ -0,0 +1,175 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package staker
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/alerts"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/ethbridge"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+)
+
+// alertState remembers what has already been alerted on so that each
+// condition only fires once instead of on every staker iteration
+type alertState struct {
+	invalidNodes        map[string]bool
+	lowBalance          bool
+	lowBalanceThreshold *big.Int
+	unconfirmedStake    *big.Int
+	stakedChallenge     *common.Address
+	deadlineState       common.Hash
+}
+
+func newAlertState(lowBalanceEth float64) *alertState {
+	var threshold *big.Int
+	if lowBalanceEth > 0 {
+		threshold, _ = new(big.Float).Mul(big.NewFloat(lowBalanceEth), big.NewFloat(1e18)).Int(nil)
+	}
+	return &alertState{
+		invalidNodes:        make(map[string]bool),
+		lowBalanceThreshold: threshold,
+	}
+}
+
+func (s *Staker) stakerAddress() common.Address {
+	if addr := s.wallet.Address(); addr != nil {
+		return common.NewAddressFromEth(*addr)
+	}
+	return s.wallet.From()
+}
+
+func (s *Staker) alertInvalidNodes(nodes []*core.NodeInfo) {
+	for _, nd := range nodes {
+		nodeNum := (*big.Int)(nd.NodeNum)
+		if s.alertState.invalidNodes[nodeNum.String()] {
+			continue
+		}
+		s.alertState.invalidNodes[nodeNum.String()] = true
+		s.alerter.Fire(alerts.InvalidNodeKind, alerts.CriticalSeverity, "found node with incorrect assertion", map[string]interface{}{
+			"node":          nodeNum.String(),
+			"nodeHash":      nd.NodeHash.String(),
+			"blockProposed": nd.BlockProposed.Height.AsInt().String(),
+			"strategy":      s.config.StrategyImpl,
+		})
+	}
+}
+
+func (s *Staker) alertChallengeStarted(challengeAddress common.Address, challengedNode *big.Int) {
+	s.alerter.Fire(alerts.ChallengeStartedKind, alerts.CriticalSeverity, "entered challenge", map[string]interface{}{
+		"challenge": challengeAddress.String(),
+		"node":      challengedNode.String(),
+	})
+}
+
+func (s *Staker) checkChallengeDeadline(ctx context.Context, challengeCon *ethbridge.Challenge) error {
+	if !s.alerter.Enabled() || s.config.Alerts.ChallengeDeadlineBlocks <= 0 {
+		return nil
+	}
+	responder, err := challengeCon.CurrentResponder(ctx)
+	if err != nil {
+		return err
+	}
+	if responder != s.stakerAddress() {
+		return nil
+	}
+	challengeState, err := challengeCon.ChallengeState(ctx)
+	if err != nil {
+		return err
+	}
+	if challengeState == s.alertState.deadlineState {
+		return nil
+	}
+	blocksLeft, err := challengeCon.CurrentResponderBlocksLeft(ctx)
+	if err != nil {
+		return err
+	}
+	if blocksLeft.Cmp(big.NewInt(s.config.Alerts.ChallengeDeadlineBlocks)) > 0 {
+		return nil
+	}
+	s.alertState.deadlineState = challengeState
+	s.alerter.Fire(alerts.ChallengeDeadlineKind, alerts.CriticalSeverity, "challenge turn approaching deadline", map[string]interface{}{
+		"challenge":  challengeCon.Address().String(),
+		"blocksLeft": blocksLeft.String(),
+	})
+	return nil
+}
+
+// checkStakeAlerts compares our current stake against what was seen on the
+// previous iteration to detect confirmation of our stake or losing a challenge
+func (s *Staker) checkStakeAlerts(ctx context.Context, info *ethbridge.StakerInfo) error {
+	if !s.alerter.Enabled() {
+		return nil
+	}
+	if info == nil {
+		if s.alertState.stakedChallenge != nil {
+			s.alerter.Fire(alerts.StakeSlashedKind, alerts.CriticalSeverity, "stake removed after challenge", map[string]interface{}{
+				"challenge": s.alertState.stakedChallenge.String(),
+			})
+		}
+		s.alertState.stakedChallenge = nil
+		s.alertState.unconfirmedStake = nil
+		return nil
+	}
+	s.alertState.stakedChallenge = info.CurrentChallenge
+
+	latestConfirmed, err := s.rollup.LatestConfirmedNode(ctx)
+	if err != nil {
+		return err
+	}
+	if s.alertState.unconfirmedStake != nil && s.alertState.unconfirmedStake.Cmp(latestConfirmed) <= 0 {
+		s.alerter.Fire(alerts.StakeConfirmedKind, alerts.InfoSeverity, "node we staked on was confirmed", map[string]interface{}{
+			"node":            s.alertState.unconfirmedStake.String(),
+			"latestConfirmed": latestConfirmed.String(),
+		})
+		s.alertState.unconfirmedStake = nil
+	}
+	if info.LatestStakedNode != nil && info.LatestStakedNode.Cmp(latestConfirmed) > 0 {
+		s.alertState.unconfirmedStake = new(big.Int).Set(info.LatestStakedNode)
+	}
+	return nil
+}
+
+func (s *Staker) checkBalanceAlert(ctx context.Context) error {
+	if !s.alerter.Enabled() || s.alertState.lowBalanceThreshold == nil {
+		return nil
+	}
+	from := s.wallet.From()
+	if from.IsZero() {
+		return nil
+	}
+	balance, err := s.client.BalanceAt(ctx, from.ToEthAddress(), nil)
+	if err != nil {
+		return err
+	}
+	if balance.Cmp(s.alertState.lowBalanceThreshold) >= 0 {
+		s.alertState.lowBalance = false
+		return nil
+	}
+	if s.alertState.lowBalance {
+		return nil
+	}
+	s.alertState.lowBalance = true
+	s.alerter.Fire(alerts.LowBalanceKind, alerts.WarningSeverity, "validator wallet balance below threshold", map[string]interface{}{
+		"address":   from.String(),
+		"balance":   balance.String(),
+		"threshold": s.alertState.lowBalanceThreshold.String(),
+	})
+	return nil
+}
@@ -0,0 +1,63 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package staker
+
+import (
+	"sync"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/arbtransaction"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+)
+
+// StakerGroup coordinates several stakers that share one ArbCore. Members
+// choose and send their transactions one at a time, and members never
+// challenge each other. Receipts are awaited outside the group, so a member
+// may act before another's transaction is mined; a transaction that reverts
+// because of that is retried on the member's next round.
+type StakerGroup struct {
+	mutex   sync.Mutex
+	members map[common.Address]bool
+}
+
+func NewStakerGroup() *StakerGroup {
+	return &StakerGroup{
+		members: make(map[common.Address]bool),
+	}
+}
+
+func (g *StakerGroup) Add(s *Staker) {
+	g.members[s.stakerAddress()] = true
+	s.group = g
+}
+
+func (g *StakerGroup) IsMember(address common.Address) bool {
+	if g == nil {
+		return false
+	}
+	return g.members[address]
+}
+
+// act runs f while no other member of the group is choosing or sending a
+// transaction
+func (g *StakerGroup) act(f func() (*arbtransaction.ArbTransaction, error)) (*arbtransaction.ArbTransaction, error) {
+	if g == nil {
+		return f()
+	}
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return f()
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright 2022, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package staker
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/arbtransaction"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+)
+
+func TestNilStakerGroup(t *testing.T) {
+	var group *StakerGroup
+	if group.IsMember(common.Address{1}) {
+		t.Error("nil group has members")
+	}
+	called := false
+	_, err := group.act(func() (*arbtransaction.ArbTransaction, error) {
+		called = true
+		return nil, nil
+	})
+	if err != nil || !called {
+		t.Error("nil group didn't act")
+	}
+}
+
+func TestStakerGroupMembers(t *testing.T) {
+	group := NewStakerGroup()
+	group.members[common.Address{1}] = true
+	if !group.IsMember(common.Address{1}) {
+		t.Error("member not found")
+	}
+	if group.IsMember(common.Address{2}) {
+		t.Error("unexpected member")
+	}
+}
+
+func TestStakerGroupActsOneAtATime(t *testing.T) {
+	group := NewStakerGroup()
+	var active, maxActive int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = group.act(func() (*arbtransaction.ArbTransaction, error) {
+				current := atomic.AddInt32(&active, 1)
+				for {
+					seen := atomic.LoadInt32(&maxActive)
+					if current <= seen || atomic.CompareAndSwapInt32(&maxActive, seen, current) {
+						break
+					}
+				}
+				atomic.AddInt32(&active, -1)
+				return nil, nil
+			})
+		}()
+	}
+	wg.Wait()
+	if maxActive != 1 {
+		t.Errorf("%v members acted at once", maxActive)
+	}
+}
+
+func TestStakerGroupReleasedAfterSending(t *testing.T) {
+	group := NewStakerGroup()
+	_, err := group.act(func() (*arbtransaction.ArbTransaction, error) {
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first member is still waiting for its receipt, which must not stop
+	// another member from acting
+	acted := make(chan struct{})
+	go func() {
+		_, _ = group.act(func() (*arbtransaction.ArbTransaction, error) {
+			close(acted)
+			return nil, nil
+		})
+	}()
+	<-acted
+}
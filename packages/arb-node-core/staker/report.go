@@ -0,0 +1,92 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package staker
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/challenge"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethutils"
+)
+
+// SetMoveRecorder enables recording of the challenge moves made by this staker
+func (s *Staker) SetMoveRecorder(recorder *challenge.MoveRecorder) {
+	s.moveRecorder = recorder
+}
+
+func (s *Staker) addPendingMove(ctx context.Context, move challenge.Move) error {
+	if s.moveRecorder == nil || move == nil {
+		return nil
+	}
+	challengeCon := s.activeChallenge.Challenge()
+	blocksLeft, err := challengeCon.CurrentResponderBlocksLeft(ctx)
+	if err != nil {
+		return err
+	}
+	currentBlock, err := getBlockID(ctx, s.client, nil)
+	if err != nil {
+		return err
+	}
+	record, err := challenge.NewMoveRecord(challengeCon.Address(), s.stakerAddress(), move, currentBlock.Height.AsInt().Uint64(), blocksLeft)
+	if err != nil {
+		return err
+	}
+	s.pendingMoves = append(s.pendingMoves, record)
+	return nil
+}
+
+// recordMoves stores the moves included in the staker transaction with the given receipt
+func (s *Staker) recordMoves(ctx context.Context, receipt *types.Receipt) {
+	if len(s.pendingMoves) == 0 {
+		return
+	}
+	gasPrice, err := effectiveGasPrice(ctx, s.client, receipt)
+	if err != nil {
+		logger.Warn().Err(err).Msg("error looking up gas price of challenge move")
+		gasPrice = big.NewInt(0)
+	}
+	for _, record := range s.pendingMoves {
+		record.SetReceipt(receipt.TxHash, receipt.BlockNumber.Uint64(), receipt.GasUsed, gasPrice)
+		if err := s.moveRecorder.Record(record); err != nil {
+			logger.Warn().Err(err).Str("challenge", record.Challenge.Hex()).Msg("error recording challenge move")
+		}
+	}
+	s.pendingMoves = nil
+}
+
+func effectiveGasPrice(ctx context.Context, client ethutils.EthClient, receipt *types.Receipt) (*big.Int, error) {
+	tx, _, err := client.TransactionByHash(ctx, receipt.TxHash)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	header, err := client.HeaderByNumber(ctx, receipt.BlockNumber)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	tip, err := tx.EffectiveGasTip(header.BaseFee)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if header.BaseFee == nil {
+		return tip, nil
+	}
+	return tip.Add(tip, header.BaseFee), nil
+}
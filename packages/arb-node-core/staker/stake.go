@@ -0,0 +1,141 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package staker
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/ethbridge"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+)
+
+// stakeTargets returns the deposit we aim to keep and the deposit above which
+// we reduce back down to the target
+func stakeTargets(config configuration.ValidatorStake, currentRequired *big.Int, baseStake *big.Int) (*big.Int, *big.Int) {
+	required := currentRequired
+	if baseStake.Cmp(required) > 0 {
+		required = baseStake
+	}
+	target := multiplyStake(required, config.TargetMultiplier)
+	if target.Cmp(required) < 0 {
+		target = new(big.Int).Set(required)
+	}
+	reduceAbove := multiplyStake(required, config.ReduceMultiplier)
+	if reduceAbove.Cmp(target) < 0 {
+		reduceAbove = new(big.Int).Set(target)
+	}
+	return target, reduceAbove
+}
+
+func multiplyStake(amount *big.Int, multiplier float64) *big.Int {
+	product, _ := new(big.Float).Mul(new(big.Float).SetInt(amount), big.NewFloat(multiplier)).Int(nil)
+	return product
+}
+
+func (s *Staker) currentStakeTargets(ctx context.Context) (*big.Int, *big.Int, error) {
+	currentRequired, err := s.rollup.CurrentRequiredStake(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	baseStake, err := s.rollup.BaseStake(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	target, reduceAbove := stakeTargets(s.config.Stake, currentRequired, baseStake)
+	return target, reduceAbove, nil
+}
+
+// manageStake tops up our deposit when it is below the target and reduces it
+// when requirements have fallen far enough that too much is locked up
+func (s *Staker) manageStake(ctx context.Context, info *ethbridge.StakerInfo) error {
+	if !s.config.Stake.Manage || info == nil || info.CurrentChallenge != nil {
+		// Deposits can't be changed while in a challenge
+		return nil
+	}
+	target, reduceAbove, err := s.currentStakeTargets(ctx)
+	if err != nil {
+		return err
+	}
+	if info.AmountStaked.Cmp(target) < 0 {
+		amount := new(big.Int).Sub(target, info.AmountStaked)
+		logger.Info().
+			Str("staked", info.AmountStaked.String()).
+			Str("target", target.String()).
+			Msg("topping up stake")
+		// This is safe to dereference, as we can only be staked if we have a wallet address
+		return s.rollup.AddToDeposit(ctx, common.NewAddressFromEth(*s.wallet.Address()), amount)
+	}
+	if info.AmountStaked.Cmp(reduceAbove) > 0 {
+		logger.Info().
+			Str("staked", info.AmountStaked.String()).
+			Str("target", target.String()).
+			Msg("reducing stake")
+		// The rollup won't reduce below the current required stake, and the
+		// difference becomes withdrawable funds
+		return s.rollup.ReduceDeposit(ctx, target)
+	}
+	return nil
+}
+
+// newStakeAmount is the deposit to place when creating a new stake
+func (s *Staker) newStakeAmount(ctx context.Context) (*big.Int, error) {
+	if s.config.Stake.Manage {
+		target, _, err := s.currentStakeTargets(ctx)
+		return target, err
+	}
+	return s.rollup.CurrentRequiredStake(ctx)
+}
+
+// withdrawFunds sweeps withdrawable funds to the withdraw destination, no
+// more often than the configured withdraw interval
+func (s *Staker) withdrawFunds(ctx context.Context) error {
+	addr := s.wallet.Address()
+	if addr == nil || s.withdrawDestination == (common.Address{}) {
+		return nil
+	}
+	if s.config.Stake.WithdrawInterval > 0 && time.Since(s.lastWithdrawal) < s.config.Stake.WithdrawInterval {
+		return nil
+	}
+	withdrawable, err := s.rollup.WithdrawableFunds(ctx, common.NewAddressFromEth(*addr))
+	if err != nil {
+		return err
+	}
+	if withdrawable.Sign() <= 0 {
+		return nil
+	}
+	logger.Info().
+		Str("amount", withdrawable.String()).
+		Str("destination", s.withdrawDestination.String()).
+		Msg("withdrawing funds")
+	// The withdraw interval starts once the transaction succeeds
+	s.pendingWithdrawal = true
+	return s.rollup.WithdrawFunds(ctx, s.withdrawDestination)
+}
+
+// recordWithdrawal starts the withdraw interval if the staker transaction with
+// the given receipt withdrew funds
+func (s *Staker) recordWithdrawal(receipt *types.Receipt, now time.Time) {
+	if s.pendingWithdrawal && receipt.Status == types.ReceiptStatusSuccessful {
+		s.lastWithdrawal = now
+	}
+	s.pendingWithdrawal = false
+}
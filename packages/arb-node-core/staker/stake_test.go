@@ -0,0 +1,74 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package staker
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+)
+
+func TestStakeTargets(t *testing.T) {
+	cases := []struct {
+		name            string
+		config          configuration.ValidatorStake
+		currentRequired int64
+		baseStake       int64
+		target          int64
+		reduceAbove     int64
+	}{
+		{"defaults", configuration.ValidatorStake{TargetMultiplier: 1, ReduceMultiplier: 1.5}, 100, 100, 100, 150},
+		{"elevated", configuration.ValidatorStake{TargetMultiplier: 1.2, ReduceMultiplier: 2}, 300, 100, 360, 600},
+		{"base stake larger", configuration.ValidatorStake{TargetMultiplier: 1, ReduceMultiplier: 1.5}, 50, 100, 100, 150},
+		{"clamped", configuration.ValidatorStake{TargetMultiplier: 0.5, ReduceMultiplier: 0}, 100, 100, 100, 100},
+	}
+	for _, c := range cases {
+		target, reduceAbove := stakeTargets(c.config, big.NewInt(c.currentRequired), big.NewInt(c.baseStake))
+		if target.Cmp(big.NewInt(c.target)) != 0 {
+			t.Error(c.name, "wrong target", target)
+		}
+		if reduceAbove.Cmp(big.NewInt(c.reduceAbove)) != 0 {
+			t.Error(c.name, "wrong reduce threshold", reduceAbove)
+		}
+	}
+}
+
+func TestRecordWithdrawal(t *testing.T) {
+	s := &Staker{}
+	now := time.Now()
+
+	s.pendingWithdrawal = true
+	s.recordWithdrawal(&types.Receipt{Status: types.ReceiptStatusFailed}, now)
+	if !s.lastWithdrawal.IsZero() || s.pendingWithdrawal {
+		t.Error("failed withdrawal started the withdraw interval")
+	}
+
+	s.recordWithdrawal(&types.Receipt{Status: types.ReceiptStatusSuccessful}, now)
+	if !s.lastWithdrawal.IsZero() {
+		t.Error("transaction without a withdrawal started the withdraw interval")
+	}
+
+	s.pendingWithdrawal = true
+	s.recordWithdrawal(&types.Receipt{Status: types.ReceiptStatusSuccessful}, now)
+	if !s.lastWithdrawal.Equal(now) || s.pendingWithdrawal {
+		t.Error("confirmed withdrawal didn't start the withdraw interval")
+	}
+}
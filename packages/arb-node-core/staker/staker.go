@@ -24,8 +24,10 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/pkg/errors"
 
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/alerts"
 	"github.com/offchainlabs/arbitrum/packages/arb-node-core/challenge"
 	"github.com/offchainlabs/arbitrum/packages/arb-node-core/cmdhelp"
 	"github.com/offchainlabs/arbitrum/packages/arb-node-core/ethbridge"
@@ -59,6 +61,13 @@ type Staker struct {
 	bringActiveUntilNode    core.NodeID
 	withdrawDestination     common.Address
 	lookup                  core.ArbCoreLookup
+	alerter                 *alerts.Alerter
+	alertState              *alertState
+	group                   *StakerGroup
+	moveRecorder            *challenge.MoveRecorder
+	pendingMoves            []*challenge.MoveRecord
+	lastWithdrawal          time.Time
+	pendingWithdrawal       bool
 }
 
 func NewStaker(
@@ -81,7 +90,7 @@ func NewStaker(
 	if ethcommon.IsHexAddress(config.WithdrawDestination) {
 		withdrawDestination = common.HexToAddress(config.WithdrawDestination)
 	}
-	return &Staker{
+	s := &Staker{
 		Validator:           val,
 		strategy:            strategy,
 		fromBlock:           fromBlock,
@@ -92,7 +101,10 @@ func NewStaker(
 		lastActCalledBlock:  nil,
 		withdrawDestination: withdrawDestination,
 		lookup:              lookup,
-	}, val.delayedBridge, nil
+		alertState:          newAlertState(config.Alerts.LowBalance),
+	}
+	s.alerter = alerts.NewAlerterFromConfig(config.Alerts, wallet.RollupAddress(), s.stakerAddress())
+	return s, val.delayedBridge, nil
 }
 
 func (s *Staker) RunInBackground(ctx context.Context, stakerDelay time.Duration) chan bool {
@@ -103,10 +115,13 @@ func (s *Staker) RunInBackground(ctx context.Context, stakerDelay time.Duration)
 		}()
 		backoff := time.Second
 		for {
-			arbTx, err := s.Act(ctx)
+			arbTx, err := s.group.act(func() (*arbtransaction.ArbTransaction, error) {
+				return s.Act(ctx)
+			})
 			if err == nil && arbTx != nil {
 				// Note: methodName isn't accurate, it's just used for logging
-				_, err = transactauth.WaitForReceiptWithResultsAndReplaceByFee(ctx, s.client, s.wallet.From().ToEthAddress(), arbTx, "for staking", s.auth, s.auth)
+				var receipt *types.Receipt
+				receipt, err = transactauth.WaitForReceiptWithResultsAndReplaceByFee(ctx, s.client, s.wallet.From().ToEthAddress(), arbTx, "for staking", s.auth, s.auth)
 				if err != nil && common.IsFatalError(err) {
 					logger.Error().Err(err).Msg("aborting staker background thread")
 					break
@@ -114,6 +129,8 @@ func (s *Staker) RunInBackground(ctx context.Context, stakerDelay time.Duration)
 				err = errors.Wrap(err, "error waiting for tx receipt")
 				if err == nil {
 					logger.Info().Str("hash", arbTx.Hash().String()).Msg("successfully executed transaction")
+					s.recordMoves(ctx, receipt)
+					s.recordWithdrawal(receipt, time.Now())
 				}
 			}
 			if err != nil {
@@ -203,6 +220,8 @@ func (s *Staker) Act(ctx context.Context) (*arbtransaction.ArbTransaction, error
 		return nil, nil
 	}
 	s.builder.ClearTransactions()
+	s.pendingMoves = nil
+	s.pendingWithdrawal = false
 	var rawInfo *ethbridge.StakerInfo
 	walletAddress := s.wallet.Address()
 	var walletAddressOrZero common.Address
@@ -225,6 +244,12 @@ func (s *Staker) Act(ctx context.Context) (*arbtransaction.ArbTransaction, error
 	if rawInfo != nil {
 		rawInfo.LatestStakedNode = latestStakedNode
 	}
+	if err := s.checkStakeAlerts(ctx, rawInfo); err != nil {
+		logger.Warn().Err(err).Msg("error checking stake for alerts")
+	}
+	if err := s.checkBalanceAlert(ctx); err != nil {
+		logger.Warn().Err(err).Msg("error checking wallet balance for alerts")
+	}
 	info := OurStakerInfo{
 		CanProgress:          true,
 		LatestStakedNode:     latestStakedNode,
@@ -285,18 +310,8 @@ func (s *Staker) Act(ctx context.Context) (*arbtransaction.ArbTransaction, error
 		}
 	}
 
-	addr := s.wallet.Address()
-	if addr != nil {
-		withdrawable, err := s.rollup.WithdrawableFunds(ctx, common.NewAddressFromEth(*addr))
-		if err != nil {
-			return nil, err
-		}
-		if withdrawable.Sign() > 0 && s.withdrawDestination != (common.Address{}) {
-			err = s.rollup.WithdrawFunds(ctx, s.withdrawDestination)
-			if err != nil {
-				return nil, err
-			}
-		}
+	if err := s.withdrawFunds(ctx); err != nil {
+		return nil, err
 	}
 
 	// Don't attempt to create a new stake if we're resolving a node,
@@ -312,6 +327,10 @@ func (s *Staker) Act(ctx context.Context) (*arbtransaction.ArbTransaction, error
 		if err = s.handleConflict(ctx, rawInfo); err != nil {
 			return nil, err
 		}
+		// Top up before advancing so that we have enough stake to place it
+		if err = s.manageStake(ctx, rawInfo); err != nil {
+			return nil, err
+		}
 	}
 	if rawInfo != nil || creatingNewStake {
 		// Advance stake up to 20 times in one transaction
@@ -368,10 +387,21 @@ func (s *Staker) handleConflict(ctx context.Context, info *ethbridge.StakerInfo)
 		// This is safe to dereference, as handleConflict can only be called if we have a wallet address
 		ourAddr := common.NewAddressFromEth(*s.wallet.Address())
 		s.activeChallenge = challenge.NewChallenger(challengeCon, s.sequencerInbox, s.lookup, nodeInfo.Assertion, ourAddr)
+		s.alertChallengeStarted(*info.CurrentChallenge, challengedNode)
+	}
+
+	if err := s.checkChallengeDeadline(ctx, s.activeChallenge.Challenge()); err != nil {
+		logger.Warn().Err(err).Msg("error checking challenge deadline for alerts")
 	}
 
-	_, err := s.activeChallenge.HandleConflict(ctx)
-	return err
+	move, err := s.activeChallenge.HandleConflict(ctx)
+	if err != nil {
+		return err
+	}
+	if err := s.addPendingMove(ctx, move); err != nil {
+		logger.Warn().Err(err).Msg("error preparing challenge move record")
+	}
+	return nil
 }
 
 func (s *Staker) newStake(ctx context.Context) error {
@@ -385,7 +415,7 @@ func (s *Staker) newStake(ctx context.Context) error {
 			return nil
 		}
 	}
-	stakeAmount, err := s.rollup.CurrentRequiredStake(ctx)
+	stakeAmount, err := s.newStakeAmount(ctx)
 	if err != nil {
 		return err
 	}
@@ -400,6 +430,7 @@ func (s *Staker) advanceStake(ctx context.Context, info *OurStakerInfo, effectiv
 	if wrongNodesExist && effectiveStrategy == configuration.WatchtowerStrategy {
 		logger.Error().Msg("found incorrect assertion in watchtower mode")
 	}
+	s.alertInvalidNodes(info.invalidNodes)
 	if action == nil {
 		info.CanProgress = false
 		return nil
@@ -470,6 +501,10 @@ func (s *Staker) createConflict(ctx context.Context, info *ethbridge.StakerInfo)
 		if stakerInfo.CurrentChallenge != nil {
 			continue
 		}
+		if s.group.IsMember(staker) {
+			// Never challenge another validator run by this node
+			continue
+		}
 		conflictType, node1, node2, err := s.validatorUtils.FindStakerConflict(ctx, walletAddr, staker)
 		if err != nil {
 			return err
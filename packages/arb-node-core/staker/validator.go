@@ -237,6 +237,7 @@ type OurStakerInfo struct {
 	LatestStakedNodeHash  [32]byte
 	CanProgress           bool
 	latestExecutionCursor core.ExecutionCursor
+	invalidNodes          []*core.NodeInfo
 	*ethbridge.StakerInfo
 }
 
@@ -363,22 +364,7 @@ func (v *Validator) generateNodeAction(ctx context.Context, stakerInfo *OurStake
 			break
 		}
 		if correctNode == nil {
-			var batchItemEndAcc common.Hash
-			if nd.Assertion.After.TotalMessagesRead.Cmp(nd.AfterInboxBatchEndCount) == 0 {
-				batchItemEndAcc = nd.AfterInboxBatchAcc
-			} else if nd.Assertion.After.TotalMessagesRead.Cmp(big.NewInt(0)) > 0 {
-				var haveBatchEndAcc common.Hash
-				index1 := new(big.Int).Sub(nd.Assertion.After.TotalMessagesRead, big.NewInt(1))
-				index2 := new(big.Int).Sub(nd.AfterInboxBatchEndCount, big.NewInt(1))
-				batchItemEndAcc, haveBatchEndAcc, err = v.lookup.GetInboxAccPair(index1, index2)
-				if err != nil {
-					return nil, false, err
-				}
-				if haveBatchEndAcc != nd.AfterInboxBatchAcc {
-					return nil, false, errors.New("inbox reorg detected by batch end acc mismatch")
-				}
-			}
-			valid, err := core.IsAssertionValid(nd.Assertion, execTracker, batchItemEndAcc)
+			valid, err := core.IsNodeValid(nd, execTracker)
 			if err != nil {
 				return nil, false, err
 			}
@@ -399,6 +385,7 @@ func (v *Validator) generateNodeAction(ctx context.Context, stakerInfo *OurStake
 				continue
 			} else {
 				logger.Warn().Int("node", int((*big.Int)(nd.NodeNum).Int64())).Msg("found node with incorrect assertion")
+				stakerInfo.invalidNodes = append(stakerInfo.invalidNodes, nd)
 			}
 		} else {
 			logger.Warn().Int("node", int((*big.Int)(nd.NodeNum).Int64())).Msg("found younger sibling to correct node")
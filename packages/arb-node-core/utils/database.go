@@ -0,0 +1,127 @@
+/*
+ * Copyright 2022, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/arblog"
+)
+
+var logger = arblog.Logger.With().Str("component", "utils").Logger()
+
+// CopyDatabase creates a copy-on-write fork of the database in src in dst,
+// which must not exist yet. RocksDB never modifies table files once written,
+// so they're hard linked and shared with the source while everything else is
+// copied. Writes to the fork only ever create new files in dst, leaving src
+// untouched, so any number of forks can share one source. Hard links fail
+// across filesystems, so unless allowCopy is set the fork must be on the
+// source's filesystem. Sources open in another process are refused, as their
+// files change while they're copied.
+func CopyDatabase(src, dst string, allowCopy bool) error {
+	if err := checkUnlocked(src); err != nil {
+		return err
+	}
+	if _, err := os.Stat(dst); err == nil {
+		return errors.Errorf("can't copy database into existing path %v", dst)
+	} else if !os.IsNotExist(err) {
+		return errors.WithStack(err)
+	}
+	copiedTables := 0
+	err := filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if entry.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if filepath.Ext(path) == ".sst" {
+			linkErr := os.Link(path, target)
+			if linkErr == nil {
+				return nil
+			}
+			if !allowCopy {
+				return errors.Wrapf(linkErr, "couldn't share table with %v, fork onto its filesystem or allow copying", src)
+			}
+			copiedTables++
+		}
+		return copyFile(path, target)
+	})
+	if err != nil {
+		_ = os.RemoveAll(dst)
+		return errors.WithStack(err)
+	}
+	if copiedTables > 0 {
+		logger.Warn().
+			Int("tables", copiedTables).
+			Str("source", src).
+			Str("destination", dst).
+			Msg("couldn't hard link database tables so copied them instead, copy onto the source's filesystem to share them")
+	}
+	return nil
+}
+
+// checkUnlocked returns an error if another process holds the RocksDB LOCK
+// file of the database in dir. POSIX locks held by this process aren't
+// reported, and opening the file here would release them, so databases this
+// process has open must be closed by the caller before copying.
+func checkUnlocked(dir string) error {
+	lockFile, err := os.Open(filepath.Join(dir, "LOCK"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer lockFile.Close()
+	lock := syscall.Flock_t{Type: syscall.F_WRLCK, Whence: io.SeekStart}
+	if err := syscall.FcntlFlock(lockFile.Fd(), syscall.F_GETLK, &lock); err != nil {
+		return errors.Wrap(err, "error checking database lock")
+	}
+	if lock.Type != syscall.F_UNLCK {
+		return errors.Errorf("database %v is open in process %v, close it before copying", dir, lock.Pid)
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(out.Close())
+}
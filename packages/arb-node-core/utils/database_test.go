@@ -0,0 +1,112 @@
+/*
+ * Copyright 2022, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+)
+
+func TestCopyDatabase(t *testing.T) {
+	src := t.TempDir()
+	test.FailIfError(t, os.MkdirAll(filepath.Join(src, "state"), 0755))
+	files := []string{"CURRENT", "MANIFEST-000001", "state/000005.sst", "state/000006.log"}
+	for _, file := range files {
+		test.FailIfError(t, os.WriteFile(filepath.Join(src, file), []byte(file), 0644))
+	}
+
+	dst := filepath.Join(t.TempDir(), "fork")
+	test.FailIfError(t, CopyDatabase(src, dst, false))
+	for _, file := range files {
+		srcInfo, err := os.Stat(filepath.Join(src, file))
+		test.FailIfError(t, err)
+		dstInfo, err := os.Stat(filepath.Join(dst, file))
+		test.FailIfError(t, err)
+		shared := os.SameFile(srcInfo, dstInfo)
+		if shared != (filepath.Ext(file) == ".sst") {
+			t.Error("wrong sharing for", file, shared)
+		}
+	}
+
+	// Writes to the fork's mutable files don't reach the source
+	test.FailIfError(t, os.WriteFile(filepath.Join(dst, "CURRENT"), []byte("MANIFEST-000002"), 0644))
+	data, err := os.ReadFile(filepath.Join(src, "CURRENT"))
+	test.FailIfError(t, err)
+	if string(data) != "CURRENT" {
+		t.Error("source modified by fork")
+	}
+
+	if err := CopyDatabase(src, dst, false); err == nil {
+		t.Error("copied over existing fork")
+	}
+}
+
+func TestCopyLockedDatabase(t *testing.T) {
+	src := t.TempDir()
+	test.FailIfError(t, os.WriteFile(filepath.Join(src, "LOCK"), nil, 0644))
+	test.FailIfError(t, CopyDatabase(src, filepath.Join(t.TempDir(), "unlocked"), false))
+
+	// POSIX locks don't conflict within a process, so hold it from another
+	cmd := exec.Command(os.Args[0], "-test.run=TestHoldDatabaseLock")
+	cmd.Env = append(os.Environ(), "HOLD_DATABASE_LOCK="+filepath.Join(src, "LOCK"))
+	stdin, err := cmd.StdinPipe()
+	test.FailIfError(t, err)
+	stdout, err := cmd.StdoutPipe()
+	test.FailIfError(t, err)
+	test.FailIfError(t, cmd.Start())
+	defer func() {
+		_ = stdin.Close()
+		_ = cmd.Wait()
+	}()
+	lines := bufio.NewScanner(stdout)
+	for lines.Scan() && lines.Text() != "locked" {
+	}
+	if lines.Text() != "locked" {
+		t.Fatal("lock holder failed", lines.Err())
+	}
+
+	dst := filepath.Join(t.TempDir(), "locked")
+	if err := CopyDatabase(src, dst, false); err == nil {
+		t.Error("copied database open in another process")
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Error("refused copy created", dst)
+	}
+}
+
+// TestHoldDatabaseLock locks a database for TestCopyLockedDatabase until its
+// stdin closes
+func TestHoldDatabaseLock(t *testing.T) {
+	path := os.Getenv("HOLD_DATABASE_LOCK")
+	if path == "" {
+		t.Skip("only run by TestCopyLockedDatabase")
+	}
+	lockFile, err := os.OpenFile(path, os.O_RDWR, 0644)
+	test.FailIfError(t, err)
+	defer lockFile.Close()
+	lock := syscall.Flock_t{Type: syscall.F_WRLCK, Whence: io.SeekStart}
+	test.FailIfError(t, syscall.FcntlFlock(lockFile.Fd(), syscall.F_SETLK, &lock))
+	_, _ = os.Stdout.WriteString("locked\n")
+	_, _ = io.Copy(io.Discard, os.Stdin)
+}
@@ -72,6 +72,13 @@ func (m *Server) SendTransaction(ctx context.Context, tx *types.Transaction) err
 	return errors.New("no batcher defined, cannot send transaction")
 }
 
+// Impersonator returns the batcher if it supports sending from impersonated
+// accounts and nil otherwise
+func (m *Server) Impersonator() batcher.Impersonator {
+	impersonator, _ := m.batch.(batcher.Impersonator)
+	return impersonator
+}
+
 func (m *Server) GetBlockCount() (uint64, error) {
 	latest, err := m.db.BlockCount()
 	if err != nil {
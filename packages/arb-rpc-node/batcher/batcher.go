@@ -83,6 +83,14 @@ type TransactionBatcher interface {
 	Start(context.Context)
 }
 
+// Impersonator is implemented by development batchers which can send unsigned
+// transactions from accounts whose keys they don't hold
+type Impersonator interface {
+	IsImpersonating(account common.Address) bool
+
+	SendImpersonatedTransaction(ctx context.Context, sender common.Address, tx *types.Transaction) (common.Hash, error)
+}
+
 type pendingSentBatch struct {
 	batchTx *arbtransaction.ArbTransaction
 	txes    []*types.Transaction
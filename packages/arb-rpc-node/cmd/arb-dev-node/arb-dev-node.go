@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/cmd/internal"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/arblog"
+	"io"
 	"io/ioutil"
 	golog "log"
 	"math/big"
@@ -31,6 +32,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -94,15 +96,23 @@ func startup() error {
 
 	enablePProf := fs.Bool("pprof", false, "enable profiling server")
 	saveMessages := fs.String("save", "", "save messages")
+	loadMessages := fs.String("load", "", "load messages saved with -save or dev_dumpState into a new chain")
 	walletcount := fs.Int("walletcount", 10, "number of wallets to fund")
 	walletbalance := fs.Int64("walletbalance", 100, "amount of funds in each wallet (Eth)")
 	arbosPath := fs.String("arbos", "", "ArbOS version")
-	enableFees := fs.Bool("with-fees", false, "Run arbos with fees on")
+	enableFees := fs.Bool("with-fees", false, "Run arbos with fees on, which dev_getTransactionGasBreakdown requires")
+	gasBreakdown := fs.Bool("gas-breakdown", false, "include a fee breakdown in transaction receipts. Implies -with-fees")
 	dbDir := fs.String("dbdir", "", "directory to load dev node on. Use temporary if empty")
 	aggStr := fs.String("aggregator", "", "aggregator to use as the sender from this node")
 	initialL1Height := fs.Uint64("l1height", 0, "initial l1 height")
 	chainId64 := fs.Uint64("chainId", 68799, "chain id of chain")
 	tracingNamespace := fs.String("node.rpc.tracing.namespace", "arbtrace", "rpc namespace for tracing api")
+	enableL1Sim := fs.Bool("l1sim", false, "run the bridge contracts on an in-process simulated L1")
+	enableProfile := fs.Bool("profile", false, "collect contract coverage and gas profiles of all transactions")
+	profileLcov := fs.String("profile.lcov", "", "file to write lcov coverage to on exit. Implies -profile")
+	profileFolded := fs.String("profile.folded", "", "file to write folded gas stacks for flamegraphs to on exit. Implies -profile")
+	l1SimDelay := fs.Uint64("l1sim.delay", 0, "number of L1 blocks before delayed messages from the simulated L1 are sequenced")
+	l1SimDelaySeconds := fs.Uint64("l1sim.delay-seconds", 0, "number of seconds before delayed messages from the simulated L1 are sequenced")
 	mnemonic := fs.String(
 		"mnemonic",
 		"jar deny prosper gasp flush glass core corn alarm treat leg smart",
@@ -126,6 +136,11 @@ func startup() error {
 		}()
 	}
 
+	if *gasBreakdown {
+		// Without fees every component is priced at zero
+		*enableFees = true
+	}
+
 	chainId := new(big.Int).SetUint64(*chainId64)
 
 	wallet, accounts, err := internal.InitializeWallet(*mnemonic, *walletcount)
@@ -186,7 +201,25 @@ func startup() error {
 	}
 	defer cancel()
 
-	if deleteDir {
+	// A loaded chain already contains its own setup, so it's used as is
+	newChain := deleteDir && *loadMessages == ""
+	if *loadMessages != "" {
+		if !deleteDir {
+			return errors.New("can only load messages into a new chain")
+		}
+		if *enableL1Sim {
+			return errors.New("can't load messages into a chain running on the simulated L1")
+		}
+		data, err := ioutil.ReadFile(*loadMessages)
+		if err != nil {
+			return errors.Wrap(err, "error reading saved messages")
+		}
+		if err := backend.ImportData(ctx, data); err != nil {
+			return err
+		}
+	}
+
+	if newChain {
 		owner := common.NewAddressFromEth(accounts[0].Address)
 		config := protocol.ChainParams{
 			GracePeriod:               common.NewTimeBlocksInt(3),
@@ -231,20 +264,22 @@ func startup() error {
 		return errors.New("invalid value for deposit amount")
 	}
 	depositSize = depositSize.Mul(depositSize, big.NewInt(*walletbalance))
-	for _, account := range accounts {
-		deposit := message.EthDepositTx{
-			L2Message: message.NewSafeL2Message(message.ContractTransaction{
-				BasicTx: message.BasicTx{
-					MaxGas:      big.NewInt(1000000),
-					GasPriceBid: big.NewInt(0),
-					DestAddress: common.NewAddressFromEth(account.Address),
-					Payment:     depositSize,
-					Data:        nil,
-				},
-			}),
-		}
-		if _, err := backend.AddInboxMessage(ctx, deposit, common.RandAddress()); err != nil {
-			return err
+	if *loadMessages == "" {
+		for _, account := range accounts {
+			deposit := message.EthDepositTx{
+				L2Message: message.NewSafeL2Message(message.ContractTransaction{
+					BasicTx: message.BasicTx{
+						MaxGas:      big.NewInt(1000000),
+						GasPriceBid: big.NewInt(0),
+						DestAddress: common.NewAddressFromEth(account.Address),
+						Payment:     depositSize,
+						Data:        nil,
+					},
+				}),
+			}
+			if _, err := backend.AddInboxMessage(ctx, deposit, common.RandAddress()); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -265,7 +300,7 @@ func startup() error {
 
 	srv := aggregator.NewServer(backend, chainId, db)
 
-	if deleteDir {
+	if newChain {
 		client := web3.NewEthClient(srv, true)
 		arbOwner, err := arboscontracts.NewArbOwner(arbos.ARB_OWNER_ADDRESS, client)
 		if err != nil {
@@ -316,11 +351,38 @@ func startup() error {
 
 	plugins := make(map[string]interface{})
 	plugins["evm"] = dev.NewEVM(backend)
+	cheats := dev.NewCheats(backend)
+	plugins["hardhat"] = cheats
+	plugins["anvil"] = cheats
+	plugins["dev"] = dev.NewStateAPI(backend)
+
+	if *enableProfile || *profileLcov != "" || *profileFolded != "" {
+		profiler, err := dev.NewProfiler(ctx, backend)
+		if err != nil {
+			return err
+		}
+		plugins["profile"] = dev.NewProfileAPI(profiler)
+		defer func() {
+			if err := writeProfile(profiler, *profileLcov, *profileFolded); err != nil {
+				log.Error().Err(err).Msg("error writing profile")
+			}
+		}()
+	}
+
+	if *enableL1Sim {
+		l1Sim, err := dev.NewL1Sim(backend, privateKeys, depositSize, *l1SimDelay, *l1SimDelaySeconds)
+		if err != nil {
+			return err
+		}
+		l1Sim.Start(ctx, time.Second)
+		plugins["l1sim"] = dev.NewL1SimAPI(l1Sim)
+	}
 
 	rpcConfig := web3.DefaultConfig
 	rpcConfig.Mode = configuration.GanacheRpcMode
 	rpcConfig.Tracing.Enable = true
 	rpcConfig.Tracing.Namespace = *tracingNamespace
+	rpcConfig.ReceiptGasBreakdown = *gasBreakdown
 
 	web3Server, err := web3.GenerateWeb3Server(srv, privateKeys, rpcConfig, mon.CoreConfig, plugins, nil)
 	if err != nil {
@@ -353,3 +415,24 @@ func startup() error {
 		return nil
 	}
 }
+
+func writeProfile(profiler *dev.Profiler, lcovPath string, foldedPath string) error {
+	if err := profiler.Update(); err != nil {
+		return err
+	}
+	write := func(path string, writeTo func(io.Writer) error) error {
+		if path == "" {
+			return nil
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		defer f.Close()
+		return writeTo(f)
+	}
+	if err := write(lcovPath, profiler.WriteLcov); err != nil {
+		return err
+	}
+	return write(foldedPath, profiler.WriteFoldedGas)
+}
@@ -17,9 +17,11 @@
 package main
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"encoding/json"
 	"flag"
+	"fmt"
 	gethlog "github.com/ethereum/go-ethereum/log"
 	"github.com/offchainlabs/arbitrum/packages/arb-evm/arbos"
 	"github.com/offchainlabs/arbitrum/packages/arb-evm/message"
@@ -42,6 +44,7 @@ import (
 	"path/filepath"
 
 	"github.com/offchainlabs/arbitrum/packages/arb-node-core/cmdhelp"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/utils"
 	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/dev"
 )
 
@@ -88,6 +91,14 @@ func startup() error {
 	)
 	fs.Bool("prettyprint", true, "pretty log output")
 	persistState := fs.Bool("persist-state", false, "chain id of chain")
+	forkFrom := fs.String("fork-from", "", "database to fork, shared copy-on-write with dbdir so that it isn't modified")
+	forkCopy := fs.Bool("fork-copy", false, "copy tables of fork-from that can't be shared with dbdir, such as across filesystems, instead of failing")
+	forkBlock := fs.Int64("fork-block", -1, "L2 block to fork at, defaults to the latest")
+	forkBlockHash := fs.String("fork-block-hash", "", "hash of the L2 block to fork at")
+	forkMessage := fs.Int64("fork-message", -1, "index of the last inbox message to keep in the fork")
+	upgradeFile := fs.String("upgrade", "", "rehearse the ArbOS upgrade in this file on forks of fork-from instead of running a node")
+	upgradeMexe := fs.String("upgrade.mexe", "", "ArbOS mexe the upgrade produces")
+	upgradeReplay := fs.Uint64("upgrade.replay", 100, "number of most recent inbox messages to replay before and after the upgrade")
 	gethLogLevel, arbLogLevel := cmdhelp.AddLogFlags(fs)
 
 	err := fs.Parse(os.Args[1:])
@@ -108,6 +119,30 @@ func startup() error {
 
 	chainId := new(big.Int).SetUint64(*chainId64)
 
+	if *upgradeFile != "" {
+		if *forkFrom == "" || *upgradeMexe == "" {
+			return errors.New("upgrade rehearsal requires fork-from and upgrade.mexe")
+		}
+		return rehearseUpgrade(ctx, *forkFrom, chainId, *upgradeFile, *upgradeMexe, *upgradeReplay)
+	}
+
+	var point dev.ForkPoint
+	if *forkBlock >= 0 {
+		block := uint64(*forkBlock)
+		point.Block = &block
+	}
+	if *forkBlockHash != "" {
+		hash := common.HexToHash(*forkBlockHash)
+		point.BlockHash = &hash
+	}
+	if *forkMessage >= 0 {
+		index := uint64(*forkMessage)
+		point.Message = &index
+	}
+	if err := point.Validate(); err != nil {
+		return err
+	}
+
 	wallet, accounts, err := internal.InitializeWallet(*mnemonic, *walletcount)
 	if err != nil {
 		return err
@@ -123,6 +158,19 @@ func startup() error {
 		agg = common.NewAddressFromEth(accounts[1].Address)
 
 	}
+	if *dbDir == "" {
+		if *forkFrom == "" {
+			return errors.New("must specify dbdir or fork-from")
+		}
+		tmpDir, err := dev.ForkWorkDir(*forkFrom, "arb-fork-node")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmpDir)
+		*dbDir = filepath.Join(tmpDir, "db")
+		logger.Info().Str("dbdir", *dbDir).Msg("Using temporary fork database, discarded on exit")
+	}
+
 	type forkInfo struct {
 		LastMessage int64 `json:"last_block"`
 	}
@@ -134,8 +182,20 @@ func startup() error {
 		if err := json.Unmarshal(forkData, &fork); err != nil {
 			return err
 		}
+		if *forkFrom != "" || *forkBlock >= 0 || *forkBlockHash != "" || *forkMessage >= 0 {
+			return errors.Errorf("%v already forked, fork options can't be changed", *dbDir)
+		}
 	} else {
-		msgCount, err := dev.GetMessageCount(*dbDir)
+		if *forkFrom != "" {
+			if _, err := os.Stat(*dbDir); err == nil {
+				return errors.Errorf("can't fork %v into existing directory %v", *forkFrom, *dbDir)
+			}
+			logger.Info().Str("source", *forkFrom).Str("dbdir", *dbDir).Msg("Forking database")
+			if err := utils.CopyDatabase(*forkFrom, *dbDir, *forkCopy); err != nil {
+				return err
+			}
+		}
+		msgCount, err := dev.ForkMessageCount(*dbDir, point)
 		if err != nil {
 			return err
 		}
@@ -192,6 +252,9 @@ func startup() error {
 
 	plugins := make(map[string]interface{})
 	plugins["evm"] = dev.NewEVM(backend)
+	cheats := dev.NewCheats(backend)
+	plugins["hardhat"] = cheats
+	plugins["anvil"] = cheats
 
 	privateKeys := make([]*ecdsa.PrivateKey, 0)
 	for _, account := range accounts {
@@ -264,3 +327,31 @@ func startup() error {
 		return nil
 	}
 }
+
+func rehearseUpgrade(ctx context.Context, source string, chainId *big.Int, upgradeFile, targetMexe string, replay uint64) error {
+	upgrade, err := dev.LoadArbOSUpgrade(upgradeFile, targetMexe)
+	if err != nil {
+		return err
+	}
+
+	workDir, err := dev.ForkWorkDir(source, "arb-upgrade-rehearsal")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+
+	report, err := dev.RehearseArbOSUpgrade(ctx, source, workDir, chainId, upgrade, replay)
+	if err != nil {
+		return err
+	}
+	reportData, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	fmt.Println(string(reportData))
+	if len(report.Differences) > 0 {
+		return errors.Errorf("upgrade changed the results of %v of %v replayed messages", len(report.Differences), report.Replayed)
+	}
+	logger.Info().Int("replayed", report.Replayed).Msg("upgrade rehearsal found no differences")
+	return nil
+}
@@ -46,6 +46,8 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/pkgerrors"
 
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/backup"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/challenge"
 	"github.com/offchainlabs/arbitrum/packages/arb-node-core/cmdhelp"
 	"github.com/offchainlabs/arbitrum/packages/arb-node-core/ethbridge"
 	"github.com/offchainlabs/arbitrum/packages/arb-node-core/metrics"
@@ -146,19 +148,19 @@ func startup() error {
 			return err
 		}
 
-		if config.Validator.OnlyCreateWalletContract {
-			// Just create validator smart wallet if needed then exit
-			_, err := startValidator(ctx, config, walletConfig, l1Client, validatorAuth, nil)
-			if err != nil {
-				return err
-			}
-
-			return errors.New("missing message when only-create-wallet-contract set")
-		}
 	} else {
 		// No wallet, so just use empty auth object
 		validatorAuth = &bind.TransactOpts{}
 	}
+	if config.Node.Type() == configuration.ValidatorNodeType && config.Validator.OnlyCreateWalletContract {
+		// Just create validator smart wallets if needed then exit
+		_, err := startValidators(ctx, config, walletConfig, l1Client, l1ChainId, validatorAuth, nil)
+		if err != nil {
+			return err
+		}
+
+		return errors.New("missing message when only-create-wallet-contract set")
+	}
 
 	if config.BridgeUtilsAddress == "" {
 		return errors.Errorf("Missing --bridge-utils-address")
@@ -202,9 +204,18 @@ func startup() error {
 		} else if config.Validator.Strategy() == configuration.UnknownStrategy {
 			return errors.Errorf("Unrecognized --validator.strategy %s, should be Watchtower, Defensive, StakeLatest, or MakeNodes", config.Validator.StrategyImpl)
 		}
+		for i, additional := range config.Validator.Additional {
+			additionalConfig := config.Validator.ForAdditional(additional)
+			if additionalConfig.Strategy() == configuration.UnknownStrategy {
+				return errors.Errorf("Unrecognized strategy %s for additional validator %v, should be Watchtower, Defensive, StakeLatest, or MakeNodes", additionalConfig.StrategyImpl, i+1)
+			}
+		}
 	} else {
 		return errors.Errorf("Unrecognized node type %s", config.Node.TypeImpl)
 	}
+	if config.Core.Database.Secondary.Enable && config.Node.Type() != configuration.ForwarderNodeType {
+		return errors.New("Secondary database only supported with --node.type=forwarder")
+	}
 
 	if config.Node.Sequencer.Dangerous != (configuration.SequencerDangerous{}) {
 		logger.
@@ -249,6 +260,10 @@ func startup() error {
 	if err != nil {
 		return err
 	}
+	if config.Core.Database.Secondary.Enable {
+		defer mon.Close()
+		return startSecondary(ctx, config, mon, l2ChainId, rpcMode, cancelChan)
+	}
 	if err := mon.Initialize(config.Rollup.Machine.Filename); err != nil {
 		return err
 	}
@@ -257,6 +272,13 @@ func startup() error {
 	}
 	defer mon.Close()
 
+	if config.Core.Database.Backup.Enable {
+		backupManager := backup.NewManager(config.Core.Database.SavePath, config.Core.Database.Backup)
+		if err := backupManager.Start(ctx, time.Minute); err != nil {
+			return err
+		}
+	}
+
 	metricsConfig := metrics.NewMetricsConfig(config.MetricsServer, &config.Healthcheck.MetricsPrefix)
 
 	var healthChan chan nodehealth.Log
@@ -349,12 +371,20 @@ func startup() error {
 
 	var dataSigner func([]byte) ([]byte, error)
 	var batcherMode rpc.BatcherMode
-	var stakerManager *staker.Staker
+	var stakerManagers []*staker.Staker
+	var moveRecorder *challenge.MoveRecorder
 	if config.Node.Type() == configuration.ValidatorNodeType {
-		stakerManager, err = startValidator(ctx, config, walletConfig, l1Client, validatorAuth, mon)
+		stakerManagers, err = startValidators(ctx, config, walletConfig, l1Client, l1ChainId, validatorAuth, mon)
 		if err != nil {
 			return err
 		}
+		moveRecorder, err = challenge.NewMoveRecorder(config.Validator.ChallengeMovesFilename)
+		if err != nil {
+			return err
+		}
+		for _, stakerManager := range stakerManagers {
+			stakerManager.SetMoveRecorder(moveRecorder)
+		}
 		batcherMode = rpc.ErrorBatcherMode{Error: errors.New("validator doesn't support transactions")}
 	} else if config.Node.Type() == configuration.ForwarderNodeType {
 		logger.Info().Str("forwardTxURL", config.Node.Forwarder.Target).Msg("Arbitrum node starting in forwarder mode")
@@ -470,12 +500,25 @@ func startup() error {
 		if !filepath.IsAbs(basedir) {
 			basedir = path.Join(config.Persistent.Chain, basedir)
 		}
-		exportServer, err := nitroexport.NewExportRPCServer(ctx, db, mon.Core, basedir, l2ChainId)
+		delayedBridge, err := rollup.DelayedBridge(ctx)
+		if err != nil {
+			return err
+		}
+		l1Outbox := &nitroexport.L1OutboxConfig{
+			Client:          l1Client,
+			BridgeAddress:   delayedBridge.ToEthAddress(),
+			FromBlock:       config.Rollup.FromBlock,
+			BlockSearchSize: config.Rollup.BlockSearchSize,
+		}
+		exportServer, err := nitroexport.NewExportRPCServer(ctx, db, mon.Core, basedir, l2ChainId, l1Outbox)
 		if err != nil {
 			return err
 		}
 		plugins["arb"] = exportServer
 	}
+	if moveRecorder != nil {
+		plugins["arbvalidator"] = challenge.NewReportRPCServer(moveRecorder)
+	}
 
 	srv := aggregator.NewServer(batch, l2ChainId, db)
 	serverConfig := web3.ServerConfig{
@@ -550,11 +593,12 @@ func startup() error {
 		}()
 	}
 
-	var stakerDone chan bool
-	if stakerManager != nil {
-		stakerDone = stakerManager.RunInBackground(ctx, config.Validator.StakerDelay)
-	} else {
-		stakerDone = make(chan bool)
+	stakerDone := make(chan bool, len(stakerManagers))
+	for _, stakerManager := range stakerManagers {
+		done := stakerManager.RunInBackground(ctx, config.Validator.StakerDelay)
+		go func() {
+			stakerDone <- <-done
+		}()
 	}
 
 	select {
@@ -575,6 +619,69 @@ func startup() error {
 	}
 }
 
+// startSecondary serves read only RPC requests from a database written by a
+// primary node running on the same machine, forwarding transactions if a
+// forwarder target is set
+func startSecondary(
+	ctx context.Context,
+	config *configuration.Config,
+	mon *monitor.Monitor,
+	l2ChainId *big.Int,
+	rpcMode configuration.RpcMode,
+	cancelChan chan bool,
+) error {
+	if !mon.Storage.Initialized() {
+		return errors.New("secondary database opened before primary node initialized it")
+	}
+	logger.Info().
+		Str("secondaryPath", config.Core.Database.Secondary.Path).
+		Str("forwardTxURL", config.Node.Forwarder.Target).
+		Msg("Arbitrum node starting with read only secondary database")
+
+	db, txDBErrChan, err := txdb.NewSecondary(ctx, mon.Core, mon.Storage, &config.Node, config.Core.Database.Secondary.PollInterval)
+	if err != nil {
+		return errors.Wrap(err, "error opening txdb")
+	}
+	defer db.Close()
+
+	var batch batcher.TransactionBatcher
+	if config.Node.Forwarder.Target != "" {
+		batch, err = batcher.NewForwarder(ctx, config.Node.Forwarder)
+		if err != nil {
+			return err
+		}
+		go batch.Start(ctx)
+	}
+
+	srv := aggregator.NewServer(batch, l2ChainId, db)
+	serverConfig := web3.ServerConfig{
+		Mode:          rpcMode,
+		MaxCallAVMGas: config.Node.RPC.MaxCallGas * 100, // Multiply by 100 for arb gas to avm gas conversion
+		Tracing:       config.Node.RPC.Tracing,
+		DevopsStubs:   config.Node.RPC.EnableDevopsStubs,
+	}
+	web3Server, err := web3.GenerateWeb3Server(srv, nil, serverConfig, mon.CoreConfig, nil, nil)
+	if err != nil {
+		return err
+	}
+	errChan := make(chan error, 1)
+	go func() {
+		err := rpc.LaunchPublicServer(ctx, web3Server, config.Node.RPC, config.Node.WS)
+		if err != nil {
+			errChan <- err
+		}
+	}()
+
+	select {
+	case err := <-txDBErrChan:
+		return err
+	case err := <-errChan:
+		return err
+	case <-cancelChan:
+		return nil
+	}
+}
+
 func checkBlockHash(ctx context.Context, clnt *ethclient.Client, db *txdb.TxDB) (bool, error) {
 	if clnt == nil {
 		return false, errors.New("need a client to check block hash")
@@ -609,36 +716,95 @@ type ChainState struct {
 	ValidatorWallet string `json:"validatorWallet"`
 }
 
+// startValidators starts the main validator along with any additional
+// validators, all sharing the monitor's ArbCore and coordinating through one
+// staker group so that they never challenge each other
+func startValidators(
+	ctx context.Context,
+	config *configuration.Config,
+	walletConfig *configuration.Wallet,
+	l1Client ethutils.EthClient,
+	l1ChainId *big.Int,
+	auth *bind.TransactOpts,
+	mon *monitor.Monitor,
+) ([]*staker.Staker, error) {
+	group := staker.NewStakerGroup()
+	validatorConfigs := []configuration.Validator{config.Validator}
+	walletConfigs := []*configuration.Wallet{walletConfig}
+	auths := []*bind.TransactOpts{auth}
+	for i, additional := range config.Validator.Additional {
+		additionalConfig := config.Validator.ForAdditional(additional)
+		additionalWallet := &configuration.Wallet{Local: additional.Wallet}
+		additionalAuth := &bind.TransactOpts{}
+		if additionalConfig.Strategy() != configuration.WatchtowerStrategy {
+			var err error
+			additionalAuth, _, err = getKeystore(config, additionalWallet, l1ChainId, false)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error loading wallet for additional validator %v", i+1)
+			}
+		}
+		validatorConfigs = append(validatorConfigs, additionalConfig)
+		walletConfigs = append(walletConfigs, additionalWallet)
+		auths = append(auths, additionalAuth)
+	}
+
+	var stakers []*staker.Staker
+	var onlyCreateMessages []string
+	for i := range validatorConfigs {
+		stakerManager, err := startValidator(ctx, config, validatorConfigs[i], walletConfigs[i], l1Client, auths[i], mon)
+		if config.Validator.OnlyCreateWalletContract {
+			// Every validator reports what happened to its wallet as an error
+			if err != nil {
+				onlyCreateMessages = append(onlyCreateMessages, err.Error())
+			}
+			continue
+		}
+		if err != nil {
+			if i > 0 {
+				return nil, errors.Wrapf(err, "error starting additional validator %v", i)
+			}
+			return nil, err
+		}
+		group.Add(stakerManager)
+		stakers = append(stakers, stakerManager)
+	}
+	if config.Validator.OnlyCreateWalletContract {
+		return nil, errors.New(strings.Join(onlyCreateMessages, "\n"))
+	}
+	return stakers, nil
+}
+
 func startValidator(
 	ctx context.Context,
 	config *configuration.Config,
+	validatorConfig configuration.Validator,
 	walletConfig *configuration.Wallet,
 	l1Client ethutils.EthClient,
 	auth *bind.TransactOpts,
 	mon *monitor.Monitor,
 ) (*staker.Staker, error) {
-	if len(config.Validator.UtilsAddress) == 0 ||
-		len(config.Validator.WalletFactoryAddress) == 0 || config.Validator.Strategy() == configuration.UnknownStrategy {
+	if len(validatorConfig.UtilsAddress) == 0 ||
+		len(validatorConfig.WalletFactoryAddress) == 0 || validatorConfig.Strategy() == configuration.UnknownStrategy {
 		return nil, errors.New("Contract addresses and strategy required for validator")
 	}
 
 	rollupAddr := ethcommon.HexToAddress(config.Rollup.Address)
-	validatorUtilsAddr := ethcommon.HexToAddress(config.Validator.UtilsAddress)
-	validatorWalletFactoryAddr := ethcommon.HexToAddress(config.Validator.WalletFactoryAddress)
+	validatorUtilsAddr := ethcommon.HexToAddress(validatorConfig.UtilsAddress)
+	validatorWalletFactoryAddr := ethcommon.HexToAddress(validatorConfig.WalletFactoryAddress)
 
 	chainState := ChainState{}
-	if config.Validator.ContractWalletAddress != "" {
-		if !ethcommon.IsHexAddress(config.Validator.ContractWalletAddress) {
-			logger.Error().Str("address", config.Validator.ContractWalletAddress).Msg("invalid validator smart contract wallet")
+	if validatorConfig.ContractWalletAddress != "" {
+		if !ethcommon.IsHexAddress(validatorConfig.ContractWalletAddress) {
+			logger.Error().Str("address", validatorConfig.ContractWalletAddress).Msg("invalid validator smart contract wallet")
 			return nil, errors.New("invalid validator smart contract wallet address")
 		}
-		chainState.ValidatorWallet = config.Validator.ContractWalletAddress
+		chainState.ValidatorWallet = validatorConfig.ContractWalletAddress
 	} else {
-		chainStateFile, err := os.Open(config.Validator.ContractWalletAddressFilename)
+		chainStateFile, err := os.Open(validatorConfig.ContractWalletAddressFilename)
 		if err != nil {
 			// If file doesn't exist yet, will be created when needed
 			if !os.IsNotExist(err) {
-				return nil, errors.Wrap(err, "failed to open chainState file: "+config.Validator.ContractWalletAddressFilename)
+				return nil, errors.Wrap(err, "failed to open chainState file: "+validatorConfig.ContractWalletAddressFilename)
 			}
 		} else {
 			chainStateData, err := ioutil.ReadAll(chainStateFile)
@@ -679,26 +845,26 @@ func startValidator(
 		if owner != valAuth.From() {
 			return nil, fmt.Errorf("validator smart contract wallet owner %v doesn't match validator wallet %v", owner, valAuth.From())
 		}
-	} else if config.Validator.OnlyCreateWalletContract {
+	} else if validatorConfig.OnlyCreateWalletContract {
 		logger.Info().Msg("only creating validator smart contract and exiting")
 	} else {
 		return nil, errors.New("validator smart contract wallet not present, add --validator.only-create-wallet-contract to create")
 	}
 
 	onValidatorWalletCreated := func(addr ethcommon.Address) {}
-	if config.Validator.ContractWalletAddress == "" {
+	if validatorConfig.ContractWalletAddress == "" {
 		onValidatorWalletCreated = func(addr ethcommon.Address) {
 			chainState.ValidatorWallet = addr.String()
 			newChainStateData, err := json.Marshal(chainState)
 			if err != nil {
 				logger.Warn().Err(err).Msg("failed to marshal chain state")
-			} else if err := ioutil.WriteFile(config.Validator.ContractWalletAddressFilename, newChainStateData, 0644); err != nil {
+			} else if err := ioutil.WriteFile(validatorConfig.ContractWalletAddressFilename, newChainStateData, 0644); err != nil {
 				logger.Warn().Err(err).Msg("failed to write chain state config")
 			}
 			logger.
 				Info().
 				Str("address", chainState.ValidatorWallet).
-				Str("filename", config.Validator.ContractWalletAddressFilename).
+				Str("filename", validatorConfig.ContractWalletAddressFilename).
 				Msg("created validator smart contract wallet")
 		}
 	} else {
@@ -712,7 +878,7 @@ func startValidator(
 		return nil, errors.Wrap(err, "error creating validator")
 	}
 
-	if config.Validator.OnlyCreateWalletContract {
+	if validatorConfig.OnlyCreateWalletContract {
 		// Create validator smart contract wallet if needed then exit
 		oldValidatorWallet := chainState.ValidatorWallet
 		err = val.CreateWalletIfNeeded(ctx)
@@ -726,11 +892,11 @@ func startValidator(
 		return nil, errors.Errorf("validator smart contract wallet (%v) created, remove --validator.only-create-wallet-contract to run normally", chainState.ValidatorWallet)
 	}
 
-	stakerManager, _, err := staker.NewStaker(ctx, mon.Core, l1Client, val, config.Rollup.FromBlock, common.NewAddressFromEth(validatorUtilsAddr), config.Validator.Strategy(), bind.CallOpts{}, valAuth, config.Validator)
+	stakerManager, _, err := staker.NewStaker(ctx, mon.Core, l1Client, val, config.Rollup.FromBlock, common.NewAddressFromEth(validatorUtilsAddr), validatorConfig.Strategy(), bind.CallOpts{}, valAuth, validatorConfig)
 	if err != nil {
 		return nil, errors.Wrap(err, "error setting up staker")
 	}
 
-	logger.Info().Str("strategy", config.Validator.StrategyImpl).Msg("Initialized validator")
+	logger.Info().Str("strategy", validatorConfig.StrategyImpl).Msg("Initialized validator")
 	return stakerManager, nil
 }
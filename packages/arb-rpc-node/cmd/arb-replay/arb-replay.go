@@ -0,0 +1,174 @@
+/*
+ * Copyright 2022, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	golog "log"
+	"math/big"
+	"os"
+
+	gethlog "github.com/ethereum/go-ethereum/log"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/pkgerrors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/cmdhelp"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/monitor"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/dev"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/replay"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/arblog"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+)
+
+var logger = arblog.Logger.With().Str("component", "arb-replay").Logger()
+
+func main() {
+	// Enable line numbers in logging
+	golog.SetFlags(golog.LstdFlags | golog.Lshortfile)
+
+	// Print stack trace when `.Error().Stack().Err(err).` is added to zerolog call
+	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
+
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	if err := startup(); err != nil {
+		logger.Error().Err(err).Msg("Error running arb-replay")
+		os.Exit(1)
+	}
+}
+
+func startup() error {
+	ctx, cancelFunc, _ := cmdhelp.CreateLaunchContext()
+	defer cancelFunc()
+
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	dbDir := fs.String("dbdir", "", "database to replay")
+	start := fs.Uint64("start", 0, "first L2 block to replay")
+	end := fs.Int64("end", -1, "last L2 block to replay, defaults to the latest")
+	mexe := fs.String("mexe", "", "compare the range with and without upgrading to this ArbOS build at the stored checkpoint before start")
+	upgradeFile := fs.String("upgrade", "", "ArbOS upgrade in the format used by arb-cli which installs mexe")
+	chainId64 := fs.Uint64("chainId", 42161, "chain id of the chain, used to send the upgrade")
+	gethLogLevel, arbLogLevel := cmdhelp.AddLogFlags(fs)
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		return errors.Wrap(err, "error parsing arguments")
+	}
+	if err := cmdhelp.ParseLogFlags(gethLogLevel, arbLogLevel, gethlog.StreamHandler(os.Stderr, gethlog.TerminalFormat(true))); err != nil {
+		return err
+	}
+	if *dbDir == "" {
+		fmt.Printf("Sample usage: %s -dbdir=.arbitrum/mainnet/db -start=100 -end=200 [-mexe=arbos.mexe -upgrade=upgrade.json]\n", os.Args[0])
+		return nil
+	}
+	if (*mexe == "") != (*upgradeFile == "") {
+		return errors.New("mexe and upgrade must be used together")
+	}
+
+	mon, err := monitor.NewMonitor(*dbDir, configuration.DefaultCoreSettingsMaxExecution())
+	if err != nil {
+		return errors.Wrap(err, "error opening monitor")
+	}
+	replayer, blocks, err := newReplayer(mon, *start, *end)
+	if err != nil {
+		mon.Close()
+		return err
+	}
+	mach, messagesRead, logCount, err := replayer.CheckpointMachine(blocks)
+	if err != nil {
+		mon.Close()
+		return err
+	}
+
+	if *mexe != "" {
+		endMessage, err := replayer.EndMessage(blocks)
+		// The database is forked to apply the upgrade, which needs it closed
+		mon.Close()
+		if err != nil {
+			return err
+		}
+		chainId := new(big.Int).SetUint64(*chainId64)
+		return rehearseUpgrade(ctx, *dbDir, chainId, *upgradeFile, *mexe, messagesRead, endMessage)
+	}
+	defer mon.Close()
+
+	divergence, err := replayer.Replay(ctx, mach, messagesRead, logCount, blocks)
+	if err != nil {
+		return err
+	}
+	if divergence == nil {
+		logger.Info().Uint64("start", blocks.Start).Uint64("end", blocks.End).Msg("replay matched stored history")
+		return nil
+	}
+	divergenceData, err := json.MarshalIndent(divergence, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	fmt.Println(string(divergenceData))
+	return errors.Errorf("replay diverged at log %v", divergence.LogIndex)
+}
+
+func newReplayer(mon *monitor.Monitor, start uint64, end int64) (*replay.Replayer, replay.Range, error) {
+	store := mon.Storage.GetNodeStore()
+	blocks := replay.Range{Start: start}
+	if end >= 0 {
+		blocks.End = uint64(end)
+	} else {
+		blockCount, err := store.BlockCount()
+		if err != nil {
+			return nil, blocks, err
+		}
+		if blockCount == 0 {
+			return nil, blocks, errors.New("database has no blocks")
+		}
+		blocks.End = blockCount - 1
+	}
+	return replay.NewReplayer(mon.Core, store), blocks, nil
+}
+
+// rehearseUpgrade replays the messages of the range on two forks of the
+// database at the checkpoint before it, one upgraded to the new ArbOS, and
+// compares their results
+func rehearseUpgrade(ctx context.Context, dbDir string, chainId *big.Int, upgradeFile, mexe string, startMessage, endMessage uint64) error {
+	upgrade, err := dev.LoadArbOSUpgrade(upgradeFile, mexe)
+	if err != nil {
+		return err
+	}
+	workDir, err := dev.ForkWorkDir(dbDir, "arb-replay")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+
+	report, err := dev.RehearseArbOSUpgradeRange(ctx, dbDir, workDir, chainId, upgrade, startMessage, endMessage)
+	if err != nil {
+		return err
+	}
+	reportData, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	fmt.Println(string(reportData))
+	if len(report.Differences) > 0 {
+		return errors.Errorf("upgrade changed the results of %v of %v replayed messages", len(report.Differences), report.Replayed)
+	}
+	logger.Info().Int("replayed", report.Replayed).Msg("upgrade changed no results")
+	return nil
+}
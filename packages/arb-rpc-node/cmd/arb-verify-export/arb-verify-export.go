@@ -0,0 +1,80 @@
+/*
+ * Copyright 2022, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	golog "log"
+	"os"
+
+	gethlog "github.com/ethereum/go-ethereum/log"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/pkgerrors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/cmdhelp"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/nitroexport"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/arblog"
+)
+
+var logger = arblog.Logger.With().Str("component", "arb-verify-export").Logger()
+
+func main() {
+	// Enable line numbers in logging
+	golog.SetFlags(golog.LstdFlags | golog.Lshortfile)
+
+	// Print stack trace when `.Error().Stack().Err(err).` is added to zerolog call
+	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
+
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	if err := startup(); err != nil {
+		logger.Error().Err(err).Msg("Error verifying state export")
+		os.Exit(1)
+	}
+}
+
+func startup() error {
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	dir := fs.String("dir", "", "state export directory to verify")
+	gethLogLevel, arbLogLevel := cmdhelp.AddLogFlags(fs)
+
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		return errors.Wrap(err, "error parsing arguments")
+	}
+	if err := cmdhelp.ParseLogFlags(gethLogLevel, arbLogLevel, gethlog.StreamHandler(os.Stderr, gethlog.TerminalFormat(true))); err != nil {
+		return err
+	}
+	if *dir == "" {
+		fmt.Printf("Sample usage: %s -dir=.arbitrum/mainnet/nitroexport/state/0x1\n", os.Args[0])
+		return nil
+	}
+
+	totals, err := nitroexport.VerifyState(*dir)
+	if err != nil {
+		return err
+	}
+	totalsData, err := json.MarshalIndent(totals, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	fmt.Println(string(totalsData))
+	logger.Info().Str("dir", *dir).Msg("state export verified")
+	return nil
+}
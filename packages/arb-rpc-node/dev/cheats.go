@@ -0,0 +1,207 @@
+/*
+* Copyright 2021, Offchain Labs, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package dev
+
+import (
+	"context"
+	"math/big"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/arbos"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/message"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+)
+
+// Cheats implements the Hardhat and Anvil state manipulation RPCs. It is
+// registered under both the hardhat and anvil namespaces.
+type Cheats struct {
+	backend *Backend
+}
+
+func NewCheats(backend *Backend) *Cheats {
+	return &Cheats{backend: backend}
+}
+
+func (c *Cheats) SetBalance(ctx context.Context, address ethcommon.Address, balance *hexutil.Big) error {
+	return c.backend.applyArbosTest(ctx, arbos.SetBalanceData(common.NewAddressFromEth(address), balance.ToInt()))
+}
+
+func (c *Cheats) SetNonce(ctx context.Context, address ethcommon.Address, nonce hexutil.Uint64) error {
+	return c.backend.applyArbosTest(ctx, arbos.SetNonceData(common.NewAddressFromEth(address), uint64(nonce)))
+}
+
+func (c *Cheats) SetCode(ctx context.Context, address ethcommon.Address, code hexutil.Bytes) error {
+	return c.backend.applyArbosTest(ctx, arbos.SetCodeData(common.NewAddressFromEth(address), code))
+}
+
+func (c *Cheats) SetStorageAt(ctx context.Context, address ethcommon.Address, slot *hexutil.Big, value ethcommon.Hash) error {
+	key := common.NewHashFromEth(ethcommon.BigToHash(slot.ToInt()))
+	return c.backend.applyArbosTest(ctx, arbos.StoreData(common.NewAddressFromEth(address), key, common.NewHashFromEth(value)))
+}
+
+// Mine mines the given number of blocks, defaulting to one, optionally
+// advancing the timestamp by interval seconds between them
+func (c *Cheats) Mine(ctx context.Context, blocks *hexutil.Uint64, interval *hexutil.Uint64) error {
+	count := uint64(1)
+	if blocks != nil {
+		count = uint64(*blocks)
+	}
+	for i := uint64(0); i < count; i++ {
+		if i > 0 && interval != nil {
+			c.backend.l1Emulator.IncreaseTime(int64(*interval))
+		}
+		if err := c.backend.Mine(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Cheats) ImpersonateAccount(address ethcommon.Address) error {
+	c.backend.Impersonate(common.NewAddressFromEth(address), true)
+	return nil
+}
+
+func (c *Cheats) StopImpersonatingAccount(address ethcommon.Address) error {
+	c.backend.Impersonate(common.NewAddressFromEth(address), false)
+	return nil
+}
+
+// ImpersonateL1Alias impersonates the L2 alias of an L1 contract, allowing
+// transactions to be sent as if they came from that contract through the
+// inbox. It returns the aliased address.
+func (c *Cheats) ImpersonateL1Alias(l1Address ethcommon.Address) (ethcommon.Address, error) {
+	alias := message.L2RemapAccount(common.NewAddressFromEth(l1Address))
+	c.backend.Impersonate(alias, true)
+	return alias.ToEthAddress(), nil
+}
+
+func (c *Cheats) SetAutomine(ctx context.Context, enabled bool) error {
+	return c.backend.SetAutomine(ctx, enabled)
+}
+
+// applyArbosTest runs an ArbOS test hook in its own block
+func (b *Backend) applyArbosTest(ctx context.Context, data []byte) error {
+	return b.applyPrivileged(ctx, arbos.ARB_TEST_ADDRESS, data)
+}
+
+// applyPrivileged calls an ArbOS precompile in its own block from the zero
+// address. ArbOS treats it as a chain owner and allows its test hooks, and no
+// real L1 sender can use it.
+func (b *Backend) applyPrivileged(ctx context.Context, dest ethcommon.Address, data []byte) error {
+	b.Lock()
+	defer b.Unlock()
+	msg := message.ContractTransaction{
+		BasicTx: message.BasicTx{
+			MaxGas:      big.NewInt(1000000000),
+			GasPriceBid: big.NewInt(0),
+			DestAddress: common.NewAddressFromEth(dest),
+			Payment:     big.NewInt(0),
+			Data:        data,
+		},
+	}
+	block := b.l1Emulator.GenerateBlock()
+	requestId, err := b.addInboxMessage(ctx, message.NewSafeL2Message(msg), common.Address{}, big.NewInt(0), block)
+	if err != nil {
+		return err
+	}
+	if err := b.waitForBlockCount(block.blockId.Height.AsInt().Uint64()); err != nil {
+		return err
+	}
+	res, _, _, err := b.db.GetRequest(requestId)
+	if err != nil {
+		return err
+	}
+	if res == nil {
+		return errors.New("arbos call result not found")
+	}
+	if res.ResultCode != evm.ReturnCode {
+		return errors.Errorf("arbos call failed with code %v", res.ResultCode)
+	}
+	return nil
+}
+
+// Impersonate allows or stops sending transactions as an address without
+// its private key
+func (b *Backend) Impersonate(address common.Address, enabled bool) {
+	b.Lock()
+	defer b.Unlock()
+	if enabled {
+		b.impersonated[address] = true
+	} else {
+		delete(b.impersonated, address)
+	}
+}
+
+func (b *Backend) IsImpersonating(address common.Address) bool {
+	b.Lock()
+	defer b.Unlock()
+	return b.impersonated[address]
+}
+
+// SendImpersonatedTransaction mines an unsigned transaction from an
+// impersonated account, ignoring automine. ArbOS aliases the sender of L2
+// messages arriving through the inbox, so the message is sent from the L1
+// address that aliases to sender.
+func (b *Backend) SendImpersonatedTransaction(ctx context.Context, sender common.Address, tx *types.Transaction) (common.Hash, error) {
+	b.Lock()
+	defer b.Unlock()
+	if !b.impersonated[sender] {
+		return common.Hash{}, errors.Errorf("not impersonating %v", sender)
+	}
+	var dest common.Address
+	if tx.To() != nil {
+		dest = common.NewAddressFromEth(*tx.To())
+	}
+	msg := message.Transaction{
+		MaxGas:      new(big.Int).SetUint64(tx.Gas()),
+		GasPriceBid: tx.GasPrice(),
+		SequenceNum: new(big.Int).SetUint64(tx.Nonce()),
+		DestAddress: dest,
+		Payment:     tx.Value(),
+		Data:        tx.Data(),
+	}
+
+	logger.
+		Info().
+		Uint64("gasLimit", tx.Gas()).
+		Str("gasPrice", tx.GasPrice().String()).
+		Uint64("nonce", tx.Nonce()).
+		Str("from", sender.Hex()).
+		Str("value", tx.Value().String()).
+		Msg("sent impersonated transaction")
+
+	startHeight := b.l1Emulator.LatestHeight()
+	startCount, err := b.arbcore.GetMessageCount()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	block := b.l1Emulator.GenerateBlock()
+	requestId, err := b.addInboxMessage(ctx, message.NewSafeL2Message(msg), message.L1RemapAccount(sender), b.l1GasPrice, block)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if err := b.waitForBlockCount(block.blockId.Height.AsInt().Uint64()); err != nil {
+		return common.Hash{}, err
+	}
+	return requestId, b.checkResult(ctx, requestId, startCount.Uint64(), startHeight)
+}
@@ -0,0 +1,178 @@
+/*
+* Copyright 2021, Offchain Labs, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package dev
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/message"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/protocol"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+)
+
+func TestCheats(t *testing.T) {
+	ctx := context.Background()
+	config := protocol.ChainParams{
+		GracePeriod:               common.NewTimeBlocksInt(3),
+		ArbGasSpeedLimitPerSecond: 2000000000000,
+	}
+	backend, db, _, closeFunc := NewSimpleTestDevNode(t, config, common.RandAddress())
+	defer closeFunc()
+
+	cheats := NewCheats(backend)
+	account := common.RandAddress()
+	ethAccount := account.ToEthAddress()
+	code := []byte{0x60, 0x00, 0x60, 0x00, 0xf3}
+
+	test.FailIfError(t, cheats.SetBalance(ctx, ethAccount, (*hexutil.Big)(big.NewInt(12345))))
+	test.FailIfError(t, cheats.SetNonce(ctx, ethAccount, 7))
+	test.FailIfError(t, cheats.SetCode(ctx, ethAccount, code))
+	test.FailIfError(t, cheats.SetStorageAt(ctx, ethAccount, (*hexutil.Big)(big.NewInt(3)), ethcommon.BigToHash(big.NewInt(99))))
+
+	snap, err := db.LatestSnapshot(ctx)
+	test.FailIfError(t, err)
+	balance, err := snap.GetBalance(ctx, account)
+	test.FailIfError(t, err)
+	if balance.Cmp(big.NewInt(12345)) != 0 {
+		t.Error("wrong balance", balance)
+	}
+	nonce, err := snap.GetTransactionCount(ctx, account)
+	test.FailIfError(t, err)
+	if nonce.Cmp(big.NewInt(7)) != 0 {
+		t.Error("wrong nonce", nonce)
+	}
+	storedCode, err := snap.GetCode(ctx, account)
+	test.FailIfError(t, err)
+	if !bytes.Equal(storedCode, code) {
+		t.Error("wrong code", hexutil.Encode(storedCode))
+	}
+	storage, err := snap.GetStorageAt(ctx, account, big.NewInt(3))
+	test.FailIfError(t, err)
+	if storage.Cmp(big.NewInt(99)) != 0 {
+		t.Error("wrong storage", storage)
+	}
+
+	test.FailIfError(t, cheats.ImpersonateAccount(ethAccount))
+	if !backend.IsImpersonating(account) {
+		t.Error("account not impersonated")
+	}
+	test.FailIfError(t, cheats.StopImpersonatingAccount(ethAccount))
+	if backend.IsImpersonating(account) {
+		t.Error("account still impersonated")
+	}
+}
+
+func TestImpersonatedTransaction(t *testing.T) {
+	ctx := context.Background()
+	config := protocol.ChainParams{
+		GracePeriod:               common.NewTimeBlocksInt(3),
+		ArbGasSpeedLimitPerSecond: 2000000000000,
+	}
+	backend, db, _, closeFunc := NewSimpleTestDevNode(t, config, common.RandAddress())
+	defer closeFunc()
+
+	cheats := NewCheats(backend)
+	l1Contract := common.RandAddress()
+	alias, err := cheats.ImpersonateL1Alias(l1Contract.ToEthAddress())
+	test.FailIfError(t, err)
+	if alias != message.L2RemapAccount(l1Contract).ToEthAddress() {
+		t.Fatal("wrong alias", alias.Hex())
+	}
+	test.FailIfError(t, cheats.SetBalance(ctx, alias, (*hexutil.Big)(big.NewInt(1000000000000000000))))
+
+	dest := common.RandAddress()
+	tx := types.NewTransaction(0, dest.ToEthAddress(), big.NewInt(100), 100000, big.NewInt(0), nil)
+	if _, err := backend.SendImpersonatedTransaction(ctx, dest, tx); err == nil {
+		t.Error("sent from account that isn't impersonated")
+	}
+	_, err = backend.SendImpersonatedTransaction(ctx, common.NewAddressFromEth(alias), tx)
+	test.FailIfError(t, err)
+
+	snap, err := db.LatestSnapshot(ctx)
+	test.FailIfError(t, err)
+	balance, err := snap.GetBalance(ctx, dest)
+	test.FailIfError(t, err)
+	if balance.Cmp(big.NewInt(100)) != 0 {
+		t.Error("wrong balance", balance)
+	}
+	nonce, err := snap.GetTransactionCount(ctx, common.NewAddressFromEth(alias))
+	test.FailIfError(t, err)
+	if nonce.Cmp(big.NewInt(1)) != 0 {
+		t.Error("wrong nonce", nonce)
+	}
+}
+
+func TestPendingTransactionCount(t *testing.T) {
+	ctx := context.Background()
+	config := protocol.ChainParams{
+		GracePeriod:               common.NewTimeBlocksInt(3),
+		ArbGasSpeedLimitPerSecond: 2000000000000,
+	}
+	backend, _, _, closeFunc := NewSimpleTestDevNode(t, config, common.RandAddress())
+	defer closeFunc()
+
+	key, err := crypto.GenerateKey()
+	test.FailIfError(t, err)
+	sender := common.NewAddressFromEth(crypto.PubkeyToAddress(key.PublicKey))
+	test.FailIfError(t, NewCheats(backend).SetBalance(ctx, sender.ToEthAddress(), (*hexutil.Big)(big.NewInt(1000000000000000000))))
+
+	sendPending := func() {
+		t.Helper()
+		for nonce := uint64(0); nonce < 2; nonce++ {
+			tx := types.NewTransaction(nonce, common.RandAddress().ToEthAddress(), big.NewInt(1), 100000, big.NewInt(0), nil)
+			signedTx, err := types.SignTx(tx, backend.signer, key)
+			test.FailIfError(t, err)
+			test.FailIfError(t, backend.SendTransaction(ctx, signedTx))
+		}
+	}
+	requireCount := func(account common.Address, expected *uint64) {
+		t.Helper()
+		count, err := backend.PendingTransactionCount(ctx, account)
+		test.FailIfError(t, err)
+		if (count == nil) != (expected == nil) || (count != nil && *count != *expected) {
+			t.Error("wrong pending transaction count", count)
+		}
+	}
+	two := uint64(2)
+
+	evm := NewEVM(backend)
+	snapshot, err := evm.Snapshot()
+	test.FailIfError(t, err)
+
+	test.FailIfError(t, evm.SetAutomine(false))
+	requireCount(sender, nil)
+	sendPending()
+	requireCount(sender, &two)
+	requireCount(common.RandAddress(), nil)
+
+	test.FailIfError(t, evm.Revert(ctx, snapshot))
+	requireCount(sender, nil)
+
+	sendPending()
+	requireCount(sender, &two)
+	test.FailIfError(t, evm.Mine(nil))
+	requireCount(sender, nil)
+}
@@ -41,6 +41,7 @@ import (
 	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethutils"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/transactauth"
 	"github.com/pkg/errors"
@@ -121,9 +122,23 @@ func (s *EVM) Mine(timestamp *hexutil.Uint64) error {
 	if timestamp != nil {
 		s.backend.l1Emulator.SetTime(int64(*timestamp))
 	}
-	ctx := context.Background()
-	_, err := s.backend.AddInboxMessage(ctx, message.NewSafeL2Message(message.HeartbeatMessage{}), common.Address{})
-	return err
+	return s.backend.Mine(context.Background())
+}
+
+func (s *EVM) SetNextBlockTimestamp(timestamp hexutil.Uint64) error {
+	s.backend.l1Emulator.SetTime(int64(timestamp))
+	return nil
+}
+
+func (s *EVM) SetAutomine(enabled bool) error {
+	return s.backend.SetAutomine(context.Background(), enabled)
+}
+
+// SetIntervalMining mines a block every interval milliseconds, or stops
+// interval mining if interval is 0
+func (s *EVM) SetIntervalMining(interval hexutil.Uint64) error {
+	s.backend.SetIntervalMining(time.Duration(interval) * time.Millisecond)
+	return nil
 }
 
 func (s *EVM) IncreaseTime(amount int64) (string, error) {
@@ -163,13 +178,22 @@ func (b *BackendCore) addInboxMessage(ctx context.Context, msg message.Message,
 		return common.Hash{}, err
 	}
 	inboxMessage := message.NewInboxMessage(msg, sender, new(big.Int).Set(msgCount), gasPrice, chainTime)
+	if err := b.deliverInboxMessage(ctx, inboxMessage); err != nil {
+		return common.Hash{}, err
+	}
+	return message.CalculateRequestId(b.chainID, msgCount), nil
+}
 
-	requestId := message.CalculateRequestId(b.chainID, msgCount)
+// deliverInboxMessage sequences a message into its own block and waits for it
+// to execute. The message's sequence number must be the current message count.
+func (b *BackendCore) deliverInboxMessage(ctx context.Context, inboxMessage inbox.InboxMessage) error {
+	msgCount := inboxMessage.InboxSeqNum
 	var prevHash common.Hash
 	if msgCount.Cmp(big.NewInt(0)) > 0 {
+		var err error
 		prevHash, err = b.arbcore.GetInboxAcc(new(big.Int).Sub(msgCount, big.NewInt(1)))
 		if err != nil {
-			return common.Hash{}, err
+			return err
 		}
 	}
 	seqBatchItem := inbox.NewSequencerItem(b.delayedCount, inboxMessage, prevHash)
@@ -185,17 +209,23 @@ func (b *BackendCore) addInboxMessage(ctx context.Context, msg message.Message,
 		},
 	}
 	nextBlockBatchItem := inbox.NewSequencerItem(b.delayedCount, nextBlockMessage, seqBatchItem.Accumulator)
-	err = core.DeliverMessagesAndWait(ctx, b.arbcore, msgCount, prevHash, []inbox.SequencerBatchItem{seqBatchItem, nextBlockBatchItem}, nil, nil)
+	err := core.DeliverMessagesAndWait(ctx, b.arbcore, msgCount, prevHash, []inbox.SequencerBatchItem{seqBatchItem, nextBlockBatchItem}, nil, nil)
 	if err != nil {
-		return common.Hash{}, err
+		return err
 	}
+	return b.waitForExecution()
+}
+
+// waitForExecution waits until the machine has executed every delivered
+// message and all of its logs have been processed
+func (b *BackendCore) waitForExecution() error {
 	for {
 		if b.arbcore.MachineIdle() {
 			break
 		}
 		select {
 		case <-b.ctx.Done():
-			return [32]byte{}, errors.New("dev node canceled")
+			return errors.New("dev node canceled")
 		case <-time.After(time.Millisecond * 200):
 		}
 
@@ -203,23 +233,22 @@ func (b *BackendCore) addInboxMessage(ctx context.Context, msg message.Message,
 	for {
 		cursorPos, err := b.arbcore.LogsCursorPosition(big.NewInt(0))
 		if err != nil {
-			return common.Hash{}, err
+			return err
 		}
 		coreLogs, err := b.arbcore.GetLogCount()
 		if err != nil {
-			return common.Hash{}, err
+			return err
 		}
 		if cursorPos.Cmp(coreLogs) == 0 {
 			break
 		}
 		select {
 		case <-b.ctx.Done():
-			return [32]byte{}, errors.New("dev node canceled")
+			return errors.New("dev node canceled")
 		case <-time.After(time.Millisecond * 200):
 		}
 	}
-
-	return requestId, nil
+	return nil
 }
 
 type Backend struct {
@@ -233,6 +262,17 @@ type Backend struct {
 	chainAggregator   common.Address
 	l1GasPrice        *big.Int
 	revertFailedTxes  bool
+
+	// When automine is off, transactions wait in pendingTxs until mined
+	automine         bool
+	pendingTxs       []*types.Transaction
+	stopIntervalMine chan struct{}
+	impersonated     map[common.Address]bool
+
+	// Called with the backend locked before each reorg, and after it with the
+	// new block count
+	beforeReorgHooks []func() error
+	reorgHooks       []func(blockCount uint64) error
 }
 
 func NewBackend(ctx context.Context, core *BackendCore, db *txdb.TxDB, l1 *L1Emulator, signer types.Signer, aggregator common.Address, l1GasPrice *big.Int, revertFailedTxes bool) *Backend {
@@ -246,6 +286,8 @@ func NewBackend(ctx context.Context, core *BackendCore, db *txdb.TxDB, l1 *L1Emu
 		chainAggregator:   aggregator,
 		l1GasPrice:        l1GasPrice,
 		revertFailedTxes:  revertFailedTxes,
+		automine:          true,
+		impersonated:      make(map[common.Address]bool),
 	}
 }
 
@@ -257,11 +299,11 @@ func (b *Backend) ExportData() ([]byte, error) {
 		return nil, err
 	}
 	messages, err := b.arbcore.GetMessages(big.NewInt(0), messageCount)
+	b.Unlock()
 	if err != nil {
 		return nil, err
 	}
-	b.Unlock()
-	return inbox.TestVectorJSON(messages, nil, nil)
+	return exportChain(messages, b.l1Emulator.TimeIncrease())
 }
 
 func (b *Backend) Reorg(ctx context.Context, messageCount, blockCount uint64) error {
@@ -271,12 +313,48 @@ func (b *Backend) Reorg(ctx context.Context, messageCount, blockCount uint64) er
 }
 
 func (b *Backend) reorg(ctx context.Context, messageCount, blockCount uint64) error {
+	// Transactions waiting to be mined were sent after the point we return to
+	b.pendingTxs = nil
+	for _, hook := range b.beforeReorgHooks {
+		if err := hook(); err != nil {
+			return err
+		}
+	}
 	b.l1Emulator.Reorg(blockCount)
 	logger.Info().Uint64("message", messageCount).Uint64("block", blockCount).Msg("Reorged chain")
 	if err := core.ReorgAndWait(ctx, b.arbcore, new(big.Int).SetUint64(messageCount)); err != nil {
 		return err
 	}
-	return b.waitForBlockCount(blockCount)
+	delayedCount, err := b.arbcore.GetTotalDelayedMessagesSequenced()
+	if err != nil {
+		return err
+	}
+	b.delayedCount = delayedCount
+	if err := b.waitForBlockCount(blockCount); err != nil {
+		return err
+	}
+	for _, hook := range b.reorgHooks {
+		if err := hook(blockCount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OnReorg registers a hook to run after the chain is reorged, which is given
+// the new block count and runs with the backend locked
+func (b *Backend) OnReorg(hook func(blockCount uint64) error) {
+	b.Lock()
+	defer b.Unlock()
+	b.reorgHooks = append(b.reorgHooks, hook)
+}
+
+// BeforeReorg registers a hook to run with the backend locked before the
+// chain is reorged, while the blocks being dropped still exist
+func (b *Backend) BeforeReorg(hook func() error) {
+	b.Lock()
+	defer b.Unlock()
+	b.beforeReorgHooks = append(b.beforeReorgHooks, hook)
 }
 
 func (b *Backend) waitForBlockCount(blockCount uint64) error {
@@ -293,26 +371,36 @@ func (b *Backend) waitForBlockCount(blockCount uint64) error {
 	return nil
 }
 
-func (b *Backend) PendingTransactionCount(_ context.Context, _ common.Address) (*uint64, error) {
+// PendingTransactionCount returns the next nonce for account after any of its
+// transactions waiting to be mined, or nil if it has none waiting
+func (b *Backend) PendingTransactionCount(_ context.Context, account common.Address) (*uint64, error) {
 	b.Lock()
 	defer b.Unlock()
-	return nil, nil
+	var count *uint64
+	for _, tx := range b.pendingTxs {
+		sender, err := types.Sender(b.signer, tx)
+		if err != nil {
+			return nil, err
+		}
+		if sender != account.ToEthAddress() {
+			continue
+		}
+		next := tx.Nonce() + 1
+		if count == nil || next > *count {
+			count = &next
+		}
+	}
+	return count, nil
 }
 
 func (b *Backend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
 	b.Lock()
 	defer b.Unlock()
-	arbTx := message.NewCompressedECDSAFromEth(tx)
 	sender, err := types.Sender(b.signer, tx)
 	if err != nil {
 		return err
 	}
 
-	arbMsg, err := message.NewTransactionBatchFromMessages([]message.AbstractL2Message{arbTx})
-	if err != nil {
-		return err
-	}
-
 	logger.
 		Info().
 		Uint64("gasLimit", tx.Gas()).
@@ -323,20 +411,26 @@ func (b *Backend) SendTransaction(ctx context.Context, tx *types.Transaction) er
 		Hex("hash", tx.Hash().Bytes()).
 		Msg("sent transaction")
 
+	if !b.automine {
+		b.pendingTxs = append(b.pendingTxs, tx)
+		return nil
+	}
+
 	startHeight := b.l1Emulator.LatestHeight()
 	startCount, err := b.arbcore.GetMessageCount()
 	if err != nil {
 		return err
 	}
 
-	block := b.l1Emulator.GenerateBlock()
-	if _, err := b.addInboxMessage(ctx, message.NewSafeL2Message(arbMsg), b.currentAggregator, b.l1GasPrice, block); err != nil {
+	if err := b.mineTransactions(ctx, []*types.Transaction{tx}); err != nil {
 		return err
 	}
-	if err := b.waitForBlockCount(block.blockId.Height.AsInt().Uint64()); err != nil {
-		return err
-	}
-	txHash := common.NewHashFromEth(tx.Hash())
+	return b.checkResult(ctx, common.NewHashFromEth(tx.Hash()), startCount.Uint64(), startHeight)
+}
+
+// checkResult looks up the result of a just mined transaction and, if
+// failed transactions are being reverted, replaces its block with an empty one
+func (b *Backend) checkResult(ctx context.Context, txHash common.Hash, startCount, startHeight uint64) error {
 	res, _, _, err := b.db.GetRequest(txHash)
 	if err != nil {
 		return err
@@ -348,7 +442,7 @@ func (b *Backend) SendTransaction(ctx context.Context, tx *types.Transaction) er
 	if b.revertFailedTxes && res.ResultCode != evm.ReturnCode {
 		logger.Warn().Int("code", int(res.ResultCode)).Msg("transaction failed")
 		// If transaction failed, rollback the block
-		if err := b.reorg(ctx, startCount.Uint64(), startHeight); err != nil {
+		if err := b.reorg(ctx, startCount, startHeight); err != nil {
 			return err
 		}
 
@@ -364,6 +458,85 @@ func (b *Backend) SendTransaction(ctx context.Context, tx *types.Transaction) er
 	return nil
 }
 
+// mineTransactions includes the given transactions in a new block
+func (b *Backend) mineTransactions(ctx context.Context, txes []*types.Transaction) error {
+	arbTxes := make([]message.AbstractL2Message, 0, len(txes))
+	for _, tx := range txes {
+		arbTxes = append(arbTxes, message.NewCompressedECDSAFromEth(tx))
+	}
+	arbMsg, err := message.NewTransactionBatchFromMessages(arbTxes)
+	if err != nil {
+		return err
+	}
+	block := b.l1Emulator.GenerateBlock()
+	if _, err := b.addInboxMessage(ctx, message.NewSafeL2Message(arbMsg), b.currentAggregator, b.l1GasPrice, block); err != nil {
+		return err
+	}
+	return b.waitForBlockCount(block.blockId.Height.AsInt().Uint64())
+}
+
+// Mine creates a block containing any pending transactions. Transactions
+// mined this way are kept even if they fail.
+func (b *Backend) Mine(ctx context.Context) error {
+	b.Lock()
+	defer b.Unlock()
+	return b.mine(ctx)
+}
+
+func (b *Backend) mine(ctx context.Context) error {
+	if len(b.pendingTxs) == 0 {
+		_, err := b.addInboxMessage(ctx, message.NewSafeL2Message(message.HeartbeatMessage{}), common.Address{}, big.NewInt(0), b.l1Emulator.GenerateBlock())
+		return err
+	}
+	txes := b.pendingTxs
+	b.pendingTxs = nil
+	logger.Info().Int("count", len(txes)).Msg("mining pending transactions")
+	return b.mineTransactions(ctx, txes)
+}
+
+// SetAutomine switches between mining each transaction as it arrives and
+// holding transactions until the next mine. Turning automine back on mines
+// anything still pending.
+func (b *Backend) SetAutomine(ctx context.Context, enabled bool) error {
+	b.Lock()
+	defer b.Unlock()
+	b.automine = enabled
+	if enabled && len(b.pendingTxs) > 0 {
+		return b.mine(ctx)
+	}
+	return nil
+}
+
+func (b *Backend) SetIntervalMining(interval time.Duration) {
+	b.Lock()
+	defer b.Unlock()
+	if b.stopIntervalMine != nil {
+		close(b.stopIntervalMine)
+		b.stopIntervalMine = nil
+	}
+	if interval == 0 {
+		return
+	}
+	stop := make(chan struct{})
+	b.stopIntervalMine = stop
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-b.ctx.Done():
+				return
+			case <-stop:
+				return
+			case <-ticker.C:
+				if err := b.Mine(b.ctx); err != nil {
+					logger.Warn().Err(err).Msg("error mining block on interval")
+				}
+			}
+		}
+	}()
+}
+
 func (b *Backend) Aggregator() *common.Address {
 	return &b.chainAggregator
 }
@@ -392,6 +565,10 @@ type L1Emulator struct {
 	sync.Mutex
 	timeIncrease int64
 	latestHeight uint64
+
+	// When set, every block is mined on this simulated L1 so that the dev
+	// chain and the simulated L1 agree on block heights and timestamps
+	chain *ethutils.SimulatedEthClient
 }
 
 func NewL1Emulator(initialHeight uint64) *L1Emulator {
@@ -403,16 +580,36 @@ func NewL1Emulator(initialHeight uint64) *L1Emulator {
 }
 
 func (b *L1Emulator) LatestHeight() uint64 {
+	b.Lock()
+	defer b.Unlock()
 	return b.latestHeight
 }
 
+// followsChain returns whether blocks are mined on a simulated L1
+func (b *L1Emulator) followsChain() bool {
+	b.Lock()
+	defer b.Unlock()
+	return b.chain != nil
+}
+
 func (b *L1Emulator) Reorg(height uint64) {
 	b.Lock()
 	defer b.Unlock()
+	if b.chain != nil {
+		// The simulated L1 can't go back, so later blocks continue from its
+		// head
+		for b.latestHeight < height {
+			b.mineChainBlock()
+		}
+		return
+	}
 	b.latestHeight = height
 }
 
 func (b *L1Emulator) addBlock() L1BlockInfo {
+	if b.chain != nil {
+		return b.mineChainBlock()
+	}
 	info := L1BlockInfo{
 		blockId: &common.BlockId{
 			Height:     common.NewTimeBlocksInt(int64(b.latestHeight + 1)),
@@ -436,6 +633,24 @@ func (b *L1Emulator) SetTime(timestamp int64) {
 	b.timeIncrease = timestamp - time.Now().Unix()
 }
 
+// TimeIncrease returns how far the emulated clock is ahead of the real one
+func (b *L1Emulator) TimeIncrease() int64 {
+	b.Lock()
+	defer b.Unlock()
+	return b.timeIncrease
+}
+
+// restoreTime sets how far the emulated clock is ahead of the real one, while
+// never putting it before notBefore
+func (b *L1Emulator) restoreTime(timeIncrease int64, notBefore int64) {
+	b.Lock()
+	defer b.Unlock()
+	if minIncrease := notBefore - time.Now().Unix(); timeIncrease < minIncrease {
+		timeIncrease = minIncrease
+	}
+	b.timeIncrease = timeIncrease
+}
+
 func (b *L1Emulator) IncreaseTime(amount int64) {
 	b.Lock()
 	defer b.Unlock()
@@ -30,13 +30,17 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
 
 	"github.com/offchainlabs/arbitrum/packages/arb-evm/arbos"
 	"github.com/offchainlabs/arbitrum/packages/arb-evm/arboscontracts"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
 	"github.com/offchainlabs/arbitrum/packages/arb-evm/message"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/aggregator"
 	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/arbostestcontracts"
 	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/web3"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/hashing"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/protocol"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
@@ -289,6 +293,131 @@ func TestFees(t *testing.T) {
 	checkPaid()
 }
 
+func TestGasBreakdown(t *testing.T) {
+	ctx := context.Background()
+	backend, web3Server, client, _, aggAuth, _, _, _, cancel := setupFeeChain(t, ctx)
+	defer cancel()
+
+	arbAggregator, err := arboscontracts.NewArbAggregator(arbos.ARB_AGGREGATOR_ADDRESS, client)
+	test.FailIfError(t, err)
+
+	userOpts, userAddr := OptsAddressPair(t, nil)
+	addSomeBalance(t, ctx, userAddr, backend, client)
+	tx, err := arbAggregator.SetFeeCollector(userOpts, userOpts.From, userOpts.From)
+	test.FailIfError(t, err)
+
+	arbRes, _, _, err := backend.db.GetRequest(common.NewHashFromEth(tx.Hash()))
+	test.FailIfError(t, err)
+
+	receipt, err := web3Server.GetTransactionReceipt(ctx, tx.Hash().Bytes(), nil)
+	test.FailIfError(t, err)
+	if receipt.GasBreakdown != nil {
+		t.Error("receipt has gas breakdown without receipt-gas-breakdown")
+	}
+
+	srv := aggregator.NewServer(backend, backend.chainID, backend.db)
+	breakdownServer := web3.NewServer(srv, web3.ServerConfig{Mode: configuration.GanacheRpcMode, ReceiptGasBreakdown: true}, nil)
+	receipt, err = breakdownServer.GetTransactionReceipt(ctx, tx.Hash().Bytes(), nil)
+	test.FailIfError(t, err)
+	breakdown := receipt.GasBreakdown
+	if breakdown == nil {
+		t.Fatal("receipt missing gas breakdown")
+	}
+
+	fees := arbRes.FeeStats
+	target := fees.PayTarget()
+	components := []struct {
+		name      string
+		component *web3.GasComponentResult
+		get       func(*evm.FeeSet) *big.Int
+	}{
+		{"l1Transaction", breakdown.L1Transaction, func(fs *evm.FeeSet) *big.Int { return fs.L1Transaction }},
+		{"l1Calldata", breakdown.L1Calldata, func(fs *evm.FeeSet) *big.Int { return fs.L1Calldata }},
+		{"l2Storage", breakdown.L2Storage, func(fs *evm.FeeSet) *big.Int { return fs.L2Storage }},
+		{"l2Computation", breakdown.L2Computation, func(fs *evm.FeeSet) *big.Int { return fs.L2Computation }},
+	}
+	componentTotal := big.NewInt(0)
+	for _, c := range components {
+		if c.component.Units.ToInt().Cmp(c.get(fees.UnitsUsed)) != 0 ||
+			c.component.Price.ToInt().Cmp(c.get(fees.Price)) != 0 ||
+			c.component.Paid.ToInt().Cmp(c.get(fees.Paid)) != 0 {
+			t.Errorf("%v has %v units at %v paying %v", c.name, c.component.Units, c.component.Price, c.component.Paid)
+		}
+		if c.component.Paid.ToInt().Cmp(c.get(target)) != 0 {
+			t.Errorf("%v paid %v but should have paid %v", c.name, c.component.Paid, c.get(target))
+		}
+		componentTotal.Add(componentTotal, c.component.Paid.ToInt())
+	}
+	if breakdown.L1Transaction.Paid.ToInt().Sign() <= 0 || breakdown.L2Computation.Paid.ToInt().Sign() <= 0 {
+		t.Error("expected to pay for the L1 transaction and computation")
+	}
+	if componentTotal.Cmp(breakdown.TotalPaid.ToInt()) != 0 {
+		t.Error("components add up to", componentTotal, "but total paid is", breakdown.TotalPaid)
+	}
+	if breakdown.TotalPaid.ToInt().Cmp(fees.Paid.Total()) != 0 {
+		t.Error("wrong total paid", breakdown.TotalPaid, fees.Paid.Total())
+	}
+	if breakdown.GasUsed.ToInt().Cmp(arbRes.CalcGasUsed()) != 0 || uint64(receipt.GasUsed) != arbRes.CalcGasUsed().Uint64() {
+		t.Error("wrong gas used", breakdown.GasUsed, receipt.GasUsed, arbRes.CalcGasUsed())
+	}
+	if breakdown.GasUsedForL1.ToInt().Cmp(arbRes.CalcGasUsedForL1()) != 0 || breakdown.GasUsedForL1.ToInt().Cmp(breakdown.GasUsed.ToInt()) > 0 {
+		t.Error("wrong gas used for L1", breakdown.GasUsedForL1, breakdown.GasUsed)
+	}
+	if breakdown.ArbGasUsed.ToInt().Cmp(arbRes.GasUsed) != 0 {
+		t.Error("wrong arbgas used", breakdown.ArbGasUsed, arbRes.GasUsed)
+	}
+	if breakdown.Aggregator == nil || *breakdown.Aggregator != aggAuth.From {
+		t.Error("wrong aggregator", breakdown.Aggregator)
+	}
+	if breakdown.BlockPrices == nil || breakdown.BlockPrices.PricePerArbGasTotal.ToInt().Sign() <= 0 {
+		t.Error("missing block prices", breakdown.BlockPrices)
+	}
+
+	rpcServer, err := web3.GenerateWeb3Server(srv, nil, web3.ServerConfig{Mode: configuration.GanacheRpcMode}, nil, nil, nil)
+	test.FailIfError(t, err)
+	rpcClient := rpc.DialInProc(rpcServer)
+	defer rpcClient.Close()
+	var devBreakdown web3.GasBreakdownResult
+	err = rpcClient.CallContext(ctx, &devBreakdown, "dev_getTransactionGasBreakdown", hexutil.Bytes(tx.Hash().Bytes()))
+	test.FailIfError(t, err)
+	if devBreakdown.TotalPaid.ToInt().Cmp(breakdown.TotalPaid.ToInt()) != 0 || devBreakdown.GasUsed.ToInt().Cmp(breakdown.GasUsed.ToInt()) != 0 {
+		t.Error("dev_getTransactionGasBreakdown returned", devBreakdown.TotalPaid, devBreakdown.GasUsed)
+	}
+}
+
+func TestGasBreakdownWithoutFees(t *testing.T) {
+	ctx := context.Background()
+	config := protocol.ChainParams{
+		GracePeriod:               common.NewTimeBlocksInt(3),
+		ArbGasSpeedLimitPerSecond: 2000000000000,
+	}
+	backend, _, srv, cancel := NewSimpleTestDevNode(t, config, common.RandAddress())
+	defer cancel()
+
+	deposit := message.EthDepositTx{
+		L2Message: message.NewSafeL2Message(message.ContractTransaction{
+			BasicTx: message.BasicTx{
+				MaxGas:      big.NewInt(1000000),
+				GasPriceBid: big.NewInt(0),
+				DestAddress: common.RandAddress(),
+				Payment:     big.NewInt(100),
+			},
+		}),
+	}
+	requestId, err := backend.AddInboxMessage(ctx, deposit, common.RandAddress())
+	test.FailIfError(t, err)
+
+	rpcServer, err := web3.GenerateWeb3Server(srv, nil, web3.ServerConfig{Mode: configuration.GanacheRpcMode}, nil, nil, nil)
+	test.FailIfError(t, err)
+	rpcClient := rpc.DialInProc(rpcServer)
+	defer rpcClient.Close()
+	var breakdown web3.GasBreakdownResult
+	err = rpcClient.CallContext(ctx, &breakdown, "dev_getTransactionGasBreakdown", hexutil.Bytes(requestId.Bytes()))
+	if err == nil {
+		t.Error("got gas breakdown without fees")
+	}
+}
+
 func checkFees(t *testing.T, backend *Backend, tx *types.Transaction) *big.Int {
 	t.Helper()
 	arbRes, _, _, err := backend.db.GetRequest(common.NewHashFromEth(tx.Hash()))
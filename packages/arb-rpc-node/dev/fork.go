@@ -18,14 +18,18 @@ package dev
 
 import (
 	"context"
+	"math/big"
+	"os"
+	"path/filepath"
+
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/offchainlabs/arbitrum/packages/arb-node-core/monitor"
 	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/txdb"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/machine"
 	"github.com/pkg/errors"
-	"math/big"
 )
 
 func NewForkNode(
@@ -98,13 +102,99 @@ func NewForkNode(
 	return backend, db, mon, cancel, errChan, nil
 }
 
-func GetMessageCount(dir string) (uint64, error) {
+// ForkPoint selects where a fork node branches off from its source chain. At
+// most one field should be set, and if none are the fork keeps every message.
+type ForkPoint struct {
+	Block     *uint64
+	BlockHash *common.Hash
+	// Index of the last inbox message to keep
+	Message *uint64
+}
+
+func (p ForkPoint) Validate() error {
+	set := 0
+	if p.Block != nil {
+		set++
+	}
+	if p.BlockHash != nil {
+		set++
+	}
+	if p.Message != nil {
+		set++
+	}
+	if set > 1 {
+		return errors.New("only one of fork block, block hash and message can be given")
+	}
+	return nil
+}
+
+// ForkMessageCount resolves a fork point to the number of inbox messages kept
+// by a fork of the database in dir
+func ForkMessageCount(dir string, point ForkPoint) (uint64, error) {
 	coreConfig := configuration.DefaultCoreSettingsMaxExecution()
 	mon, err := monitor.NewMonitor(dir, coreConfig)
 	if err != nil {
 		return 0, errors.Wrap(err, "error opening monitor")
 	}
-	msgCount, err := mon.Core.GetMessageCount()
-	mon.Close()
-	return msgCount.Uint64(), err
+	defer mon.Close()
+	return forkMessageCount(mon.Core, mon.Storage.GetNodeStore(), point)
+}
+
+func forkMessageCount(lookup core.ArbCoreLookup, store machine.NodeStore, point ForkPoint) (uint64, error) {
+	if err := point.Validate(); err != nil {
+		return 0, err
+	}
+	msgCount, err := lookup.GetMessageCount()
+	if err != nil {
+		return 0, err
+	}
+	var height uint64
+	switch {
+	case point.Message != nil:
+		if *point.Message >= msgCount.Uint64() {
+			return 0, errors.Errorf("message %v not in database with %v messages", *point.Message, msgCount)
+		}
+		return *point.Message + 1, nil
+	case point.BlockHash != nil:
+		blockHeight := store.GetPossibleBlock(*point.BlockHash)
+		if blockHeight == nil {
+			return 0, errors.Errorf("block %v not found", *point.BlockHash)
+		}
+		height = *blockHeight
+	case point.Block != nil:
+		height = *point.Block
+	default:
+		return msgCount.Uint64(), nil
+	}
+
+	blockCount, err := store.BlockCount()
+	if err != nil {
+		return 0, err
+	}
+	if height >= blockCount {
+		return 0, errors.Errorf("block %v not in database with %v blocks", height, blockCount)
+	}
+	info, err := store.GetBlockInfo(height)
+	if err != nil {
+		return 0, err
+	}
+	if point.BlockHash != nil && info.Header.Hash() != point.BlockHash.ToEthHash() {
+		return 0, errors.Errorf("block %v not found", *point.BlockHash)
+	}
+	blockLog, err := core.GetZeroOrOneLog(lookup, new(big.Int).SetUint64(info.BlockLog))
+	if err != nil {
+		return 0, err
+	}
+	if blockLog.Value == nil {
+		return 0, errors.Errorf("missing log for block %v", height)
+	}
+	return blockLog.Inbox.Count.Uint64(), nil
+}
+
+// ForkWorkDir creates a temporary directory beside the database in src, so
+// that forks made inside it are on the same filesystem and can share the
+// source's tables
+func ForkWorkDir(src string, pattern string) (string, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(filepath.Clean(src)), pattern)
+	return dir, errors.WithStack(err)
 }
@@ -0,0 +1,116 @@
+/*
+* Copyright 2022, Offchain Labs, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package dev
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/message"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/protocol"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+)
+
+func TestForkWorkDir(t *testing.T) {
+	parent := t.TempDir()
+	src := filepath.Join(parent, "db")
+	test.FailIfError(t, os.MkdirAll(src, 0755))
+	workDir, err := ForkWorkDir(src+"/", "fork")
+	test.FailIfError(t, err)
+	if filepath.Dir(workDir) != parent {
+		t.Error("work dir", workDir, "not beside source", src)
+	}
+}
+
+func TestForkMessageCount(t *testing.T) {
+	ctx := context.Background()
+	backend, db, mon, cancel, _, err := NewDevNode(ctx, t.TempDir(), *arbosfile, big.NewInt(42161), common.RandAddress(), 0, false)
+	test.FailIfError(t, err)
+	defer cancel()
+	params := protocol.ChainParams{
+		GracePeriod:               common.NewTimeBlocksInt(3),
+		ArbGasSpeedLimitPerSecond: 2000000000000,
+	}
+	initMsg, err := message.NewInitMessage(params, common.RandAddress(), nil)
+	test.FailIfError(t, err)
+	_, err = backend.AddInboxMessage(ctx, initMsg, common.Address{})
+	test.FailIfError(t, err)
+	for i := 0; i < 3; i++ {
+		test.FailIfError(t, backend.Mine(ctx))
+	}
+	test.FailIfError(t, backend.waitForExecution())
+
+	store := mon.Storage.GetNodeStore()
+	msgCount, err := mon.Core.GetMessageCount()
+	test.FailIfError(t, err)
+	blockCount, err := db.BlockCount()
+	test.FailIfError(t, err)
+
+	count, err := forkMessageCount(mon.Core, store, ForkPoint{})
+	test.FailIfError(t, err)
+	if count != msgCount.Uint64() {
+		t.Error("fork without a fork point kept", count, "of", msgCount, "messages")
+	}
+
+	lastMessage := uint64(1)
+	count, err = forkMessageCount(mon.Core, store, ForkPoint{Message: &lastMessage})
+	test.FailIfError(t, err)
+	if count != 2 {
+		t.Error("fork at message 1 kept", count, "messages")
+	}
+
+	var prevCount uint64
+	for height := uint64(0); height < blockCount; height++ {
+		count, err := forkMessageCount(mon.Core, store, ForkPoint{Block: &height})
+		test.FailIfError(t, err)
+		if count < prevCount || count > msgCount.Uint64() {
+			t.Error("fork at block", height, "kept", count, "messages")
+		}
+		prevCount = count
+
+		info, err := store.GetBlockInfo(height)
+		test.FailIfError(t, err)
+		hash := common.NewHashFromEth(info.Header.Hash())
+		hashCount, err := forkMessageCount(mon.Core, store, ForkPoint{BlockHash: &hash})
+		test.FailIfError(t, err)
+		if hashCount != count {
+			t.Error("fork at hash of block", height, "kept", hashCount, "messages but expected", count)
+		}
+	}
+	if prevCount == 0 {
+		t.Error("fork at latest block kept no messages")
+	}
+
+	missingBlock := blockCount
+	missingMessage := msgCount.Uint64()
+	unknownHash := common.RandHash()
+	invalid := map[string]ForkPoint{
+		"missing block":   {Block: &missingBlock},
+		"missing message": {Message: &missingMessage},
+		"unknown hash":    {BlockHash: &unknownHash},
+		"multiple points": {Block: &lastMessage, Message: &lastMessage},
+	}
+	for name, point := range invalid {
+		if _, err := forkMessageCount(mon.Core, store, point); err == nil {
+			t.Error("no error for", name)
+		}
+	}
+}
@@ -0,0 +1,621 @@
+/*
+* Copyright 2021, Offchain Labs, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package dev
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethcore "github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/message"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/ethbridge"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethbridgecontracts"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethutils"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/protocol"
+)
+
+// The chain id used by go-ethereum's simulated backend
+var l1SimChainId = big.NewInt(1337)
+
+// The simulated L1 can catch up with a dev chain that is at most this many L1
+// blocks ahead of it
+const maxL1SimCatchUp = 1024
+
+// L1Sim runs the bridge, inbox and outbox contracts on an in-process simulated
+// L1 chain, which also provides the L1 blocks of the dev chain. Messages sent
+// to the inbox go through the real delayed inbox and, like in the sequencer
+// inbox, are sequenced once they are both delayBlocks L1 blocks and
+// delaySeconds old. L2 to L1 sends are posted to the outbox where they can be
+// executed with proofs. There's no sequencer inbox on the simulated L1, so
+// transactions sent to the dev chain directly aren't posted to it in batches.
+// The simulated L1 can't be reorged either, so after the dev chain is reverted
+// its outbox keeps the sends that were dropped.
+type L1Sim struct {
+	sync.Mutex
+	backend      *Backend
+	client       *ethutils.SimulatedEthClient
+	rollup       *bind.TransactOpts
+	accounts     map[ethcommon.Address]*bind.TransactOpts
+	delayBlocks  uint64
+	delaySeconds uint64
+
+	bridgeAddress ethcommon.Address
+	inboxAddress  ethcommon.Address
+	outboxAddress ethcommon.Address
+	inbox         *ethbridgecontracts.Inbox
+	outbox        *ethbridgecontracts.Outbox
+	watcher       *ethbridge.DelayedBridgeWatcher
+
+	// Next L1 block to read delayed messages from
+	nextBlock uint64
+	// Number of L2 to L1 sends posted to the outbox, only accessed with the
+	// backend locked
+	sendsPosted *big.Int
+}
+
+// NewL1Sim creates a simulated L1 where each of the given keys is funded with
+// balance and deploys the bridge contracts to it. The first key acts as the
+// rollup, posting outbox entries.
+func NewL1Sim(backend *Backend, keys []*ecdsa.PrivateKey, balance *big.Int, delayBlocks uint64, delaySeconds uint64) (*L1Sim, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("simulated L1 needs at least one account")
+	}
+	genesisAlloc := make(map[ethcommon.Address]ethcore.GenesisAccount)
+	accounts := make(map[ethcommon.Address]*bind.TransactOpts)
+	var rollup *bind.TransactOpts
+	for _, key := range keys {
+		auth, err := bind.NewKeyedTransactorWithChainID(key, l1SimChainId)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if rollup == nil {
+			rollup = auth
+		}
+		address := crypto.PubkeyToAddress(key.PublicKey)
+		accounts[address] = auth
+		genesisAlloc[address] = ethcore.GenesisAccount{Balance: balance}
+	}
+	client := &ethutils.SimulatedEthClient{SimulatedBackend: backends.NewSimulatedBackend(genesisAlloc, 1000000000)}
+
+	sim := &L1Sim{
+		backend:      backend,
+		client:       client,
+		rollup:       rollup,
+		accounts:     accounts,
+		delayBlocks:  delayBlocks,
+		delaySeconds: delaySeconds,
+		sendsPosted:  big.NewInt(0),
+	}
+	if err := sim.deploy(); err != nil {
+		return nil, err
+	}
+	if err := backend.l1Emulator.followChain(client); err != nil {
+		return nil, err
+	}
+	backend.OnReorg(sim.reorg)
+	return sim, nil
+}
+
+// reorg makes sends of the reorged chain be posted from its new send count.
+// It's called with the backend locked, which guards sendsPosted, so the
+// simulated L1 isn't locked as that would invert the lock order of Sync.
+func (s *L1Sim) reorg(uint64) error {
+	sendCount, err := s.backend.arbcore.GetSendCount()
+	if err != nil {
+		return err
+	}
+	if sendCount.Cmp(s.sendsPosted) < 0 {
+		s.sendsPosted = sendCount
+	}
+	return nil
+}
+
+func (s *L1Sim) deploy() error {
+	bridgeAddress, _, bridge, err := ethbridgecontracts.DeployBridge(s.rollup, s.client)
+	if err != nil {
+		return errors.Wrap(err, "error deploying bridge")
+	}
+	outboxAddress, _, outbox, err := ethbridgecontracts.DeployOutbox(s.rollup, s.client)
+	if err != nil {
+		return errors.Wrap(err, "error deploying outbox")
+	}
+	inboxAddress, _, inboxCon, err := ethbridgecontracts.DeployInbox(s.rollup, s.client)
+	if err != nil {
+		return errors.Wrap(err, "error deploying inbox")
+	}
+	s.client.Commit()
+
+	if _, err := bridge.Initialize(s.rollup); err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := outbox.Initialize(s.rollup, s.rollup.From, bridgeAddress); err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := inboxCon.Initialize(s.rollup, bridgeAddress, ethcommon.Address{}); err != nil {
+		return errors.WithStack(err)
+	}
+	s.client.Commit()
+
+	if _, err := bridge.SetOutbox(s.rollup, outboxAddress, true); err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := bridge.SetInbox(s.rollup, inboxAddress, true); err != nil {
+		return errors.WithStack(err)
+	}
+	s.client.Commit()
+
+	watcher, err := ethbridge.NewDelayedBridgeWatcher(bridgeAddress, 0, s.client)
+	if err != nil {
+		return err
+	}
+	s.bridgeAddress = bridgeAddress
+	s.inboxAddress = inboxAddress
+	s.outboxAddress = outboxAddress
+	s.inbox = inboxCon
+	s.outbox = outbox
+	s.watcher = watcher
+	return nil
+}
+
+// Start syncs the simulated L1 with the dev chain every interval until ctx
+// is cancelled
+func (s *L1Sim) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.Sync(ctx, false); err != nil {
+					logger.Warn().Err(err).Msg("error syncing simulated L1")
+				}
+			}
+		}
+	}()
+}
+
+// Sync delivers new delayed inbox messages to the dev chain, sequences the
+// ones which are old enough, or all of them if force is set, and posts new
+// L2 to L1 sends to the outbox
+func (s *L1Sim) Sync(ctx context.Context, force bool) error {
+	s.Lock()
+	defer s.Unlock()
+	s.backend.Lock()
+	defer s.backend.Unlock()
+	if err := s.readDelayedMessages(ctx); err != nil {
+		return err
+	}
+	if err := s.sequenceDelayedMessages(ctx, force); err != nil {
+		return err
+	}
+	return s.postSends()
+}
+
+func (s *L1Sim) readDelayedMessages(ctx context.Context) error {
+	head := s.client.Blockchain().CurrentBlock().NumberU64()
+	if s.nextBlock > head {
+		return nil
+	}
+	delivered, err := s.watcher.LookupMessagesInRange(ctx, new(big.Int).SetUint64(s.nextBlock), new(big.Int).SetUint64(head))
+	if err != nil {
+		return err
+	}
+	s.nextBlock = head + 1
+	if len(delivered) == 0 {
+		return nil
+	}
+	delayedMessages := make([]inbox.DelayedMessage, 0, len(delivered))
+	for _, msg := range delivered {
+		delayedMessages = append(delayedMessages, inbox.NewDelayedMessage(msg.BeforeInboxAcc, msg.Message))
+	}
+	msgCount, err := s.backend.arbcore.GetMessageCount()
+	if err != nil {
+		return err
+	}
+	prevAcc, err := s.prevInboxAcc(msgCount)
+	if err != nil {
+		return err
+	}
+	logger.Info().Int("count", len(delayedMessages)).Msg("delivering delayed messages from simulated L1")
+	return core.DeliverMessagesAndWait(ctx, s.backend.arbcore, msgCount, prevAcc, nil, delayedMessages, nil)
+}
+
+// lastSequencableBlock returns the latest L1 block whose delayed messages are
+// old enough to be sequenced
+func (s *L1Sim) lastSequencableBlock() (uint64, bool) {
+	head := s.client.Blockchain().CurrentBlock()
+	if head.NumberU64() < s.delayBlocks {
+		return 0, false
+	}
+	for height := head.NumberU64() - s.delayBlocks; ; height-- {
+		block := s.client.Blockchain().GetBlockByNumber(height)
+		if block.Time()+s.delaySeconds <= head.Time() {
+			return height, true
+		}
+		if height == 0 {
+			return 0, false
+		}
+	}
+}
+
+func (s *L1Sim) sequenceDelayedMessages(ctx context.Context, force bool) error {
+	maxBlock := s.client.Blockchain().CurrentBlock().Number()
+	if !force {
+		height, ok := s.lastSequencableBlock()
+		if !ok {
+			return nil
+		}
+		maxBlock.SetUint64(height)
+	}
+	oldDelayedCount, err := s.backend.arbcore.GetTotalDelayedMessagesSequenced()
+	if err != nil {
+		return err
+	}
+	newDelayedCount, err := s.backend.arbcore.GetDelayedMessagesToSequence(maxBlock)
+	if err != nil {
+		return err
+	}
+	if newDelayedCount.Cmp(oldDelayedCount) <= 0 {
+		return nil
+	}
+
+	msgCount, err := s.backend.arbcore.GetMessageCount()
+	if err != nil {
+		return err
+	}
+	prevAcc, err := s.prevInboxAcc(msgCount)
+	if err != nil {
+		return err
+	}
+	delayedAcc, err := s.backend.arbcore.GetDelayedInboxAcc(new(big.Int).Sub(newDelayedCount, big.NewInt(1)))
+	if err != nil {
+		return err
+	}
+	delayedRead := new(big.Int).Sub(newDelayedCount, oldDelayedCount)
+	lastSeqNum := new(big.Int).Add(msgCount, delayedRead)
+	lastSeqNum.Sub(lastSeqNum, big.NewInt(1))
+	batchItem := inbox.NewDelayedItem(lastSeqNum, newDelayedCount, prevAcc, oldDelayedCount, delayedAcc)
+
+	block := s.backend.l1Emulator.GenerateBlock()
+	endOfBlockMessage := message.NewInboxMessage(
+		message.EndBlockMessage{},
+		common.Address{},
+		new(big.Int).Add(lastSeqNum, big.NewInt(1)),
+		big.NewInt(0),
+		inbox.ChainTime{
+			BlockNum:  block.blockId.Height,
+			Timestamp: block.timestamp,
+		},
+	)
+	endBlockBatchItem := inbox.NewSequencerItem(newDelayedCount, endOfBlockMessage, batchItem.Accumulator)
+	logger.Info().
+		Str("old", oldDelayedCount.String()).
+		Str("new", newDelayedCount.String()).
+		Msg("sequencing delayed messages from simulated L1")
+	err = core.DeliverMessagesAndWait(ctx, s.backend.arbcore, msgCount, prevAcc, []inbox.SequencerBatchItem{batchItem, endBlockBatchItem}, nil, nil)
+	if err != nil {
+		return err
+	}
+	s.backend.delayedCount = newDelayedCount
+	return s.backend.waitForBlockCount(block.blockId.Height.AsInt().Uint64())
+}
+
+func (s *L1Sim) prevInboxAcc(msgCount *big.Int) (common.Hash, error) {
+	if msgCount.Sign() == 0 {
+		return common.Hash{}, nil
+	}
+	return s.backend.arbcore.GetInboxAcc(new(big.Int).Sub(msgCount, big.NewInt(1)))
+}
+
+func (s *L1Sim) postSends() error {
+	sendCount, err := s.backend.arbcore.GetSendCount()
+	if err != nil {
+		return err
+	}
+	if sendCount.Cmp(s.sendsPosted) <= 0 {
+		return nil
+	}
+	sends, err := s.backend.arbcore.GetSends(s.sendsPosted, new(big.Int).Sub(sendCount, s.sendsPosted))
+	if err != nil {
+		return err
+	}
+	var sendsData []byte
+	var sendLengths []*big.Int
+	for _, send := range sends {
+		sendsData = append(sendsData, send...)
+		sendLengths = append(sendLengths, big.NewInt(int64(len(send))))
+	}
+	err = s.backend.l1Emulator.mineTransaction(func() error {
+		_, err := s.outbox.ProcessOutgoingMessages(s.rollup, sendsData, sendLengths)
+		return errors.Wrap(err, "error posting sends to outbox")
+	})
+	if err != nil {
+		return err
+	}
+	s.sendsPosted = sendCount
+	return nil
+}
+
+func (s *L1Sim) account(from ethcommon.Address) (*bind.TransactOpts, error) {
+	auth, ok := s.accounts[from]
+	if !ok {
+		return nil, errors.Errorf("no simulated L1 account %v", from)
+	}
+	return auth, nil
+}
+
+// send runs an L1 transaction from the given account in a new L1 block
+func (s *L1Sim) send(ctx context.Context, from ethcommon.Address, value *big.Int, build func(*bind.TransactOpts) (*types.Transaction, error)) (ethcommon.Hash, error) {
+	s.Lock()
+	auth, err := s.account(from)
+	if err != nil {
+		s.Unlock()
+		return ethcommon.Hash{}, err
+	}
+	opts := *auth
+	opts.Context = ctx
+	opts.Value = value
+	var tx *types.Transaction
+	err = s.backend.l1Emulator.mineTransaction(func() error {
+		var err error
+		tx, err = build(&opts)
+		return errors.WithStack(err)
+	})
+	if err != nil {
+		s.Unlock()
+		return ethcommon.Hash{}, err
+	}
+	receipt, err := s.client.TransactionReceipt(ctx, tx.Hash())
+	s.Unlock()
+	if err != nil {
+		return ethcommon.Hash{}, errors.WithStack(err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return tx.Hash(), errors.New("L1 transaction reverted")
+	}
+	return tx.Hash(), s.Sync(ctx, false)
+}
+
+// Mine creates empty L1 blocks, allowing delayed messages to be sequenced
+func (s *L1Sim) Mine(ctx context.Context, blocks uint64) error {
+	s.Lock()
+	for i := uint64(0); i < blocks; i++ {
+		s.backend.l1Emulator.GenerateBlock()
+	}
+	s.Unlock()
+	return s.Sync(ctx, false)
+}
+
+// ExecuteTransaction executes an L2 to L1 send through the outbox using the
+// proof generated by the dev chain
+func (s *L1Sim) ExecuteTransaction(ctx context.Context, from ethcommon.Address, batchNum *big.Int, index uint64) (ethcommon.Hash, error) {
+	if err := s.Sync(ctx, false); err != nil {
+		return ethcommon.Hash{}, err
+	}
+	batch, err := s.backend.db.GetMessageBatch(batchNum)
+	if err != nil {
+		return ethcommon.Hash{}, err
+	}
+	if batch == nil {
+		return ethcommon.Hash{}, errors.New("batch doesn't exist")
+	}
+	proof, err := batch.GenerateProof(index)
+	if err != nil {
+		return ethcommon.Hash{}, err
+	}
+	res, err := evm.NewVirtualSendResultFromData(proof.Data)
+	if err != nil {
+		return ethcommon.Hash{}, err
+	}
+	send, ok := res.(*evm.L2ToL1TxResult)
+	if !ok {
+		return ethcommon.Hash{}, errors.New("send isn't an L2 to L1 transaction")
+	}
+	nodes := make([][32]byte, 0, len(proof.Nodes))
+	for _, node := range proof.Nodes {
+		nodes = append(nodes, [32]byte(node))
+	}
+	return s.send(ctx, from, nil, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return s.outbox.ExecuteTransaction(
+			opts,
+			batchNum,
+			nodes,
+			protocol.PathSliceToInt(proof.Path),
+			send.L2Sender.ToEthAddress(),
+			send.L1Dest.ToEthAddress(),
+			send.L2Block,
+			send.L1Block,
+			send.Timestamp,
+			send.Value,
+			send.Calldata,
+		)
+	})
+}
+
+// followChain makes the emulator mine its blocks on a simulated L1, first
+// catching the simulated L1 up with blocks already given to the dev chain
+func (b *L1Emulator) followChain(chain *ethutils.SimulatedEthClient) error {
+	b.Lock()
+	defer b.Unlock()
+	head := chain.Blockchain().CurrentBlock().NumberU64()
+	if b.latestHeight > head+maxL1SimCatchUp {
+		return errors.Errorf("simulated L1 can't start at L1 height %v, use a lower initial L1 height", b.latestHeight)
+	}
+	b.chain = chain
+	target := b.latestHeight
+	// Always mine one block to move the simulated clock to ours
+	b.mineChainBlock()
+	for b.latestHeight < target {
+		b.mineChainBlock()
+	}
+	return nil
+}
+
+func (b *L1Emulator) mineChainBlock() L1BlockInfo {
+	info, err := b.mineChainBlockWith(nil)
+	if err != nil {
+		// Only sending a transaction can fail
+		logger.Error().Err(err).Msg("error mining simulated L1 block")
+	}
+	return info
+}
+
+// mineTransaction mines a simulated L1 block containing the transaction sent
+// by send
+func (b *L1Emulator) mineTransaction(send func() error) error {
+	b.Lock()
+	defer b.Unlock()
+	_, err := b.mineChainBlockWith(send)
+	return err
+}
+
+// mineChainBlockWith mines a block on the simulated L1, moving its clock
+// forward to ours if it's behind. Simulated blocks are always at least 10
+// seconds after their parent, and the simulated backend may not keep the
+// adjusted time for a block with transactions, so the time of the mined block
+// is what's used.
+func (b *L1Emulator) mineChainBlockWith(send func() error) (L1BlockInfo, error) {
+	parent := b.chain.Blockchain().CurrentBlock()
+	offset := time.Now().Unix() + b.timeIncrease - int64(parent.Time()) - 10
+	if offset > 0 {
+		if err := b.chain.AdjustTime(time.Duration(offset) * time.Second); err != nil {
+			logger.Warn().Err(err).Msg("couldn't adjust simulated L1 time")
+		}
+	}
+	var sendErr error
+	if send != nil {
+		sendErr = send()
+	}
+	b.chain.Commit()
+	head := b.chain.Blockchain().CurrentBlock()
+	b.latestHeight = head.NumberU64()
+	info := L1BlockInfo{
+		blockId: &common.BlockId{
+			Height:     common.NewTimeBlocks(new(big.Int).Set(head.Number())),
+			HeaderHash: common.NewHashFromEth(head.Hash()),
+		},
+		timestamp: new(big.Int).SetUint64(head.Time()),
+	}
+	return info, sendErr
+}
+
+// L1SimAPI exposes the simulated L1 over RPC
+type L1SimAPI struct {
+	sim *L1Sim
+}
+
+func NewL1SimAPI(sim *L1Sim) *L1SimAPI {
+	return &L1SimAPI{sim: sim}
+}
+
+type L1SimContracts struct {
+	Bridge ethcommon.Address `json:"bridge"`
+	Inbox  ethcommon.Address `json:"inbox"`
+	Outbox ethcommon.Address `json:"outbox"`
+}
+
+func (a *L1SimAPI) Contracts() L1SimContracts {
+	return L1SimContracts{
+		Bridge: a.sim.bridgeAddress,
+		Inbox:  a.sim.inboxAddress,
+		Outbox: a.sim.outboxAddress,
+	}
+}
+
+func (a *L1SimAPI) BlockNumber() hexutil.Uint64 {
+	a.sim.Lock()
+	defer a.sim.Unlock()
+	return hexutil.Uint64(a.sim.client.Blockchain().CurrentBlock().NumberU64())
+}
+
+func (a *L1SimAPI) GetBalance(ctx context.Context, address ethcommon.Address) (*hexutil.Big, error) {
+	a.sim.Lock()
+	defer a.sim.Unlock()
+	balance, err := a.sim.client.BalanceAt(ctx, address, nil)
+	return (*hexutil.Big)(balance), err
+}
+
+func (a *L1SimAPI) DepositEth(ctx context.Context, from ethcommon.Address, value *hexutil.Big) (ethcommon.Hash, error) {
+	return a.sim.send(ctx, from, value.ToInt(), func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return a.sim.inbox.DepositEth(opts, big.NewInt(0))
+	})
+}
+
+type RetryableTicketArgs struct {
+	From                   ethcommon.Address `json:"from"`
+	Deposit                *hexutil.Big      `json:"deposit"`
+	To                     ethcommon.Address `json:"to"`
+	L2CallValue            *hexutil.Big      `json:"l2CallValue"`
+	MaxSubmissionCost      *hexutil.Big      `json:"maxSubmissionCost"`
+	ExcessFeeRefundAddress ethcommon.Address `json:"excessFeeRefundAddress"`
+	CallValueRefundAddress ethcommon.Address `json:"callValueRefundAddress"`
+	MaxGas                 *hexutil.Big      `json:"maxGas"`
+	GasPriceBid            *hexutil.Big      `json:"gasPriceBid"`
+	Data                   hexutil.Bytes     `json:"data"`
+}
+
+func (a *L1SimAPI) CreateRetryableTicket(ctx context.Context, args RetryableTicketArgs) (ethcommon.Hash, error) {
+	return a.sim.send(ctx, args.From, args.Deposit.ToInt(), func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return a.sim.inbox.CreateRetryableTicket(
+			opts,
+			args.To,
+			args.L2CallValue.ToInt(),
+			args.MaxSubmissionCost.ToInt(),
+			args.ExcessFeeRefundAddress,
+			args.CallValueRefundAddress,
+			args.MaxGas.ToInt(),
+			args.GasPriceBid.ToInt(),
+			args.Data,
+		)
+	})
+}
+
+func (a *L1SimAPI) SendL2Message(ctx context.Context, from ethcommon.Address, data hexutil.Bytes) (ethcommon.Hash, error) {
+	return a.sim.send(ctx, from, nil, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return a.sim.inbox.SendL2Message(opts, data)
+	})
+}
+
+func (a *L1SimAPI) ExecuteTransaction(ctx context.Context, from ethcommon.Address, batchNum *hexutil.Big, index hexutil.Uint64) (ethcommon.Hash, error) {
+	return a.sim.ExecuteTransaction(ctx, from, batchNum.ToInt(), uint64(index))
+}
+
+func (a *L1SimAPI) Mine(ctx context.Context, blocks hexutil.Uint64) error {
+	return a.sim.Mine(ctx, uint64(blocks))
+}
+
+// ForceInclusion sequences every delayed message regardless of its age
+func (a *L1SimAPI) ForceInclusion(ctx context.Context) error {
+	return a.sim.Sync(ctx, true)
+}
@@ -0,0 +1,345 @@
+/*
+* Copyright 2021, Offchain Labs, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package dev
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/arbos"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/arboscontracts"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/message"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/aggregator"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/txdb"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/web3"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/hashing"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/protocol"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+)
+
+type l1SimTest struct {
+	backend   *Backend
+	db        *txdb.TxDB
+	srv       *aggregator.Server
+	sim       *L1Sim
+	api       *L1SimAPI
+	key       *ecdsa.PrivateKey
+	depositor ethcommon.Address
+}
+
+func newL1SimTest(t *testing.T, delayBlocks, delaySeconds uint64) (*l1SimTest, func()) {
+	config := protocol.ChainParams{
+		GracePeriod:               common.NewTimeBlocksInt(3),
+		ArbGasSpeedLimitPerSecond: 2000000000000,
+	}
+	backend, db, srv, closeFunc := NewSimpleTestDevNode(t, config, common.RandAddress())
+
+	key := test.MustGenerateKey(t)
+	balance := new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+	sim, err := NewL1Sim(backend, []*ecdsa.PrivateKey{key}, balance, delayBlocks, delaySeconds)
+	test.FailIfError(t, err)
+	return &l1SimTest{
+		backend:   backend,
+		db:        db,
+		srv:       srv,
+		sim:       sim,
+		api:       NewL1SimAPI(sim),
+		key:       key,
+		depositor: crypto.PubkeyToAddress(key.PublicKey),
+	}, closeFunc
+}
+
+func (s *l1SimTest) l2Balance(t *testing.T, ctx context.Context, account ethcommon.Address) *big.Int {
+	t.Helper()
+	snap, err := s.db.LatestSnapshot(ctx)
+	test.FailIfError(t, err)
+	bal, err := snap.GetBalance(ctx, common.NewAddressFromEth(account))
+	test.FailIfError(t, err)
+	return bal
+}
+
+func TestL1SimDelayedDeposit(t *testing.T) {
+	ctx := context.Background()
+	s, closeFunc := newL1SimTest(t, 2, 0)
+	defer closeFunc()
+
+	_, err := s.api.DepositEth(ctx, s.depositor, (*hexutil.Big)(big.NewInt(1000)))
+	test.FailIfError(t, err)
+	if s.l2Balance(t, ctx, s.depositor).Sign() != 0 {
+		t.Fatal("deposit sequenced before delay")
+	}
+
+	test.FailIfError(t, s.api.Mine(ctx, 2))
+	if s.l2Balance(t, ctx, s.depositor).Sign() == 0 {
+		t.Fatal("deposit not sequenced after delay")
+	}
+
+	_, err = s.api.DepositEth(ctx, s.depositor, (*hexutil.Big)(big.NewInt(1000)))
+	test.FailIfError(t, err)
+	before := s.l2Balance(t, ctx, s.depositor)
+	test.FailIfError(t, s.api.ForceInclusion(ctx))
+	if s.l2Balance(t, ctx, s.depositor).Cmp(before) <= 0 {
+		t.Error("forced inclusion didn't sequence deposit")
+	}
+}
+
+func TestL1SimDelaySeconds(t *testing.T) {
+	ctx := context.Background()
+	s, closeFunc := newL1SimTest(t, 0, 100)
+	defer closeFunc()
+
+	_, err := s.api.DepositEth(ctx, s.depositor, (*hexutil.Big)(big.NewInt(1000)))
+	test.FailIfError(t, err)
+	// Simulated blocks are at least 10 seconds apart
+	test.FailIfError(t, s.api.Mine(ctx, 1))
+	if s.l2Balance(t, ctx, s.depositor).Sign() != 0 {
+		t.Fatal("deposit sequenced before delay")
+	}
+
+	s.backend.l1Emulator.IncreaseTime(200)
+	test.FailIfError(t, s.api.Mine(ctx, 1))
+	if s.l2Balance(t, ctx, s.depositor).Sign() == 0 {
+		t.Fatal("deposit not sequenced after delay")
+	}
+}
+
+func TestL1SimChainTime(t *testing.T) {
+	ctx := context.Background()
+	s, closeFunc := newL1SimTest(t, 0, 0)
+	defer closeFunc()
+	startHeight := s.backend.l1Emulator.LatestHeight()
+
+	_, err := s.api.DepositEth(ctx, s.depositor, (*hexutil.Big)(big.NewInt(1000)))
+	test.FailIfError(t, err)
+	_, err = s.backend.AddInboxMessage(ctx, message.NewSafeL2Message(message.HeartbeatMessage{}), common.RandAddress())
+	test.FailIfError(t, err)
+	test.FailIfError(t, s.api.Mine(ctx, 1))
+
+	chain := s.sim.client.Blockchain()
+	if s.backend.l1Emulator.LatestHeight() != chain.CurrentBlock().NumberU64() {
+		t.Error("dev chain at L1 block", s.backend.l1Emulator.LatestHeight(), "but simulated L1 at", chain.CurrentBlock().NumberU64())
+	}
+
+	msgCount, err := s.backend.arbcore.GetMessageCount()
+	test.FailIfError(t, err)
+	messages, err := s.backend.arbcore.GetMessages(big.NewInt(0), msgCount)
+	test.FailIfError(t, err)
+	checked := 0
+	for _, msg := range messages {
+		height := msg.ChainTime.BlockNum.AsInt().Uint64()
+		if height < startHeight {
+			continue
+		}
+		block := chain.GetBlockByNumber(height)
+		if block == nil {
+			t.Error("message", msg.InboxSeqNum, "in missing L1 block", height)
+			continue
+		}
+		if block.Time() != msg.ChainTime.Timestamp.Uint64() {
+			t.Error("message", msg.InboxSeqNum, "has timestamp", msg.ChainTime.Timestamp, "but its L1 block has", block.Time())
+		}
+		checked++
+	}
+	// The deposit, the end of block after sequencing it and the heartbeat
+	if checked < 3 {
+		t.Error("only checked", checked, "messages")
+	}
+}
+
+func TestL1SimExecuteTransaction(t *testing.T) {
+	ctx := context.Background()
+	s, closeFunc := newL1SimTest(t, 0, 0)
+	defer closeFunc()
+
+	_, err := s.api.DepositEth(ctx, s.depositor, (*hexutil.Big)(big.NewInt(1000000)))
+	test.FailIfError(t, err)
+
+	client := web3.NewEthClient(s.srv, true)
+	arbSys, err := arboscontracts.NewArbSys(arbos.ARB_SYS_ADDRESS, client)
+	test.FailIfError(t, err)
+	auth, err := bind.NewKeyedTransactorWithChainID(s.key, s.backend.chainID)
+	test.FailIfError(t, err)
+	auth.Value = big.NewInt(1000)
+	dest := common.RandAddress().ToEthAddress()
+	_, err = arbSys.SendTxToL1(auth, dest, nil)
+	test.FailIfError(t, err)
+	// ArbOS spaces out send batches every 1800 seconds by default
+	s.backend.l1Emulator.IncreaseTime(1800)
+	_, err = arbSys.SendTxToL1(auth, common.RandAddress().ToEthAddress(), nil)
+	test.FailIfError(t, err)
+
+	_, err = s.api.ExecuteTransaction(ctx, s.depositor, (*hexutil.Big)(big.NewInt(0)), 0)
+	test.FailIfError(t, err)
+	balance, err := s.api.GetBalance(ctx, dest)
+	test.FailIfError(t, err)
+	if balance.ToInt().Cmp(big.NewInt(1000)) != 0 {
+		t.Error("L1 destination received", balance)
+	}
+
+	if _, err := s.api.ExecuteTransaction(ctx, s.depositor, (*hexutil.Big)(big.NewInt(0)), 0); err == nil {
+		t.Error("executed the same send twice")
+	}
+	if _, err := s.api.ExecuteTransaction(ctx, s.depositor, (*hexutil.Big)(big.NewInt(5)), 0); err == nil {
+		t.Error("executed a send from a missing batch")
+	}
+}
+
+func TestL1SimRevertSends(t *testing.T) {
+	ctx := context.Background()
+	s, closeFunc := newL1SimTest(t, 0, 0)
+	defer closeFunc()
+
+	_, err := s.api.DepositEth(ctx, s.depositor, (*hexutil.Big)(big.NewInt(1000000)))
+	test.FailIfError(t, err)
+	client := web3.NewEthClient(s.srv, true)
+	arbSys, err := arboscontracts.NewArbSys(arbos.ARB_SYS_ADDRESS, client)
+	test.FailIfError(t, err)
+	auth, err := bind.NewKeyedTransactorWithChainID(s.key, s.backend.chainID)
+	test.FailIfError(t, err)
+	auth.Value = big.NewInt(1000)
+	sendBatch := func() {
+		t.Helper()
+		_, err := arbSys.SendTxToL1(auth, common.RandAddress().ToEthAddress(), nil)
+		test.FailIfError(t, err)
+		s.backend.l1Emulator.IncreaseTime(1800)
+		_, err = arbSys.SendTxToL1(auth, common.RandAddress().ToEthAddress(), nil)
+		test.FailIfError(t, err)
+		test.FailIfError(t, s.sim.Sync(ctx, false))
+	}
+	checkPosted := func() *big.Int {
+		t.Helper()
+		sendCount, err := s.backend.arbcore.GetSendCount()
+		test.FailIfError(t, err)
+		s.backend.Lock()
+		posted := new(big.Int).Set(s.sim.sendsPosted)
+		s.backend.Unlock()
+		if posted.Cmp(sendCount) != 0 {
+			t.Error("posted", posted, "sends but chain has", sendCount)
+		}
+		return posted
+	}
+
+	evm := NewEVM(s.backend)
+	snap, err := evm.Snapshot()
+	test.FailIfError(t, err)
+	reverted := checkPosted()
+	sendBatch()
+	if checkPosted().Cmp(reverted) <= 0 {
+		t.Fatal("no sends posted")
+	}
+
+	test.FailIfError(t, evm.Revert(ctx, snap))
+	if checkPosted().Cmp(reverted) != 0 {
+		t.Error("reverted sends still counted as posted")
+	}
+	// Sends of the new chain are posted even while there are fewer of them
+	// than were posted before the revert
+	sendBatch()
+	if checkPosted().Cmp(reverted) <= 0 {
+		t.Error("no sends posted after revert")
+	}
+}
+
+func TestL1SimLoadState(t *testing.T) {
+	ctx := context.Background()
+	s, closeFunc := newL1SimTest(t, 0, 0)
+	defer closeFunc()
+
+	_, err := s.api.DepositEth(ctx, s.depositor, (*hexutil.Big)(big.NewInt(1000)))
+	test.FailIfError(t, err)
+	state, err := s.backend.ExportData()
+	test.FailIfError(t, err)
+	msgCount, err := s.backend.arbcore.GetMessageCount()
+	test.FailIfError(t, err)
+	if err := NewStateAPI(s.backend).LoadState(ctx, state); err == nil {
+		t.Fatal("loaded state on simulated L1")
+	}
+	newMsgCount, err := s.backend.arbcore.GetMessageCount()
+	test.FailIfError(t, err)
+	if newMsgCount.Cmp(msgCount) != 0 {
+		t.Error("refused load changed message count from", msgCount, "to", newMsgCount)
+	}
+
+	// Delayed messages are still delivered after the refused load
+	_, err = s.api.DepositEth(ctx, s.depositor, (*hexutil.Big)(big.NewInt(1000)))
+	test.FailIfError(t, err)
+	if balance := s.l2Balance(t, ctx, s.depositor); balance.Cmp(big.NewInt(2000)) != 0 {
+		t.Error("depositor has balance", balance)
+	}
+}
+
+func TestL1SimRetryable(t *testing.T) {
+	ctx := context.Background()
+	s, closeFunc := newL1SimTest(t, 0, 0)
+	defer closeFunc()
+
+	client := web3.NewEthClient(s.srv, true)
+	retryable, err := arboscontracts.NewArbRetryableTx(arbos.ARB_RETRYABLE_ADDRESS, client)
+	test.FailIfError(t, err)
+
+	createTicket := func(maxGas int64) (ethcommon.Address, common.Hash) {
+		t.Helper()
+		dest := common.RandAddress().ToEthAddress()
+		_, err := s.api.CreateRetryableTicket(ctx, RetryableTicketArgs{
+			From:                   s.depositor,
+			Deposit:                (*hexutil.Big)(big.NewInt(1000000000000)),
+			To:                     dest,
+			L2CallValue:            (*hexutil.Big)(big.NewInt(20)),
+			MaxSubmissionCost:      (*hexutil.Big)(big.NewInt(30)),
+			ExcessFeeRefundAddress: s.depositor,
+			CallValueRefundAddress: s.depositor,
+			MaxGas:                 (*hexutil.Big)(big.NewInt(maxGas)),
+			GasPriceBid:            (*hexutil.Big)(big.NewInt(10)),
+		})
+		test.FailIfError(t, err)
+		test.FailIfError(t, s.api.ForceInclusion(ctx))
+		// The ticket is followed by the end of block message
+		msgCount, err := s.backend.arbcore.GetMessageCount()
+		test.FailIfError(t, err)
+		requestId := message.CalculateRequestId(s.backend.chainID, new(big.Int).Sub(msgCount, big.NewInt(2)))
+		ticketId := hashing.SoliditySHA3(hashing.Bytes32(requestId), hashing.Uint256(big.NewInt(0)))
+		return dest, ticketId
+	}
+
+	// With gas the ticket is redeemed immediately
+	dest, ticketId := createTicket(1000000)
+	if balance := s.l2Balance(t, ctx, dest); balance.Cmp(big.NewInt(20)) != 0 {
+		t.Error("redeemed ticket delivered", balance)
+	}
+	if _, err := retryable.GetBeneficiary(&bind.CallOpts{}, ticketId); err == nil {
+		t.Error("redeemed ticket still exists")
+	}
+
+	// Without gas it waits to be redeemed
+	dest, ticketId = createTicket(0)
+	if balance := s.l2Balance(t, ctx, dest); balance.Sign() != 0 {
+		t.Error("unredeemed ticket delivered", balance)
+	}
+	beneficiary, err := retryable.GetBeneficiary(&bind.CallOpts{}, ticketId)
+	test.FailIfError(t, err)
+	if beneficiary != s.depositor {
+		t.Error("wrong ticket beneficiary", beneficiary.Hex())
+	}
+}
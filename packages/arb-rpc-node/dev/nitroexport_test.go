@@ -0,0 +1,229 @@
+/*
+* Copyright 2022, Offchain Labs, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package dev
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	gethcore "github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/arbos"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/arboscontracts"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/message"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/nitroexport"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/web3"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/protocol"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+)
+
+func TestExportRetryables(t *testing.T) {
+	ctx := context.Background()
+	s, closeFunc := newL1SimTest(t, 0, 0)
+	defer closeFunc()
+
+	createTicket := func(maxGas int64) common.Hash {
+		t.Helper()
+		_, err := s.api.CreateRetryableTicket(ctx, RetryableTicketArgs{
+			From:                   s.depositor,
+			Deposit:                (*hexutil.Big)(big.NewInt(1000000000000)),
+			To:                     common.RandAddress().ToEthAddress(),
+			L2CallValue:            (*hexutil.Big)(big.NewInt(20)),
+			MaxSubmissionCost:      (*hexutil.Big)(big.NewInt(30)),
+			ExcessFeeRefundAddress: s.depositor,
+			CallValueRefundAddress: s.depositor,
+			MaxGas:                 (*hexutil.Big)(big.NewInt(maxGas)),
+			GasPriceBid:            (*hexutil.Big)(big.NewInt(10)),
+			Data:                   []byte{1, 2, 3},
+		})
+		test.FailIfError(t, err)
+		test.FailIfError(t, s.api.ForceInclusion(ctx))
+		// The ticket is followed by the end of block message
+		msgCount, err := s.backend.arbcore.GetMessageCount()
+		test.FailIfError(t, err)
+		requestId := message.CalculateRequestId(s.backend.chainID, new(big.Int).Sub(msgCount, big.NewInt(2)))
+		return requestId
+	}
+
+	// Redeemed tickets are left out
+	createTicket(1000000)
+	pendingRequest := createTicket(0)
+
+	latest, err := s.db.LatestBlock()
+	test.FailIfError(t, err)
+	var buf bytes.Buffer
+	count, err := nitroexport.ExportRetryables(ctx, s.db, latest.Header.Number.Uint64(), &buf)
+	test.FailIfError(t, err)
+	if count != 1 {
+		t.Fatal("exported", count, "retryables")
+	}
+	var entry nitroexport.RetryableEntry
+	test.FailIfError(t, json.Unmarshal(buf.Bytes(), &entry))
+	ticketId := nitroexport.RetryableTicketId(pendingRequest)
+	if entry.TicketId != ticketId.ToEthHash() || entry.RequestId != pendingRequest.ToEthHash() {
+		t.Error("wrong ticket", entry.TicketId.Hex())
+	}
+	if entry.Callvalue.ToInt().Cmp(big.NewInt(20)) != 0 || entry.Beneficiary != s.depositor || !bytes.Equal(entry.Calldata, []byte{1, 2, 3}) {
+		t.Error("wrong ticket contents", entry)
+	}
+	if entry.Timeout <= latest.Header.Time {
+		t.Error("exported ticket timed out at", entry.Timeout)
+	}
+
+	client := web3.NewEthClient(s.srv, true)
+	retryable, err := arboscontracts.NewArbRetryableTx(arbos.ARB_RETRYABLE_ADDRESS, client)
+	test.FailIfError(t, err)
+	timeout, err := retryable.GetTimeout(&bind.CallOpts{}, ticketId)
+	test.FailIfError(t, err)
+	if timeout.Uint64() != entry.Timeout {
+		t.Error("exported timeout", entry.Timeout, "but ArbOS has", timeout)
+	}
+}
+
+func TestFindExecutedOutboxEntries(t *testing.T) {
+	ctx := context.Background()
+	s, closeFunc := newL1SimTest(t, 0, 0)
+	defer closeFunc()
+
+	_, err := s.api.DepositEth(ctx, s.depositor, (*hexutil.Big)(big.NewInt(1000000)))
+	test.FailIfError(t, err)
+	client := web3.NewEthClient(s.srv, true)
+	arbSys, err := arboscontracts.NewArbSys(arbos.ARB_SYS_ADDRESS, client)
+	test.FailIfError(t, err)
+	auth, err := bind.NewKeyedTransactorWithChainID(s.key, s.backend.chainID)
+	test.FailIfError(t, err)
+	auth.Value = big.NewInt(1000)
+	for i := 0; i < 3; i++ {
+		_, err = arbSys.SendTxToL1(auth, common.RandAddress().ToEthAddress(), nil)
+		test.FailIfError(t, err)
+	}
+	// ArbOS spaces out send batches every 1800 seconds by default
+	s.backend.l1Emulator.IncreaseTime(1800)
+	_, err = arbSys.SendTxToL1(auth, common.RandAddress().ToEthAddress(), nil)
+	test.FailIfError(t, err)
+
+	batch, err := s.db.GetMessageBatch(big.NewInt(0))
+	test.FailIfError(t, err)
+	if batch == nil || batch.NumInBatch.Uint64() < 3 {
+		t.Fatal("expected a batch of sends, got", batch)
+	}
+	_, err = s.api.ExecuteTransaction(ctx, s.depositor, (*hexutil.Big)(big.NewInt(0)), 1)
+	test.FailIfError(t, err)
+	proof, err := batch.GenerateProof(1)
+	test.FailIfError(t, err)
+	executedId := nitroexport.OutboxEntryId{BatchNumber: 0, Path: protocol.PathSliceToInt(proof.Path).Uint64()}
+
+	// Searching in small ranges finds the same entries
+	for _, searchSize := range []int64{0, 1, 3} {
+		executed, err := nitroexport.FindExecutedOutboxEntries(ctx, s.sim.client, s.sim.bridgeAddress, 0, searchSize)
+		test.FailIfError(t, err)
+		if len(executed) != 1 || !executed[executedId] {
+			t.Error("found executed entries", executed, "searching", searchSize, "blocks at a time")
+		}
+	}
+
+	executed, err := nitroexport.FindExecutedOutboxEntries(ctx, s.sim.client, s.sim.bridgeAddress, 0, 0)
+	test.FailIfError(t, err)
+	var buf bytes.Buffer
+	count, err := nitroexport.ExportOutbox(ctx, s.db, executed, &buf)
+	test.FailIfError(t, err)
+	if count != batch.NumInBatch.Uint64()-1 {
+		t.Error("exported", count, "of", batch.NumInBatch, "sends after one was executed")
+	}
+	decoder := json.NewDecoder(&buf)
+	for decoder.More() {
+		var entry nitroexport.OutboxEntry
+		test.FailIfError(t, decoder.Decode(&entry))
+		if entry.BatchNumber == executedId.BatchNumber && entry.Path == executedId.Path {
+			t.Error("exported executed entry")
+		}
+	}
+}
+
+// newExportTestChain creates a dev chain with blocks holding deposits to
+// random accounts, which are returned with their balances
+func newExportTestChain(t *testing.T) (*Backend, map[common.Address]*big.Int, func()) {
+	ctx := context.Background()
+	config := protocol.ChainParams{
+		GracePeriod:               common.NewTimeBlocksInt(3),
+		ArbGasSpeedLimitPerSecond: 2000000000000,
+	}
+	backend, _, _, closeFunc := NewSimpleTestDevNode(t, config, common.RandAddress())
+	balances := make(map[common.Address]*big.Int)
+	for i := int64(1); i <= 3; i++ {
+		dest := common.RandAddress()
+		deposit := message.EthDepositTx{
+			L2Message: message.NewSafeL2Message(message.ContractTransaction{
+				BasicTx: message.BasicTx{
+					MaxGas:      big.NewInt(1000000),
+					GasPriceBid: big.NewInt(0),
+					DestAddress: dest,
+					Payment:     big.NewInt(100 * i),
+				},
+			}),
+		}
+		_, err := backend.AddInboxMessage(ctx, deposit, common.RandAddress())
+		test.FailIfError(t, err)
+		balances[dest] = big.NewInt(100 * i)
+	}
+	return backend, balances, closeFunc
+}
+
+func TestExportGenesis(t *testing.T) {
+	ctx := context.Background()
+	backend, balances, closeFunc := newExportTestChain(t)
+	defer closeFunc()
+
+	latest, err := backend.db.LatestBlock()
+	test.FailIfError(t, err)
+	dir := filepath.Join(t.TempDir(), "state")
+	test.FailIfError(t, nitroexport.ExportState(ctx, backend.db, backend.arbcore, latest.Header.Number.Uint64(), dir))
+	var buf bytes.Buffer
+	_, err = nitroexport.WriteGenesis(dir, nitroexport.GenesisChainConfig(backend.chainID), latest.Header, &buf)
+	test.FailIfError(t, err)
+
+	var genesis gethcore.Genesis
+	test.FailIfError(t, json.Unmarshal(buf.Bytes(), &genesis))
+	ethDB := rawdb.NewMemoryDatabase()
+	block, err := genesis.Commit(ethDB)
+	test.FailIfError(t, err)
+	if block.Time() != latest.Header.Time {
+		t.Error("genesis has time", block.Time(), "instead of", latest.Header.Time)
+	}
+	genesisBlock, err := backend.db.GetBlock(0)
+	test.FailIfError(t, err)
+	if block.Hash() == genesisBlock.Header.Hash() || block.Hash() == latest.Header.Hash() {
+		t.Error("genesis matches a classic block")
+	}
+
+	statedb, err := state.New(block.Root(), state.NewDatabase(ethDB), nil)
+	test.FailIfError(t, err)
+	for account, balance := range balances {
+		if imported := statedb.GetBalance(account.ToEthAddress()); imported.Cmp(balance) != 0 {
+			t.Error("account", account, "imported with balance", imported, "instead of", balance)
+		}
+	}
+}
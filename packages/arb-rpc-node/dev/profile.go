@@ -0,0 +1,500 @@
+/*
+* Copyright 2021, Offchain Labs, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package dev
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+
+	ethcore "github.com/ethereum/go-ethereum/core"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+)
+
+const profileMaxGas = 100000000000
+
+// ProfileCode identifies a piece of EVM code. Constructors run different
+// code than the contract they deploy so they're tracked separately.
+type ProfileCode struct {
+	Address     common.Address
+	Constructor bool
+}
+
+func (c ProfileCode) String() string {
+	if c.Constructor {
+		return c.Address.Hex() + ":constructor"
+	}
+	return c.Address.Hex()
+}
+
+// Profiler aggregates the execution of every transaction mined since it was
+// started or reset. Each transaction is re-executed with tracing to recover
+// the opcodes it ran and its call tree. ArbOS reports gas per call frame, not
+// per opcode, so gas is attributed to call stacks. Blocks are profiled as
+// they're produced, and any left are profiled before a reorg drops them, so
+// reverted transactions are still included.
+type Profiler struct {
+	backend *Backend
+
+	sync.Mutex
+	nextBlock uint64
+	coverage  map[ProfileCode]map[uint64]uint64
+	code      map[ProfileCode][]byte
+	stackGas  map[string]*big.Int
+}
+
+// NewProfiler creates a profiler which follows the chain of backend until
+// ctx is cancelled
+func NewProfiler(ctx context.Context, backend *Backend) (*Profiler, error) {
+	p := &Profiler{backend: backend}
+	if err := p.Reset(); err != nil {
+		return nil, err
+	}
+	backend.BeforeReorg(p.beforeReorg)
+	backend.OnReorg(p.afterReorg)
+	p.follow(ctx)
+	return p, nil
+}
+
+// follow profiles new blocks in the background. The chain feed holds up
+// block production until its events are received, while the backend stays
+// locked waiting for blocks, so events only signal a separate goroutine which
+// locks the backend to profile.
+func (p *Profiler) follow(ctx context.Context) {
+	events := make(chan ethcore.ChainEvent)
+	sub := p.backend.db.SubscribeChainEvent(events)
+	updates := make(chan struct{}, 1)
+	go func() {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sub.Err():
+				return
+			case <-events:
+				select {
+				case updates <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-updates:
+				if err := p.Update(); err != nil {
+					logger.Warn().Err(err).Msg("error profiling new blocks")
+				}
+			}
+		}
+	}()
+}
+
+// Reset discards collected data and starts profiling from the next block
+func (p *Profiler) Reset() error {
+	blockCount, err := p.backend.db.BlockCount()
+	if err != nil {
+		return err
+	}
+	p.Lock()
+	defer p.Unlock()
+	p.nextBlock = blockCount
+	p.coverage = make(map[ProfileCode]map[uint64]uint64)
+	p.code = make(map[ProfileCode][]byte)
+	p.stackGas = make(map[string]*big.Int)
+	return nil
+}
+
+// Update profiles all blocks mined since the last update
+func (p *Profiler) Update() error {
+	p.backend.Lock()
+	defer p.backend.Unlock()
+	p.Lock()
+	defer p.Unlock()
+	return p.update()
+}
+
+// beforeReorg profiles the blocks a reorg is about to drop
+func (p *Profiler) beforeReorg() error {
+	p.Lock()
+	defer p.Unlock()
+	return p.update()
+}
+
+// afterReorg continues from the end of the reorged chain
+func (p *Profiler) afterReorg(blockCount uint64) error {
+	p.Lock()
+	defer p.Unlock()
+	if p.nextBlock > blockCount {
+		p.nextBlock = blockCount
+	}
+	return nil
+}
+
+// update profiles new blocks with both the backend and profiler locked
+func (p *Profiler) update() error {
+	blockCount, err := p.backend.db.BlockCount()
+	if err != nil {
+		return err
+	}
+	for ; p.nextBlock < blockCount; p.nextBlock++ {
+		if err := p.profileBlock(p.nextBlock); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Profiler) profileBlock(height uint64) error {
+	if height == 0 {
+		return nil
+	}
+	db := p.backend.db
+	blockInfo, err := db.GetBlock(height)
+	if err != nil || blockInfo == nil {
+		return err
+	}
+	blockLog, txResults, err := db.GetBlockResults(blockInfo)
+	if err != nil {
+		return err
+	}
+	cursor, err := p.backend.arbcore.GetExecutionCursorAtEndOfBlock(height-1, true)
+	if err != nil {
+		return err
+	}
+	snap, err := db.GetSnapshot(context.Background(), height)
+	if err != nil {
+		return err
+	}
+	codeAt := func(account common.Address) ([]byte, error) {
+		if snap == nil {
+			return nil, nil
+		}
+		return snap.GetCode(context.Background(), account)
+	}
+	logIndex := blockLog.FirstAVMLog()
+	for i := uint64(0); i < blockLog.BlockStats.TxCount.Uint64(); i++ {
+		emissions, err := p.backend.arbcore.AdvanceExecutionCursorWithTracing(
+			cursor,
+			big.NewInt(profileMaxGas),
+			true,
+			true,
+			logIndex,
+			new(big.Int).Add(logIndex, big.NewInt(1)),
+		)
+		logIndex = new(big.Int).Add(logIndex, big.NewInt(1))
+		if err != nil {
+			return err
+		}
+		if err := p.profileTransaction(emissionLogLines(emissions), codeAt); err != nil {
+			logger.
+				Warn().
+				Uint64("block", height).
+				Str("txhash", txResults[i].IncomingRequest.MessageID.String()).
+				Err(err).
+				Msg("error profiling transaction")
+		}
+	}
+	return nil
+}
+
+func emissionLogLines(emissions []core.MachineEmission) []evm.EVMLogLine {
+	lines := make([]evm.EVMLogLine, 0, len(emissions))
+	for _, emission := range emissions {
+		line, err := evm.NewLogLineFromValue(emission.Value)
+		if err != nil {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+type profileFrame struct {
+	frame  *evm.CallFrame
+	code   ProfileCode
+	nested int
+}
+
+func newProfileFrame(frame evm.Frame) *profileFrame {
+	callFrame := frame.GetCallFrame()
+	var code ProfileCode
+	switch frame := frame.(type) {
+	case *evm.CreateFrame:
+		code = ProfileCode{Address: frame.Create.ContractAddress, Constructor: true}
+	case *evm.Create2Frame:
+		code = ProfileCode{Address: frame.Create.ContractAddress, Constructor: true}
+	default:
+		if callFrame.Call.To != nil {
+			code = ProfileCode{Address: *callFrame.Call.To}
+		}
+	}
+	return &profileFrame{frame: callFrame, code: code}
+}
+
+func framePC(frame evm.Frame) *uint64 {
+	switch frame := frame.(type) {
+	case *evm.CreateFrame:
+		return frame.Create.PC
+	case *evm.Create2Frame:
+		return frame.Create.PC
+	default:
+		return frame.GetCallFrame().Call.PC
+	}
+}
+
+func isCallOp(op uint64) bool {
+	switch op {
+	case 0xf0, 0xf1, 0xf2, 0xf4, 0xf5, 0xfa:
+		return true
+	default:
+		return false
+	}
+}
+
+func isHaltOp(op uint64) bool {
+	switch op {
+	case 0x00, 0xf3, 0xfd, 0xfe, 0xff:
+		return true
+	default:
+		return false
+	}
+}
+
+// instructionPCs returns the pc of every instruction in code, skipping the
+// data pushed by PUSH1 to PUSH32
+func instructionPCs(code []byte) []uint64 {
+	var pcs []uint64
+	for pc := 0; pc < len(code); pc++ {
+		pcs = append(pcs, uint64(pc))
+		if op := code[pc]; op >= 0x60 && op <= 0x7f {
+			pc += int(op) - 0x5f
+		}
+	}
+	return pcs
+}
+
+// recordCode saves the code run by a frame so unexecuted instructions can be
+// reported. Constructor code comes from the trace, deployed code is read from
+// the state at the end of the block.
+func (p *Profiler) recordCode(frame evm.Frame, code ProfileCode, codeAt func(common.Address) ([]byte, error)) error {
+	if _, ok := p.code[code]; ok {
+		return nil
+	}
+	switch frame := frame.(type) {
+	case *evm.CreateFrame:
+		p.code[code] = frame.Create.Code
+	case *evm.Create2Frame:
+		p.code[code] = frame.Create.Code
+	default:
+		contractCode, err := codeAt(code.Address)
+		if err != nil {
+			return err
+		}
+		p.code[code] = contractCode
+	}
+	return nil
+}
+
+// ranCode returns whether the frame executed any opcodes. Calls to accounts
+// without code and precompiles return without a pc.
+func ranCode(frame *evm.CallFrame) bool {
+	return frame.Return != nil && frame.Return.PC != nil
+}
+
+// profileTransaction matches the opcode stream against the call tree. A call
+// opcode enters the next nested frame and the frame is left at the pc it
+// returned from.
+func (p *Profiler) profileTransaction(lines []evm.EVMLogLine, codeAt func(common.Address) ([]byte, error)) error {
+	trace, err := evm.GetTraceFromLogLines(lines)
+	if err != nil {
+		return err
+	}
+	root, err := trace.FrameTree()
+	if err != nil || root == nil {
+		return err
+	}
+	p.addStackGas(nil, root)
+
+	stack := []*profileFrame{newProfileFrame(root)}
+	if ranCode(root.GetCallFrame()) {
+		if err := p.recordCode(root, stack[0].code, codeAt); err != nil {
+			return err
+		}
+	}
+	for _, line := range lines {
+		opLog, ok := line.(*evm.EVMOpcodeLog)
+		if !ok {
+			continue
+		}
+		if len(stack) == 0 {
+			return errors.New("opcode executed after top level frame returned")
+		}
+		top := stack[len(stack)-1]
+		pcs := p.coverage[top.code]
+		if pcs == nil {
+			pcs = make(map[uint64]uint64)
+			p.coverage[top.code] = pcs
+		}
+		pcs[opLog.PC()]++
+
+		if isCallOp(opLog.Op()) && top.nested < len(top.frame.Nested) {
+			next := top.frame.Nested[top.nested]
+			if pc := framePC(next); pc == nil || *pc == opLog.PC() {
+				top.nested++
+				if ranCode(next.GetCallFrame()) {
+					nextFrame := newProfileFrame(next)
+					if err := p.recordCode(next, nextFrame.code, codeAt); err != nil {
+						return err
+					}
+					stack = append(stack, nextFrame)
+				}
+				continue
+			}
+		}
+		ret := top.frame.Return
+		if ranCode(top.frame) && *ret.PC == opLog.PC() && (isHaltOp(opLog.Op()) || ret.Result != evm.ReturnCode) {
+			stack = stack[:len(stack)-1]
+		}
+	}
+	return nil
+}
+
+// addStackGas records the gas used by a frame excluding its nested calls
+// under the stack of code that led to it
+func (p *Profiler) addStackGas(parents []string, frame evm.Frame) {
+	callFrame := frame.GetCallFrame()
+	if callFrame.Return == nil {
+		return
+	}
+	stack := append(append([]string(nil), parents...), newProfileFrame(frame).code.String())
+	gas := new(big.Int).Set(callFrame.Return.GasUsed)
+	for _, nested := range callFrame.Nested {
+		if nestedReturn := nested.GetCallFrame().Return; nestedReturn != nil {
+			gas = gas.Sub(gas, nestedReturn.GasUsed)
+		}
+		p.addStackGas(stack, nested)
+	}
+	if gas.Sign() <= 0 {
+		return
+	}
+	key := strings.Join(stack, ";")
+	total, ok := p.stackGas[key]
+	if !ok {
+		total = big.NewInt(0)
+		p.stackGas[key] = total
+	}
+	total.Add(total, gas)
+}
+
+// WriteLcov writes the program counters of every instruction in lcov format,
+// with unexecuted instructions given a count of 0. Each piece of code is a
+// source file named after its address and each pc is a line.
+func (p *Profiler) WriteLcov(w io.Writer) error {
+	p.Lock()
+	defer p.Unlock()
+	codes := make([]ProfileCode, 0, len(p.coverage))
+	for code := range p.coverage {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool {
+		return codes[i].String() < codes[j].String()
+	})
+	out := bufio.NewWriter(w)
+	for _, code := range codes {
+		counts := p.coverage[code]
+		// Executed pcs missing from the recorded code are kept in case the
+		// code was replaced before the end of its block
+		pcs := make([]uint64, 0, len(counts))
+		for pc := range counts {
+			pcs = append(pcs, pc)
+		}
+		for _, pc := range instructionPCs(p.code[code]) {
+			if _, ok := counts[pc]; !ok {
+				pcs = append(pcs, pc)
+			}
+		}
+		sort.Slice(pcs, func(i, j int) bool { return pcs[i] < pcs[j] })
+		fmt.Fprintf(out, "TN:\nSF:%v\n", code)
+		for _, pc := range pcs {
+			fmt.Fprintf(out, "DA:%v,%v\n", pc, counts[pc])
+		}
+		fmt.Fprintf(out, "LF:%v\nLH:%v\nend_of_record\n", len(pcs), len(counts))
+	}
+	return errors.WithStack(out.Flush())
+}
+
+// WriteFoldedGas writes the gas used by each call stack in the folded
+// format read by flamegraph tools
+func (p *Profiler) WriteFoldedGas(w io.Writer) error {
+	p.Lock()
+	defer p.Unlock()
+	stacks := make([]string, 0, len(p.stackGas))
+	for stack := range p.stackGas {
+		stacks = append(stacks, stack)
+	}
+	sort.Strings(stacks)
+	out := bufio.NewWriter(w)
+	for _, stack := range stacks {
+		fmt.Fprintf(out, "%v %v\n", stack, p.stackGas[stack])
+	}
+	return errors.WithStack(out.Flush())
+}
+
+// ProfileAPI exposes the profiler over RPC
+type ProfileAPI struct {
+	profiler *Profiler
+}
+
+func NewProfileAPI(profiler *Profiler) *ProfileAPI {
+	return &ProfileAPI{profiler: profiler}
+}
+
+func (p *ProfileAPI) Reset() error {
+	return p.profiler.Reset()
+}
+
+func (p *ProfileAPI) Coverage() (string, error) {
+	if err := p.profiler.Update(); err != nil {
+		return "", err
+	}
+	var builder strings.Builder
+	err := p.profiler.WriteLcov(&builder)
+	return builder.String(), err
+}
+
+func (p *ProfileAPI) GasFlamegraph() (string, error) {
+	if err := p.profiler.Update(); err != nil {
+		return "", err
+	}
+	var builder strings.Builder
+	err := p.profiler.WriteFoldedGas(&builder)
+	return builder.String(), err
+}
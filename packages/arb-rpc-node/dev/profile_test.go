@@ -0,0 +1,177 @@
+/*
+* Copyright 2021, Offchain Labs, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package dev
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/arbostestcontracts"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/web3"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/protocol"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+func opcodeLine(t *testing.T, pc int64, op int64) evm.EVMLogLine {
+	t.Helper()
+	tup, err := value.NewTupleFromSlice([]value.Value{
+		value.NewInt64Value(30000),
+		value.NewInt64Value(pc),
+		value.NewInt64Value(op),
+	})
+	test.FailIfError(t, err)
+	line, err := evm.NewLogLineFromValue(tup)
+	test.FailIfError(t, err)
+	return line
+}
+
+func TestProfileTransaction(t *testing.T) {
+	outer := common.RandAddress()
+	inner := common.RandAddress()
+	pc := func(pc uint64) *uint64 { return &pc }
+	trace := &evm.EVMTrace{Items: []evm.TraceItem{
+		&evm.CallTrace{Type: evm.Call, To: &outer, Value: big.NewInt(0), Gas: big.NewInt(1000), GasPrice: big.NewInt(0)},
+		&evm.CallTrace{Type: evm.Call, From: outer, To: &inner, Value: big.NewInt(0), Gas: big.NewInt(500), GasPrice: big.NewInt(0), PC: pc(10)},
+		&evm.ReturnTrace{Result: evm.ReturnCode, GasUsed: big.NewInt(30), PC: pc(3)},
+		&evm.ReturnTrace{Result: evm.ReturnCode, GasUsed: big.NewInt(100), PC: pc(12)},
+	}}
+	lines := []evm.EVMLogLine{
+		opcodeLine(t, 0, 0x60),
+		opcodeLine(t, 10, 0xf1),
+		opcodeLine(t, 0, 0x60),
+		opcodeLine(t, 3, 0xf3),
+		opcodeLine(t, 11, 0x50),
+		opcodeLine(t, 12, 0xf3),
+		trace,
+	}
+
+	// Inner runs PUSH1 0 and RETURN, skipping the POP at 2 and STOP at 4
+	code := map[common.Address][]byte{
+		outer: {0x60, 0x00, 0x7f},
+		inner: {0x60, 0x00, 0x50, 0xf3, 0x00},
+	}
+	codeAt := func(account common.Address) ([]byte, error) {
+		return code[account], nil
+	}
+
+	p := &Profiler{
+		coverage: make(map[ProfileCode]map[uint64]uint64),
+		code:     make(map[ProfileCode][]byte),
+		stackGas: make(map[string]*big.Int),
+	}
+	test.FailIfError(t, p.profileTransaction(lines, codeAt))
+	test.FailIfError(t, p.profileTransaction(lines, codeAt))
+
+	outerCoverage := p.coverage[ProfileCode{Address: outer}]
+	for _, pc := range []uint64{0, 10, 11, 12} {
+		if outerCoverage[pc] != 2 {
+			t.Error("outer pc", pc, "executed", outerCoverage[pc], "times")
+		}
+	}
+	innerCoverage := p.coverage[ProfileCode{Address: inner}]
+	if len(innerCoverage) != 2 || innerCoverage[0] != 2 || innerCoverage[3] != 2 {
+		t.Error("wrong inner coverage", innerCoverage)
+	}
+
+	var folded strings.Builder
+	test.FailIfError(t, p.WriteFoldedGas(&folded))
+	expected := []string{outer.Hex() + " 140", outer.Hex() + ";" + inner.Hex() + " 60"}
+	for _, line := range expected {
+		if !strings.Contains(folded.String(), line+"\n") {
+			t.Errorf("missing %v in folded output:\n%v", line, folded.String())
+		}
+	}
+
+	var lcov strings.Builder
+	test.FailIfError(t, p.WriteLcov(&lcov))
+	if !strings.Contains(lcov.String(), "SF:"+inner.Hex()+"\nDA:0,2\nDA:2,0\nDA:3,2\nDA:4,0\nLF:4\nLH:2\nend_of_record\n") {
+		t.Error("wrong inner lcov output", lcov.String())
+	}
+	// Outer's recorded code is shorter than what ran, so executed pcs past
+	// it are still reported
+	if !strings.Contains(lcov.String(), "SF:"+outer.Hex()+"\nDA:0,2\nDA:2,0\nDA:10,2\nDA:11,2\nDA:12,2\nLF:5\nLH:4\nend_of_record\n") {
+		t.Error("wrong outer lcov output", lcov.String())
+	}
+}
+
+func TestInstructionPCs(t *testing.T) {
+	code := []byte{0x60, 0x01, 0x7f}
+	code = append(code, make([]byte, 32)...)
+	code = append(code, 0x56, 0x61, 0x01)
+	pcs := instructionPCs(code)
+	expected := []uint64{0, 2, 35, 36}
+	if len(pcs) != len(expected) {
+		t.Fatal("wrong instructions", pcs)
+	}
+	for i := range pcs {
+		if pcs[i] != expected[i] {
+			t.Error("wrong instructions", pcs)
+		}
+	}
+}
+
+func TestProfileRevert(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	config := protocol.ChainParams{
+		GracePeriod:               common.NewTimeBlocksInt(3),
+		ArbGasSpeedLimitPerSecond: 2000000000000,
+	}
+	backend, _, srv, closeFunc := NewSimpleTestDevNode(t, config, common.RandAddress())
+	defer closeFunc()
+	profiler, err := NewProfiler(ctx, backend)
+	test.FailIfError(t, err)
+
+	client := web3.NewEthClient(srv, true)
+	deploy := func() string {
+		t.Helper()
+		auth, _ := OptsAddressPair(t, nil)
+		address, _, _, err := arbostestcontracts.DeploySimple(auth, client)
+		test.FailIfError(t, err)
+		return ProfileCode{Address: common.NewAddressFromEth(address), Constructor: true}.String()
+	}
+	profiled := func(code string) bool {
+		t.Helper()
+		var lcov strings.Builder
+		test.FailIfError(t, profiler.WriteLcov(&lcov))
+		return strings.Contains(lcov.String(), "SF:"+code+"\n")
+	}
+
+	devEVM := NewEVM(backend)
+	snap, err := devEVM.Snapshot()
+	test.FailIfError(t, err)
+	reverted := deploy()
+	test.FailIfError(t, devEVM.Revert(ctx, snap))
+	if !profiled(reverted) {
+		t.Error("reverted deployment wasn't profiled")
+	}
+
+	// Blocks after the revert are profiled without an update
+	kept := deploy()
+	for i := 0; !profiled(kept); i++ {
+		if i == 500 {
+			t.Fatal("deployment after revert wasn't profiled")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
@@ -0,0 +1,130 @@
+/*
+* Copyright 2022, Offchain Labs, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package dev
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/message"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/monitor"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/txdb"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/protocol"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+)
+
+func TestSecondaryReorg(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dir := t.TempDir()
+	backend, primary, _, closePrimary, _, err := NewDevNode(ctx, dir, *arbosfile, big.NewInt(42161), common.RandAddress(), 0, false)
+	test.FailIfError(t, err)
+	defer closePrimary()
+	params := protocol.ChainParams{
+		GracePeriod:               common.NewTimeBlocksInt(3),
+		ArbGasSpeedLimitPerSecond: 2000000000000,
+	}
+	initMsg, err := message.NewInitMessage(params, common.RandAddress(), nil)
+	test.FailIfError(t, err)
+	_, err = backend.AddInboxMessage(ctx, initMsg, common.Address{})
+	test.FailIfError(t, err)
+
+	dest := common.RandAddress()
+	deposit := func(amount int64) {
+		t.Helper()
+		deposit := message.EthDepositTx{
+			L2Message: message.NewSafeL2Message(message.ContractTransaction{
+				BasicTx: message.BasicTx{
+					MaxGas:      big.NewInt(1000000),
+					GasPriceBid: big.NewInt(0),
+					DestAddress: dest,
+					Payment:     big.NewInt(amount),
+				},
+			}),
+		}
+		_, err := backend.AddInboxMessage(ctx, deposit, common.RandAddress())
+		test.FailIfError(t, err)
+	}
+
+	evm := NewEVM(backend)
+	beforeStart, err := evm.Snapshot()
+	test.FailIfError(t, err)
+	deposit(100)
+
+	coreConfig := configuration.DefaultCoreSettingsMaxExecution()
+	coreConfig.Database.Secondary = configuration.DatabaseSecondary{Enable: true, Path: t.TempDir()}
+	mon, err := monitor.NewMonitor(dir, coreConfig)
+	test.FailIfError(t, err)
+	defer mon.Close()
+	nodeConfig := configuration.DefaultNodeSettings()
+	nodeConfig.Cache.BlockInfoLRUSize = 100
+	secondary, errChan, err := txdb.NewSecondary(ctx, mon.Core, mon.Storage, nodeConfig, 10*time.Millisecond)
+	test.FailIfError(t, err)
+	defer secondary.Close()
+	go func() {
+		if err := <-errChan; err != nil {
+			t.Error(err)
+		}
+	}()
+
+	// checkBalance waits for the secondary to show the latest block of the
+	// primary, then checks the balance of dest in it. Queries cache blocks
+	// and machines, so stale caches after a reorg keep the old block.
+	checkBalance := func(expected int64) {
+		t.Helper()
+		latest, err := primary.LatestBlock()
+		test.FailIfError(t, err)
+		height := latest.Header.Number.Uint64()
+		for i := 0; ; i++ {
+			block, err := secondary.GetBlock(height)
+			test.FailIfError(t, err)
+			if block != nil && block.Header.Hash() == latest.Header.Hash() {
+				break
+			}
+			if i == 500 {
+				t.Fatal("secondary didn't catch up to block", height)
+			}
+			time.Sleep(time.Millisecond * 10)
+		}
+		snap, err := secondary.GetSnapshot(ctx, height)
+		test.FailIfError(t, err)
+		balance, err := snap.GetBalance(ctx, dest)
+		test.FailIfError(t, err)
+		if balance.Cmp(big.NewInt(expected)) != 0 {
+			t.Error("secondary has balance", balance, "at block", height, "but expected", expected)
+		}
+	}
+	checkBalance(100)
+
+	// Reorg of a block from before the secondary started
+	test.FailIfError(t, evm.Revert(ctx, beforeStart))
+	deposit(200)
+	checkBalance(200)
+
+	// Reorg of a block the secondary published
+	published, err := evm.Snapshot()
+	test.FailIfError(t, err)
+	deposit(300)
+	checkBalance(500)
+	test.FailIfError(t, evm.Revert(ctx, published))
+	deposit(400)
+	checkBalance(600)
+}
@@ -0,0 +1,137 @@
+/*
+* Copyright 2021, Offchain Labs, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package dev
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+)
+
+// exportedL1 is the emulated L1 state saved alongside the exported messages
+type exportedL1 struct {
+	TimeIncrease int64 `json:"time_increase"`
+}
+
+// exportChain writes messages as a test vector with an extra field holding
+// the emulated L1 state, which other test vector readers ignore
+func exportChain(messages []inbox.InboxMessage, timeIncrease int64) ([]byte, error) {
+	data, err := inbox.TestVectorJSON(messages, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	l1, err := json.Marshal(exportedL1{TimeIncrease: timeIncrease})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	fields["l1"] = l1
+	data, err = json.Marshal(fields)
+	return data, errors.WithStack(err)
+}
+
+// ImportData replaces the chain with the messages in data, as produced by
+// ExportData. Replaying the same messages always produces the same state.
+// Imported messages are all sequenced directly, so chains running on a
+// simulated L1 can't be replaced as they'd no longer match its delayed inbox.
+func (b *Backend) ImportData(ctx context.Context, data []byte) error {
+	if b.l1Emulator.followsChain() {
+		return errors.New("can't load state while running on a simulated L1")
+	}
+	messages, _, _, err := inbox.LoadTestVector(data)
+	if err != nil {
+		return errors.Wrap(err, "error loading exported messages")
+	}
+	if len(messages) == 0 {
+		return errors.New("no messages to import")
+	}
+	// Chains exported before the L1 state was saved don't have it
+	var exported struct {
+		L1 exportedL1 `json:"l1"`
+	}
+	if err := json.Unmarshal(data, &exported); err != nil {
+		return errors.Wrap(err, "error loading exported L1 state")
+	}
+
+	b.Lock()
+	defer b.Unlock()
+	if err := core.ReorgAndWait(ctx, b.arbcore, big.NewInt(0)); err != nil {
+		return err
+	}
+	b.delayedCount = big.NewInt(0)
+	b.pendingTxs = nil
+
+	items := make([]inbox.SequencerBatchItem, 0, len(messages))
+	var prevAcc common.Hash
+	var height uint64
+	var timestamp int64
+	for i, msg := range messages {
+		if msg.InboxSeqNum.Cmp(big.NewInt(int64(i))) != 0 {
+			return errors.Errorf("expected message %v but got %v", i, msg.InboxSeqNum)
+		}
+		item := inbox.NewSequencerItem(b.delayedCount, msg, prevAcc)
+		items = append(items, item)
+		prevAcc = item.Accumulator
+		if blockNum := msg.ChainTime.BlockNum.AsInt().Uint64(); blockNum > height {
+			height = blockNum
+		}
+		if msgTime := msg.ChainTime.Timestamp.Int64(); msgTime > timestamp {
+			timestamp = msgTime
+		}
+	}
+	if err := core.DeliverMessagesAndWait(ctx, b.arbcore, big.NewInt(0), common.Hash{}, items, nil, nil); err != nil {
+		return err
+	}
+	if err := b.waitForExecution(); err != nil {
+		return err
+	}
+	b.l1Emulator.Reorg(height)
+	// New blocks mustn't go back in time from the imported ones
+	b.l1Emulator.restoreTime(exported.L1.TimeIncrease, timestamp)
+	logger.Info().Int("messages", len(messages)).Msg("imported chain")
+	return nil
+}
+
+// StateAPI dumps and loads portable chain fixtures over RPC
+type StateAPI struct {
+	backend *Backend
+}
+
+func NewStateAPI(backend *Backend) *StateAPI {
+	return &StateAPI{backend: backend}
+}
+
+func (s *StateAPI) DumpState() (json.RawMessage, error) {
+	data, err := s.backend.ExportData()
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (s *StateAPI) LoadState(ctx context.Context, state json.RawMessage) error {
+	return s.backend.ImportData(ctx, state)
+}
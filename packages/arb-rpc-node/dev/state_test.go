@@ -0,0 +1,112 @@
+/*
+* Copyright 2021, Offchain Labs, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package dev
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/protocol"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+)
+
+func TestDumpAndLoadState(t *testing.T) {
+	ctx := context.Background()
+	config := protocol.ChainParams{
+		GracePeriod:               common.NewTimeBlocksInt(3),
+		ArbGasSpeedLimitPerSecond: 2000000000000,
+	}
+	owner := common.RandAddress()
+	backend, db, _, closeFunc := NewSimpleTestDevNode(t, config, owner)
+	defer closeFunc()
+
+	account := common.RandAddress()
+	test.FailIfError(t, NewCheats(backend).SetBalance(ctx, account.ToEthAddress(), (*hexutil.Big)(big.NewInt(777))))
+	state, err := NewStateAPI(backend).DumpState()
+	test.FailIfError(t, err)
+	blockCount, err := db.BlockCount()
+	test.FailIfError(t, err)
+
+	loadedBackend, loadedDb, _, closeLoaded := NewSimpleTestDevNode(t, config, common.RandAddress())
+	defer closeLoaded()
+	test.FailIfError(t, NewStateAPI(loadedBackend).LoadState(ctx, state))
+
+	loadedBlockCount, err := loadedDb.BlockCount()
+	test.FailIfError(t, err)
+	if loadedBlockCount != blockCount {
+		t.Error("loaded", loadedBlockCount, "blocks but expected", blockCount)
+	}
+	snap, err := loadedDb.LatestSnapshot(ctx)
+	test.FailIfError(t, err)
+	balance, err := snap.GetBalance(ctx, account)
+	test.FailIfError(t, err)
+	if balance.Cmp(big.NewInt(777)) != 0 {
+		t.Error("wrong balance after load", balance)
+	}
+
+	reloaded, err := NewStateAPI(loadedBackend).DumpState()
+	test.FailIfError(t, err)
+	if string(reloaded) != string(state) {
+		t.Error("loaded chain doesn't export the same messages")
+	}
+}
+
+func TestLoadStateTime(t *testing.T) {
+	ctx := context.Background()
+	config := protocol.ChainParams{
+		GracePeriod:               common.NewTimeBlocksInt(3),
+		ArbGasSpeedLimitPerSecond: 2000000000000,
+	}
+	backend, _, _, closeFunc := NewSimpleTestDevNode(t, config, common.RandAddress())
+	defer closeFunc()
+
+	const increase = 100000
+	backend.l1Emulator.IncreaseTime(increase)
+	test.FailIfError(t, NewCheats(backend).SetBalance(ctx, common.RandAddress().ToEthAddress(), (*hexutil.Big)(big.NewInt(1))))
+	state, err := NewStateAPI(backend).DumpState()
+	test.FailIfError(t, err)
+
+	loadedBackend, _, _, closeLoaded := NewSimpleTestDevNode(t, config, common.RandAddress())
+	defer closeLoaded()
+	test.FailIfError(t, NewStateAPI(loadedBackend).LoadState(ctx, state))
+	if loadedBackend.l1Emulator.TimeIncrease() != increase {
+		t.Error("loaded time increase", loadedBackend.l1Emulator.TimeIncrease())
+	}
+
+	// Exports without the L1 state still continue from the last message
+	messages, _, _, err := inbox.LoadTestVector(state)
+	test.FailIfError(t, err)
+	legacyState, err := inbox.TestVectorJSON(messages, nil, nil)
+	test.FailIfError(t, err)
+	legacyBackend, _, _, closeLegacy := NewSimpleTestDevNode(t, config, common.RandAddress())
+	defer closeLegacy()
+	test.FailIfError(t, NewStateAPI(legacyBackend).LoadState(ctx, legacyState))
+	var lastTimestamp int64
+	for _, msg := range messages {
+		if msg.ChainTime.Timestamp.Int64() > lastTimestamp {
+			lastTimestamp = msg.ChainTime.Timestamp.Int64()
+		}
+	}
+	if block := legacyBackend.l1Emulator.GenerateBlock(); block.timestamp.Int64() < lastTimestamp {
+		t.Error("new block at", block.timestamp, "before last loaded message at", lastTimestamp)
+	}
+}
@@ -0,0 +1,279 @@
+/*
+* Copyright 2022, Offchain Labs, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package dev
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-avm-cpp/cmachine"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/arbos"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/monitor"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/utils"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+)
+
+const upgradeChunkSize = 50000
+
+// ArbOSUpgrade is an ArbOS code upload in the format used by arb-cli
+type ArbOSUpgrade struct {
+	Instructions []string `json:"instructions"`
+	// Code point hash of the upgraded ArbOS mexe
+	TargetHash common.Hash `json:"-"`
+}
+
+func (u ArbOSUpgrade) chunks() ([][]byte, error) {
+	hexChunks := []string{"0x"}
+	for _, insn := range u.Instructions {
+		if len(hexChunks[len(hexChunks)-1])+len(insn) > upgradeChunkSize {
+			hexChunks = append(hexChunks, "0x")
+		}
+		hexChunks[len(hexChunks)-1] += insn
+	}
+	chunks := make([][]byte, 0, len(hexChunks))
+	for _, hexChunk := range hexChunks {
+		chunk, err := hexutil.Decode(hexChunk)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid upgrade instructions")
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks, nil
+}
+
+// LoadArbOSUpgrade reads an upgrade in the format used by arb-cli which
+// installs the ArbOS build in targetMexe
+func LoadArbOSUpgrade(upgradeFile, targetMexe string) (ArbOSUpgrade, error) {
+	targetMach, err := cmachine.New(targetMexe)
+	if err != nil {
+		return ArbOSUpgrade{}, err
+	}
+	upgradeData, err := os.ReadFile(upgradeFile)
+	if err != nil {
+		return ArbOSUpgrade{}, errors.WithStack(err)
+	}
+	upgrade := ArbOSUpgrade{TargetHash: targetMach.CodePointHash()}
+	if err := json.Unmarshal(upgradeData, &upgrade); err != nil {
+		return ArbOSUpgrade{}, errors.Wrap(err, "error parsing upgrade")
+	}
+	return upgrade, nil
+}
+
+// UpgradeArbOS uploads and installs an ArbOS upgrade as the chain owner
+func (b *Backend) UpgradeArbOS(ctx context.Context, upgrade ArbOSUpgrade) error {
+	chunks, err := upgrade.chunks()
+	if err != nil {
+		return err
+	}
+	if err := b.applyPrivileged(ctx, arbos.ARB_OWNER_ADDRESS, arbos.StartArbOSUpgradeData()); err != nil {
+		return errors.Wrap(err, "error starting upgrade")
+	}
+	for _, chunk := range chunks {
+		if err := b.applyPrivileged(ctx, arbos.ARB_OWNER_ADDRESS, arbos.ContinueArbOSUpgradeData(chunk)); err != nil {
+			return errors.Wrap(err, "error uploading upgrade")
+		}
+	}
+	if err := b.applyPrivileged(ctx, arbos.ARB_OWNER_ADDRESS, arbos.FinishArbOSUpgradeData(upgrade.TargetHash)); err != nil {
+		return errors.Wrap(err, "error finishing upgrade")
+	}
+	return nil
+}
+
+// ReplayMessages sequences copies of messages from another chain, each in
+// its own block, and returns the transaction results they produce
+func (b *Backend) ReplayMessages(ctx context.Context, messages []inbox.InboxMessage) ([][]*evm.TxResult, error) {
+	b.Lock()
+	defer b.Unlock()
+	results := make([][]*evm.TxResult, 0, len(messages))
+	for _, msg := range messages {
+		msgCount, err := b.arbcore.GetMessageCount()
+		if err != nil {
+			return nil, err
+		}
+		logCount, err := b.arbcore.GetLogCount()
+		if err != nil {
+			return nil, err
+		}
+		msg.InboxSeqNum = msgCount
+		if err := b.deliverInboxMessage(ctx, msg); err != nil {
+			return nil, err
+		}
+		newLogCount, err := b.arbcore.GetLogCount()
+		if err != nil {
+			return nil, err
+		}
+		logs, err := b.arbcore.GetLogs(logCount, new(big.Int).Sub(newLogCount, logCount))
+		if err != nil {
+			return nil, err
+		}
+		var txResults []*evm.TxResult
+		for _, log := range logs {
+			res, err := evm.NewResultFromValue(log.Value)
+			if err != nil {
+				return nil, err
+			}
+			if txRes, ok := res.(*evm.TxResult); ok {
+				txResults = append(txResults, txRes)
+			}
+		}
+		results = append(results, txResults)
+	}
+	return results, nil
+}
+
+// ReplayDifference lists how the results of one replayed message changed
+type ReplayDifference struct {
+	Message     uint64   `json:"message"`
+	Differences []string `json:"differences"`
+}
+
+type UpgradeRehearsalReport struct {
+	ForkMessage uint64             `json:"forkMessage"`
+	Replayed    int                `json:"replayed"`
+	Differences []ReplayDifference `json:"differences"`
+}
+
+// RehearseArbOSUpgrade forks the chain in source twice, dropping its last
+// replay messages. The upgrade is applied to one fork, then the dropped
+// messages are replayed on both and their results compared. Both forks are
+// created under workDir, which must be on the filesystem of source, and share
+// data with it copy-on-write.
+func RehearseArbOSUpgrade(ctx context.Context, source, workDir string, chainId *big.Int, upgrade ArbOSUpgrade, replay uint64) (*UpgradeRehearsalReport, error) {
+	return rehearseArbOSUpgrade(ctx, source, workDir, chainId, upgrade, func(msgCount uint64) (uint64, uint64, error) {
+		if replay == 0 || replay >= msgCount {
+			return 0, 0, errors.Errorf("can't replay %v of %v messages", replay, msgCount)
+		}
+		return msgCount - replay, msgCount, nil
+	})
+}
+
+// RehearseArbOSUpgradeRange is like RehearseArbOSUpgrade, but forks the chain
+// before message start and replays the messages up to end
+func RehearseArbOSUpgradeRange(ctx context.Context, source, workDir string, chainId *big.Int, upgrade ArbOSUpgrade, start, end uint64) (*UpgradeRehearsalReport, error) {
+	return rehearseArbOSUpgrade(ctx, source, workDir, chainId, upgrade, func(msgCount uint64) (uint64, uint64, error) {
+		if start == 0 || start >= end || end > msgCount {
+			return 0, 0, errors.Errorf("can't replay messages %v to %v of %v", start, end, msgCount)
+		}
+		return start, end, nil
+	})
+}
+
+// rehearseArbOSUpgrade rehearses the upgrade on the messages selected by
+// replayRange given the message count of source
+func rehearseArbOSUpgrade(ctx context.Context, source, workDir string, chainId *big.Int, upgrade ArbOSUpgrade, replayRange func(uint64) (uint64, uint64, error)) (*UpgradeRehearsalReport, error) {
+	beforeDir := filepath.Join(workDir, "before")
+	afterDir := filepath.Join(workDir, "after")
+	if err := utils.CopyDatabase(source, beforeDir, false); err != nil {
+		return nil, err
+	}
+	if err := utils.CopyDatabase(source, afterDir, false); err != nil {
+		return nil, err
+	}
+
+	forkMessage, messages, err := readReplayMessages(beforeDir, replayRange)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info().Uint64("forkMessage", forkMessage).Int("replay", len(messages)).Msg("rehearsing ArbOS upgrade")
+
+	before, err := replayOnFork(ctx, beforeDir, chainId, forkMessage, messages, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "error replaying without upgrade")
+	}
+	after, err := replayOnFork(ctx, afterDir, chainId, forkMessage, messages, &upgrade)
+	if err != nil {
+		return nil, errors.Wrap(err, "error replaying with upgrade")
+	}
+
+	report := &UpgradeRehearsalReport{
+		ForkMessage: forkMessage,
+		Replayed:    len(messages),
+	}
+	for i := range messages {
+		differences := compareReplayedResults(before[i], after[i])
+		if len(differences) > 0 {
+			report.Differences = append(report.Differences, ReplayDifference{
+				Message:     forkMessage + uint64(i),
+				Differences: differences,
+			})
+		}
+	}
+	return report, nil
+}
+
+func readReplayMessages(dir string, replayRange func(uint64) (uint64, uint64, error)) (uint64, []inbox.InboxMessage, error) {
+	mon, err := monitor.NewMonitor(dir, configuration.DefaultCoreSettingsMaxExecution())
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "error opening monitor")
+	}
+	defer mon.Close()
+	msgCount, err := mon.Core.GetMessageCount()
+	if err != nil {
+		return 0, nil, err
+	}
+	start, end, err := replayRange(msgCount.Uint64())
+	if err != nil {
+		return 0, nil, err
+	}
+	messages, err := mon.Core.GetMessages(new(big.Int).SetUint64(start), new(big.Int).SetUint64(end-start))
+	if err != nil {
+		return 0, nil, err
+	}
+	return start, messages, nil
+}
+
+func replayOnFork(ctx context.Context, dir string, chainId *big.Int, forkMessage uint64, messages []inbox.InboxMessage, upgrade *ArbOSUpgrade) ([][]*evm.TxResult, error) {
+	backend, _, _, cancel, _, err := NewForkNode(ctx, dir, chainId, common.Address{}, int64(forkMessage), false)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	if upgrade != nil {
+		if err := backend.UpgradeArbOS(ctx, *upgrade); err != nil {
+			return nil, err
+		}
+	}
+	return backend.ReplayMessages(ctx, messages)
+}
+
+// compareReplayedResults compares the results of the same message replayed
+// on two forks. The upgrade adds messages and blocks to one fork, so request
+// fields derived from the message's position are aligned first.
+func compareReplayedResults(before, after []*evm.TxResult) []string {
+	if len(before) != len(after) {
+		return []string{fmt.Sprintf("different transaction counts %v and %v", len(before), len(after))}
+	}
+	var differences []string
+	for i, res := range after {
+		aligned := *res
+		aligned.IncomingRequest.MessageID = before[i].IncomingRequest.MessageID
+		aligned.IncomingRequest.L2BlockNumber = before[i].IncomingRequest.L2BlockNumber
+		aligned.IncomingRequest.Provenance = before[i].IncomingRequest.Provenance
+		differences = append(differences, evm.CompareResults(before[i], &aligned)...)
+	}
+	return differences
+}
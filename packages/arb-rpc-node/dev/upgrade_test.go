@@ -18,15 +18,22 @@ package dev
 
 import (
 	"context"
+	"encoding/json"
+	"io/ioutil"
 	"math/big"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/crypto"
 
+	"github.com/offchainlabs/arbitrum/packages/arb-avm-cpp/cmachine"
 	"github.com/offchainlabs/arbitrum/packages/arb-evm/arbos"
 	"github.com/offchainlabs/arbitrum/packages/arb-evm/arboscontracts"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
 	"github.com/offchainlabs/arbitrum/packages/arb-evm/message"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/aggregator"
 	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/arbostestcontracts"
 	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/web3"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
@@ -114,3 +121,141 @@ func TestUpgrade(t *testing.T) {
 		t.Error("didn't change to new version")
 	}
 }
+
+func TestArbOSUpgradeChunks(t *testing.T) {
+	insn := strings.Repeat("ab", 10000)
+	upgrade := ArbOSUpgrade{Instructions: []string{insn, insn, insn}}
+	chunks, err := upgrade.chunks()
+	test.FailIfError(t, err)
+	if len(chunks) != 2 || len(chunks[0]) != 20000 || len(chunks[1]) != 10000 {
+		t.Error("wrong chunks", len(chunks))
+	}
+}
+
+func TestCompareReplayedResults(t *testing.T) {
+	newResult := func(seqNum int64, gasUsed int64) *evm.TxResult {
+		res := &evm.TxResult{
+			IncomingRequest: evm.IncomingRequest{
+				L1BlockNumber: big.NewInt(5),
+				L2BlockNumber: big.NewInt(seqNum),
+				L2Timestamp:   big.NewInt(100),
+				Provenance: evm.Provenance{
+					L1SeqNum:      big.NewInt(seqNum),
+					IndexInParent: big.NewInt(0),
+				},
+			},
+			GasUsed:       big.NewInt(gasUsed),
+			GasPrice:      big.NewInt(1),
+			CumulativeGas: big.NewInt(gasUsed),
+			TxIndex:       big.NewInt(0),
+			StartLogIndex: big.NewInt(0),
+		}
+		res.IncomingRequest.MessageID[0] = byte(seqNum)
+		return res
+	}
+	before := []*evm.TxResult{newResult(10, 500)}
+	if differences := compareReplayedResults(before, []*evm.TxResult{newResult(14, 500)}); len(differences) != 0 {
+		t.Error("unexpected differences", differences)
+	}
+	if differences := compareReplayedResults(before, []*evm.TxResult{newResult(14, 600)}); len(differences) != 2 {
+		t.Error("expected gas differences", differences)
+	}
+	if differences := compareReplayedResults(before, nil); len(differences) != 1 {
+		t.Error("expected count difference", differences)
+	}
+}
+
+func TestRehearseArbOSUpgrade(t *testing.T) {
+	skipBelowVersion(t, 4)
+
+	ctx := context.Background()
+	arbosFile, _ := arbos.Path(true)
+	chainId := big.NewInt(42161)
+	source := filepath.Join(t.TempDir(), "source")
+
+	backend, db, _, cancel, _, err := NewDevNode(ctx, source, arbosFile, chainId, common.RandAddress(), 0, false)
+	test.FailIfError(t, err)
+	auth, owner := OptsAddressPair(t, nil)
+	params := protocol.ChainParams{
+		GracePeriod:               common.NewTimeBlocksInt(3),
+		ArbGasSpeedLimitPerSecond: 2000000000000,
+	}
+	initMsg, err := message.NewInitMessage(params, owner, nil)
+	test.FailIfError(t, err)
+	_, err = backend.AddInboxMessage(ctx, initMsg, common.Address{})
+	test.FailIfError(t, err)
+
+	deposit := message.EthDepositTx{
+		L2Message: message.NewSafeL2Message(message.ContractTransaction{
+			BasicTx: message.BasicTx{
+				MaxGas:      big.NewInt(1000000),
+				GasPriceBid: big.NewInt(0),
+				DestAddress: owner,
+				Payment:     big.NewInt(100),
+				Data:        nil,
+			},
+		}),
+	}
+	_, err = backend.AddInboxMessage(ctx, deposit, common.RandAddress())
+	test.FailIfError(t, err)
+
+	client := web3.NewEthClient(aggregator.NewServer(backend, chainId, db), true)
+	_, _, simpleCon, err := arbostestcontracts.DeploySimple(auth, client)
+	test.FailIfError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err = simpleCon.Exists(auth)
+		test.FailIfError(t, err)
+	}
+	test.FailIfError(t, backend.waitForExecution())
+	msgCount, err := backend.arbcore.GetMessageCount()
+	test.FailIfError(t, err)
+	cancel()
+
+	arbosDir, err := arbos.Dir()
+	test.FailIfError(t, err)
+	upgradedMach, err := cmachine.New(filepath.Join(arbosDir, "arbos-upgrade.mexe"))
+	test.FailIfError(t, err)
+	upgradeBytes, err := ioutil.ReadFile(filepath.Join(arbosDir, "upgrade.json"))
+	test.FailIfError(t, err)
+	upgrade := ArbOSUpgrade{TargetHash: upgradedMach.CodePointHash()}
+	test.FailIfError(t, json.Unmarshal(upgradeBytes, &upgrade))
+
+	if _, err := RehearseArbOSUpgrade(ctx, source, filepath.Join(t.TempDir(), "all"), chainId, upgrade, msgCount.Uint64()); err == nil {
+		t.Error("rehearsal replaying every message should fail")
+	}
+
+	workDir, err := ForkWorkDir(source, "rehearsal")
+	test.FailIfError(t, err)
+	report, err := RehearseArbOSUpgrade(ctx, source, workDir, chainId, upgrade, 3)
+	test.FailIfError(t, err)
+	if report.Replayed != 3 || report.ForkMessage != msgCount.Uint64()-3 {
+		t.Error("replayed", report.Replayed, "messages from", report.ForkMessage, "of", msgCount)
+	}
+	for _, difference := range report.Differences {
+		t.Log("message", difference.Message, "changed:", difference.Differences)
+		if difference.Message < report.ForkMessage || difference.Message >= msgCount.Uint64() {
+			t.Error("difference reported for message", difference.Message, "which wasn't replayed")
+		}
+	}
+
+	workDir, err = ForkWorkDir(source, "range")
+	test.FailIfError(t, err)
+	report, err = RehearseArbOSUpgradeRange(ctx, source, workDir, chainId, upgrade, msgCount.Uint64()-4, msgCount.Uint64()-2)
+	test.FailIfError(t, err)
+	if report.Replayed != 2 || report.ForkMessage != msgCount.Uint64()-4 {
+		t.Error("replayed", report.Replayed, "messages from", report.ForkMessage, "of", msgCount)
+	}
+	if _, err := RehearseArbOSUpgradeRange(ctx, source, filepath.Join(t.TempDir(), "past"), chainId, upgrade, msgCount.Uint64()-1, msgCount.Uint64()+1); err == nil {
+		t.Error("rehearsal replaying past the last message should fail")
+	}
+
+	// Rehearsing leaves the source chain untouched
+	backend, _, _, cancel, _, err = NewDevNode(ctx, source, arbosFile, chainId, common.RandAddress(), 0, false)
+	test.FailIfError(t, err)
+	defer cancel()
+	newMsgCount, err := backend.arbcore.GetMessageCount()
+	test.FailIfError(t, err)
+	if newMsgCount.Cmp(msgCount) != 0 {
+		t.Error("source chain has", newMsgCount, "messages instead of", msgCount)
+	}
+}
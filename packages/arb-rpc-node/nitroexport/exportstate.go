@@ -17,17 +17,104 @@
 package nitroexport
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/pkg/errors"
 
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/txdb"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/arblog"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
 )
 
 var logger = arblog.Logger.With().Str("component", "nitroexport").Logger()
 
-func ExportState(arbcore core.ArbCore, height uint64, dirname string) error {
+const (
+	ManifestFilename = "manifest.json"
+	IndexFilename    = "index.json"
+)
+
+// ExportedFile records the contents of a finished part of a state export
+type ExportedFile struct {
+	Hash    common.Hash `json:"hash"`
+	Size    int64       `json:"size"`
+	Entries uint64      `json:"entries"`
+}
+
+// StateManifest tracks the progress of a state export. A file is only listed
+// once it has been completely written, and the export is finished once the
+// index is listed.
+type StateManifest struct {
+	Block        uint64                  `json:"block"`
+	MachineHash  common.Hash             `json:"machineHash"`
+	TotalBalance *hexutil.Big            `json:"totalBalance"`
+	Files        map[string]ExportedFile `json:"files"`
+}
+
+func (m *StateManifest) Complete() bool {
+	_, ok := m.Files[IndexFilename]
+	return ok
+}
+
+func ReadStateManifest(dirname string) (*StateManifest, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dirname, ManifestFilename))
+	if err != nil {
+		return nil, err
+	}
+	manifest := &StateManifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, errors.Wrap(err, "invalid export manifest")
+	}
+	if manifest.Files == nil {
+		manifest.Files = make(map[string]ExportedFile)
+	}
+	return manifest, nil
+}
+
+func writeStateManifest(dirname string, manifest *StateManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return writeFileAtomic(filepath.Join(dirname, ManifestFilename), data)
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmpPath := path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.Rename(tmpPath, path))
+}
+
+// ExportState dumps the ArbOS state at the end of the given block into
+// dirname. If dirname holds a partial export of the same block, the sections
+// that were already written are kept and the export picks up where it
+// stopped.
+func ExportState(ctx context.Context, txDB *txdb.TxDB, arbcore core.ArbCore, height uint64, dirname string) error {
+	manifest, err := ReadStateManifest(dirname)
+	if os.IsNotExist(err) {
+		manifest, err = startStateExport(ctx, txDB, height, dirname)
+	}
+	if err != nil {
+		return err
+	}
+	if manifest.Block != height {
+		return errors.Errorf("%v holds an export of block %v", dirname, manifest.Block)
+	}
+	if manifest.Complete() {
+		logger.Info().Uint64("block", height).Msg("state export already complete")
+		return nil
+	}
+
 	cursor, err := arbcore.GetExecutionCursorAtEndOfBlock(height, true)
 	if err != nil {
 		return err
@@ -37,13 +124,103 @@ func ExportState(arbcore core.ArbCore, height uint64, dirname string) error {
 	if err != nil {
 		return err
 	}
-	_, statsErr := os.Stat(dirname)
-	if !os.IsNotExist(statsErr) {
-		return errors.Errorf("%v already exists", dirname)
+	machineHash := machine.Hash().ToEthHash()
+	if manifest.MachineHash == (common.Hash{}) {
+		manifest.MachineHash = machineHash
+	} else if manifest.MachineHash != machineHash {
+		return errors.Errorf("machine at block %v is %v but the export was started from %v", height, machineHash, manifest.MachineHash)
+	}
+
+	for _, section := range core.ArbosStateSections {
+		filename := section.Filename()
+		if _, ok := manifest.Files[filename]; ok {
+			logger.Info().Str("section", section.String()).Msg("section already exported")
+			continue
+		}
+		logger.Info().Str("section", section.String()).Msg("exporting section")
+		path := filepath.Join(dirname, filename)
+		if err := arbcore.DumpArbosStateSection(machine, section, path+".tmp"); err != nil {
+			return err
+		}
+		if err := finishFile(dirname, filename, manifest); err != nil {
+			return err
+		}
+	}
+
+	indexData, err := json.Marshal(core.NewArbosStateIndex(height))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dirname, IndexFilename+".tmp"), indexData, 0644); err != nil {
+		return errors.WithStack(err)
+	}
+	return finishFile(dirname, IndexFilename, manifest)
+}
+
+func startStateExport(ctx context.Context, txDB *txdb.TxDB, height uint64, dirname string) (*StateManifest, error) {
+	if entries, err := ioutil.ReadDir(dirname); err == nil && len(entries) > 0 {
+		return nil, errors.Errorf("%v already exists and isn't a state export", dirname)
+	}
+	snap, err := txDB.GetSnapshot(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+	if snap == nil {
+		return nil, errors.Errorf("block %v not found", height)
+	}
+	totalBalance, err := snap.GetTotalOfEthBalances(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting total of eth balances")
+	}
+	if err := os.MkdirAll(dirname, os.ModePerm); err != nil {
+		return nil, errors.WithStack(err)
 	}
-	err = os.MkdirAll(dirname, os.ModePerm)
+	manifest := &StateManifest{
+		Block:        height,
+		TotalBalance: (*hexutil.Big)(totalBalance),
+		Files:        make(map[string]ExportedFile),
+	}
+	return manifest, writeStateManifest(dirname, manifest)
+}
+
+// finishFile moves a fully written file into place and records it in the
+// manifest
+func finishFile(dirname, filename string, manifest *StateManifest) error {
+	path := filepath.Join(dirname, filename)
+	file, err := describeFile(path + ".tmp")
 	if err != nil {
 		return err
 	}
-	return arbcore.DumpArbosState(machine, height, dirname)
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return errors.WithStack(err)
+	}
+	manifest.Files[filename] = *file
+	return writeStateManifest(dirname, manifest)
+}
+
+func describeFile(path string) (*ExportedFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer f.Close()
+	hasher := crypto.NewKeccakState()
+	file := &ExportedFile{}
+	buf := make([]byte, 1<<20)
+	for {
+		n, err := f.Read(buf)
+		if n > 0 {
+			_, _ = hasher.Write(buf[:n])
+			file.Size += int64(n)
+			file.Entries += uint64(bytes.Count(buf[:n], []byte{'\n'}))
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	copy(file.Hash[:], hasher.Sum(nil))
+	return file, nil
 }
@@ -0,0 +1,138 @@
+/*
+* Copyright 2022, Offchain Labs, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package nitroexport
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"math/big"
+	"path/filepath"
+	"sort"
+
+	gethcore "github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+)
+
+// GenesisChainConfig returns a chain config with every fork enabled from the
+// start for a new local chain seeded with an exported genesis. Its blocks are
+// mined with ethash at the minimum difficulty, which has nothing to do with
+// how the classic chain was produced.
+func GenesisChainConfig(chainId *big.Int) *params.ChainConfig {
+	config := *params.AllEthashProtocolChanges
+	config.ChainID = new(big.Int).Set(chainId)
+	return &config
+}
+
+// WriteGenesis converts a finished state export into a geth genesis.json
+// with every account, including contract code and storage, in its alloc.
+// The genesis block starts a new chain from the exported state, taking its
+// timestamp and gas limit from header if one is given. It doesn't continue
+// the classic chain, as its hash differs from every classic block. Accounts
+// are streamed so the whole state never has to fit in memory. It returns the
+// number of accounts written.
+func WriteGenesis(dirname string, config *params.ChainConfig, header *types.Header, w io.Writer) (uint64, error) {
+	manifest, err := ReadStateManifest(dirname)
+	if err != nil {
+		return 0, err
+	}
+	if !manifest.Complete() {
+		return 0, errors.Errorf("export of block %v is incomplete", manifest.Block)
+	}
+
+	genesis := &gethcore.Genesis{
+		Config:     config,
+		GasLimit:   params.GenesisGasLimit,
+		Difficulty: big.NewInt(1),
+		Alloc:      gethcore.GenesisAlloc{},
+	}
+	if header != nil {
+		genesis.Timestamp = header.Time
+		genesis.GasLimit = header.GasLimit
+		genesis.BaseFee = header.BaseFee
+	}
+	genesisData, err := json.Marshal(genesis)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(genesisData, &fields); err != nil {
+		return 0, errors.WithStack(err)
+	}
+	delete(fields, "alloc")
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	out := bufio.NewWriter(w)
+	_, _ = out.WriteString("{")
+	for _, key := range keys {
+		keyData, _ := json.Marshal(key)
+		_, _ = out.Write(keyData)
+		_, _ = out.WriteString(":")
+		_, _ = out.Write(fields[key])
+		_, _ = out.WriteString(",")
+	}
+	_, _ = out.WriteString("\"alloc\":{")
+
+	var count uint64
+	err = forEachEntry(filepath.Join(dirname, core.ArbosStateAccounts.Filename()), func(data []byte) error {
+		var account exportedAccount
+		if err := json.Unmarshal(data, &account); err != nil {
+			return errors.Wrapf(err, "invalid account %v", count)
+		}
+		balance, ok := new(big.Int).SetString(account.Balance, 10)
+		if !ok {
+			return errors.Errorf("account %v has invalid balance %v", account.Addr, account.Balance)
+		}
+		alloc := gethcore.GenesisAccount{
+			Balance: balance,
+			Nonce:   account.Nonce,
+		}
+		if account.ContractInfo != nil {
+			alloc.Code = account.ContractInfo.Code
+			alloc.Storage = account.ContractInfo.ContractStorage
+		}
+		addressData, err := json.Marshal(account.Addr)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		allocData, err := json.Marshal(alloc)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if count > 0 {
+			_, _ = out.WriteString(",")
+		}
+		_, _ = out.Write(addressData)
+		_, _ = out.WriteString(":")
+		_, _ = out.Write(allocData)
+		count++
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	_, _ = out.WriteString("}}\n")
+	return count, errors.WithStack(out.Flush())
+}
@@ -0,0 +1,68 @@
+/*
+* Copyright 2022, Offchain Labs, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package nitroexport
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	gethcore "github.com/ethereum/go-ethereum/core"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+)
+
+func TestWriteGenesis(t *testing.T) {
+	dirname := t.TempDir()
+	writeTestExport(t, dirname, 150)
+
+	var buf bytes.Buffer
+	count, err := WriteGenesis(dirname, GenesisChainConfig(big.NewInt(42161)), nil, &buf)
+	test.FailIfError(t, err)
+	if count != 2 {
+		t.Error("expected 2 accounts, got", count)
+	}
+	var genesis gethcore.Genesis
+	test.FailIfError(t, json.Unmarshal(buf.Bytes(), &genesis))
+	if genesis.Config.ChainID.Cmp(big.NewInt(42161)) != 0 {
+		t.Error("wrong chain id", genesis.Config.ChainID)
+	}
+	account, ok := genesis.Alloc[common.HexToAddress("0x2")]
+	if !ok {
+		t.Fatal("account missing from alloc")
+	}
+	if account.Balance.Cmp(big.NewInt(50)) != 0 || account.Nonce != 3 {
+		t.Error("wrong account", account.Balance, account.Nonce)
+	}
+}
+
+func TestDecodeExportedContract(t *testing.T) {
+	data := []byte(`{"Addr":"0x0000000000000000000000000000000000000003","Nonce":1,"Balance":"0","ContractInfo":{"Code":[96,128,255],"ContractStorage":{"0x0000000000000000000000000000000000000000000000000000000000000001":"0x0000000000000000000000000000000000000000000000000000000000000002"}}}`)
+	var account exportedAccount
+	test.FailIfError(t, json.Unmarshal(data, &account))
+	if !bytes.Equal(account.ContractInfo.Code, []byte{96, 128, 255}) {
+		t.Error("wrong code", account.ContractInfo.Code)
+	}
+	if account.ContractInfo.ContractStorage[common.BigToHash(big.NewInt(1))] != common.BigToHash(big.NewInt(2)) {
+		t.Error("wrong storage", account.ContractInfo.ContractStorage)
+	}
+	if err := json.Unmarshal([]byte(`{"Code":[256]}`), &exportedContract{}); err == nil {
+		t.Error("expected invalid byte to fail")
+	}
+}
@@ -0,0 +1,207 @@
+/*
+* Copyright 2022, Offchain Labs, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package nitroexport
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/message"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/snapshot"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/txdb"
+	arbcommon "github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/hashing"
+)
+
+// The migration datasets are written as JSON lines: one JSON object per
+// line, each followed by a newline. Numbers that may not fit in 64 bits and
+// byte strings are hex encoded with a 0x prefix.
+
+// RetryableEntry is a line of the retryables dataset, describing a retryable
+// ticket that can still be redeemed
+type RetryableEntry struct {
+	// TicketId is the id used to redeem the ticket through ArbRetryableTx
+	TicketId common.Hash `json:"ticketId"`
+	// RequestId is the id of the L1 message that created the ticket
+	RequestId   common.Hash    `json:"requestId"`
+	From        common.Address `json:"from"`
+	To          common.Address `json:"to"`
+	Callvalue   *hexutil.Big   `json:"callvalue"`
+	Beneficiary common.Address `json:"beneficiary"`
+	Calldata    hexutil.Bytes  `json:"calldata"`
+	MaxGas      *hexutil.Big   `json:"maxGas"`
+	GasPriceBid *hexutil.Big   `json:"gasPriceBid"`
+	// Timeout is the timestamp after which the ticket can no longer be
+	// redeemed
+	Timeout uint64 `json:"timeout"`
+	// CreatedAt is the L2 block in which the ticket was created
+	CreatedAt uint64 `json:"createdAt"`
+}
+
+// AddressTableEntry is a line of the address table dataset. Entries are
+// written in index order.
+type AddressTableEntry struct {
+	Index   uint64         `json:"index"`
+	Address common.Address `json:"address"`
+}
+
+type jsonLinesWriter struct {
+	buf   *bufio.Writer
+	enc   *json.Encoder
+	count uint64
+}
+
+func newJSONLinesWriter(w io.Writer) *jsonLinesWriter {
+	buf := bufio.NewWriter(w)
+	return &jsonLinesWriter{buf: buf, enc: json.NewEncoder(buf)}
+}
+
+func (w *jsonLinesWriter) Write(entry interface{}) error {
+	if err := w.enc.Encode(entry); err != nil {
+		return errors.WithStack(err)
+	}
+	w.count++
+	return nil
+}
+
+func (w *jsonLinesWriter) Flush() error {
+	return errors.WithStack(w.buf.Flush())
+}
+
+func exportSnapshot(ctx context.Context, txDB *txdb.TxDB, height uint64) (*snapshot.Snapshot, error) {
+	snap, err := txDB.GetSnapshot(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+	if snap == nil {
+		return nil, errors.Errorf("block %v not found", height)
+	}
+	return snap, nil
+}
+
+// RetryableTicketId returns the id of the ticket created by a retryable
+// submission with the given request id
+func RetryableTicketId(requestId arbcommon.Hash) arbcommon.Hash {
+	return hashing.SoliditySHA3(hashing.Bytes32(requestId), hashing.Uint256(big.NewInt(0)))
+}
+
+// ExportRetryables writes the retryable tickets that are still pending at the
+// end of the given block. ArbOS can't enumerate retryables, so every ticket
+// created up to that block is found in the stored results and checked with
+// ArbRetryableTx. It returns the number of tickets written.
+func ExportRetryables(ctx context.Context, txDB *txdb.TxDB, height uint64, w io.Writer) (uint64, error) {
+	snap, err := exportSnapshot(ctx, txDB, height)
+	if err != nil {
+		return 0, err
+	}
+	lastBlock, err := txDB.GetBlock(height)
+	if err != nil {
+		return 0, err
+	}
+	now := lastBlock.Header.Time
+
+	out := newJSONLinesWriter(w)
+	for blockNum := uint64(0); blockNum <= height; blockNum++ {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		block, err := txDB.GetBlock(blockNum)
+		if err != nil {
+			return 0, err
+		}
+		if block == nil {
+			return 0, errors.Errorf("block %v not found", blockNum)
+		}
+		_, results, err := txDB.GetBlockResults(block)
+		if err != nil {
+			return 0, err
+		}
+		if results == nil && block.LogCount > 0 {
+			return 0, errors.Errorf("block %v was reorged during the export", blockNum)
+		}
+		for _, res := range results {
+			if res.IncomingRequest.Kind != message.RetryableType || res.ResultCode != evm.ReturnCode {
+				continue
+			}
+			ticketId := RetryableTicketId(res.IncomingRequest.MessageID)
+			timeout, err := snap.GetRetryableTimeout(ctx, ticketId)
+			if err != nil {
+				return 0, err
+			}
+			if timeout.Sign() == 0 || timeout.Uint64() <= now {
+				continue
+			}
+			beneficiary, err := snap.GetRetryableBeneficiary(ctx, ticketId)
+			if err != nil {
+				return 0, err
+			}
+			retryable := message.NewRetryableTxFromData(res.IncomingRequest.Data)
+			err = out.Write(&RetryableEntry{
+				TicketId:    ticketId.ToEthHash(),
+				RequestId:   res.IncomingRequest.MessageID.ToEthHash(),
+				From:        res.IncomingRequest.Sender.ToEthAddress(),
+				To:          retryable.Destination.ToEthAddress(),
+				Callvalue:   (*hexutil.Big)(retryable.Value),
+				Beneficiary: beneficiary.ToEthAddress(),
+				Calldata:    retryable.Data,
+				MaxGas:      (*hexutil.Big)(retryable.MaxGas),
+				GasPriceBid: (*hexutil.Big)(retryable.GasPriceBid),
+				Timeout:     timeout.Uint64(),
+				CreatedAt:   blockNum,
+			})
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+	return out.count, out.Flush()
+}
+
+// ExportAddressTable writes the contents of ArbAddressTable at the end of
+// the given block and returns the number of entries written
+func ExportAddressTable(ctx context.Context, txDB *txdb.TxDB, height uint64, w io.Writer) (uint64, error) {
+	snap, err := exportSnapshot(ctx, txDB, height)
+	if err != nil {
+		return 0, err
+	}
+	size, err := snap.AddressTableSize(ctx)
+	if err != nil {
+		return 0, err
+	}
+	out := newJSONLinesWriter(w)
+	for i := uint64(0); i < size.Uint64(); i++ {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		address, err := snap.AddressTableLookupIndex(ctx, new(big.Int).SetUint64(i))
+		if err != nil {
+			return 0, err
+		}
+		if err := out.Write(&AddressTableEntry{Index: i, Address: address.ToEthAddress()}); err != nil {
+			return 0, err
+		}
+	}
+	return out.count, out.Flush()
+}
@@ -0,0 +1,77 @@
+/*
+* Copyright 2022, Offchain Labs, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package nitroexport
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/math"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+)
+
+func TestNewOutboxEntry(t *testing.T) {
+	sender := common.RandAddress()
+	dest := common.RandAddress()
+	calldata := common.RandBytes(40)
+	data := []byte{byte(evm.SendTxToL1Type)}
+	data = append(data, make([]byte, 12)...)
+	data = append(data, sender[:]...)
+	data = append(data, make([]byte, 12)...)
+	data = append(data, dest[:]...)
+	for _, val := range []int64{10, 20, 30, 40} {
+		data = append(data, math.U256Bytes(big.NewInt(val))...)
+	}
+	data = append(data, calldata...)
+
+	batch := &evm.MerkleRootResult{
+		BatchNumber: big.NewInt(5),
+		NumInBatch:  big.NewInt(1),
+		Tree:        &evm.MerkleLeaf{Data: data},
+	}
+	entry, err := newOutboxEntry(batch, 0)
+	test.FailIfError(t, err)
+	if entry.Kind != "l2ToL1Tx" || entry.BatchNumber != 5 || entry.Path != 0 || len(entry.Proof) != 0 {
+		t.Error("wrong entry", entry)
+	}
+	if entry.L2Sender != sender.ToEthAddress() || entry.L1Dest != dest.ToEthAddress() {
+		t.Error("wrong addresses", entry.L2Sender, entry.L1Dest)
+	}
+	if entry.Value.ToInt().Cmp(big.NewInt(40)) != 0 || !bytes.Equal(entry.Calldata, calldata) {
+		t.Error("wrong value or calldata")
+	}
+
+	var buf bytes.Buffer
+	out := newJSONLinesWriter(&buf)
+	test.FailIfError(t, out.Write(entry))
+	test.FailIfError(t, out.Write(entry))
+	test.FailIfError(t, out.Flush())
+	lines := bytes.Split(bytes.TrimSuffix(buf.Bytes(), []byte("\n")), []byte("\n"))
+	if len(lines) != 2 || out.count != 2 {
+		t.Fatal("expected two lines, got", len(lines))
+	}
+	var decoded OutboxEntry
+	test.FailIfError(t, json.Unmarshal(lines[1], &decoded))
+	if decoded.L1Dest != entry.L1Dest || !bytes.Equal(decoded.Data, data) {
+		t.Error("entry didn't round trip")
+	}
+}
@@ -0,0 +1,212 @@
+/*
+* Copyright 2022, Offchain Labs, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package nitroexport
+
+import (
+	"context"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/txdb"
+	arbcommon "github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethbridgecontracts"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/ethutils"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/protocol"
+)
+
+// OutboxEntry is a line of the outbox dataset, describing an L2 to L1
+// message that hasn't been executed on L1. Proof and Path are the proof and
+// index arguments Outbox.executeTransaction expects.
+type OutboxEntry struct {
+	BatchNumber uint64 `json:"batchNumber"`
+	// Index is the position of the message in its batch
+	Index uint64 `json:"index"`
+	Path  uint64 `json:"path"`
+	// Kind is "l2ToL1Tx" or "withdrawEth"
+	Kind      string         `json:"kind"`
+	L2Sender  common.Address `json:"l2Sender,omitempty"`
+	L1Dest    common.Address `json:"l1Dest"`
+	L2Block   *hexutil.Big   `json:"l2Block,omitempty"`
+	L1Block   *hexutil.Big   `json:"l1Block,omitempty"`
+	Timestamp *hexutil.Big   `json:"timestamp,omitempty"`
+	Value     *hexutil.Big   `json:"value"`
+	Calldata  hexutil.Bytes  `json:"calldata,omitempty"`
+	Proof     []common.Hash  `json:"proof"`
+	// Data is the raw send as stored in the batch
+	Data hexutil.Bytes `json:"data"`
+}
+
+// L1OutboxConfig describes where to find the L1 outboxes so messages that
+// were already executed can be left out of the outbox export
+type L1OutboxConfig struct {
+	Client          ethutils.EthClient
+	BridgeAddress   common.Address
+	FromBlock       int64
+	BlockSearchSize int64
+}
+
+// OutboxEntryId identifies a message by its batch and merkle path, which is
+// how L1 reports executed messages
+type OutboxEntryId struct {
+	BatchNumber uint64
+	Path        uint64
+}
+
+// ExportOutbox writes the messages from every stored outbox batch, skipping
+// those in executed, and returns the number of messages written
+func ExportOutbox(ctx context.Context, txDB *txdb.TxDB, executed map[OutboxEntryId]bool, w io.Writer) (uint64, error) {
+	out := newJSONLinesWriter(w)
+	for batchNum := uint64(0); ; batchNum++ {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		batch, err := txDB.GetMessageBatch(new(big.Int).SetUint64(batchNum))
+		if err != nil {
+			return 0, err
+		}
+		if batch == nil {
+			break
+		}
+		for i := uint64(0); i < batch.NumInBatch.Uint64(); i++ {
+			entry, err := newOutboxEntry(batch, i)
+			if err != nil {
+				return 0, errors.Wrapf(err, "batch %v message %v", batchNum, i)
+			}
+			if executed[OutboxEntryId{BatchNumber: batchNum, Path: entry.Path}] {
+				continue
+			}
+			if err := out.Write(entry); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return out.count, out.Flush()
+}
+
+func newOutboxEntry(batch *evm.MerkleRootResult, index uint64) (*OutboxEntry, error) {
+	proof, err := batch.GenerateProof(index)
+	if err != nil {
+		return nil, err
+	}
+	entry := &OutboxEntry{
+		BatchNumber: batch.BatchNumber.Uint64(),
+		Index:       index,
+		Path:        protocol.PathSliceToInt(proof.Path).Uint64(),
+		Proof:       arbcommon.NewEthHashesFromHashes(proof.Nodes),
+		Data:        proof.Data,
+	}
+	send, err := evm.NewVirtualSendResultFromData(proof.Data)
+	if err != nil {
+		return nil, err
+	}
+	switch send := send.(type) {
+	case *evm.L2ToL1TxResult:
+		entry.Kind = "l2ToL1Tx"
+		entry.L2Sender = send.L2Sender.ToEthAddress()
+		entry.L1Dest = send.L1Dest.ToEthAddress()
+		entry.L2Block = (*hexutil.Big)(send.L2Block)
+		entry.L1Block = (*hexutil.Big)(send.L1Block)
+		entry.Timestamp = (*hexutil.Big)(send.Timestamp)
+		entry.Value = (*hexutil.Big)(send.Value)
+		entry.Calldata = send.Calldata
+	case *evm.WithdrawEthResult:
+		entry.Kind = "withdrawEth"
+		entry.L1Dest = send.Destination.ToEthAddress()
+		entry.Value = (*hexutil.Big)(send.Amount)
+	default:
+		return nil, errors.Errorf("unexpected send type %T", send)
+	}
+	return entry, nil
+}
+
+// FindExecutedOutboxEntries collects the messages executed on L1 through any
+// of the outboxes the bridge has allowed. Older outboxes number their
+// entries separately from the batches, so each outbox's entries are mapped
+// back to batch numbers using its OutboxEntryCreated events.
+func FindExecutedOutboxEntries(ctx context.Context, client ethutils.EthClient, bridgeAddress common.Address, fromBlock, blockSearchSize int64) (map[OutboxEntryId]bool, error) {
+	bridge, err := ethbridgecontracts.NewBridge(bridgeAddress, client)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	callOpts := &bind.CallOpts{Context: ctx}
+	outboxCount, err := bridge.AllowedOutboxListLength(callOpts)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	latestHeader, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	latestBlock := latestHeader.Number.Int64()
+
+	executed := make(map[OutboxEntryId]bool)
+	for i := int64(0); i < outboxCount.Int64(); i++ {
+		outboxAddress, err := bridge.AllowedOutboxList(callOpts, big.NewInt(i))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		outbox, err := ethbridgecontracts.NewOutbox(outboxAddress, client)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		batches := make(map[uint64]uint64)
+		var executedEvents []*ethbridgecontracts.OutboxOutBoxTransactionExecuted
+		for start := fromBlock; start <= latestBlock; {
+			end := latestBlock
+			if blockSearchSize > 0 && start+blockSearchSize-1 < latestBlock {
+				end = start + blockSearchSize - 1
+			}
+			endU64 := uint64(end)
+			filterOpts := &bind.FilterOpts{Start: uint64(start), End: &endU64, Context: ctx}
+			created, err := outbox.FilterOutboxEntryCreated(filterOpts, nil)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			for created.Next() {
+				batches[created.Event.OutboxEntryIndex.Uint64()] = created.Event.BatchNum.Uint64()
+			}
+			if err := created.Error(); err != nil {
+				return nil, errors.WithStack(err)
+			}
+			txs, err := outbox.FilterOutBoxTransactionExecuted(filterOpts, nil, nil, nil)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			for txs.Next() {
+				executedEvents = append(executedEvents, txs.Event)
+			}
+			if err := txs.Error(); err != nil {
+				return nil, errors.WithStack(err)
+			}
+			start = end + 1
+		}
+		for _, ev := range executedEvents {
+			batchNum, ok := batches[ev.OutboxEntryIndex.Uint64()]
+			if !ok {
+				return nil, errors.Errorf("outbox %v executed a message from unknown entry %v", outboxAddress, ev.OutboxEntryIndex)
+			}
+			executed[OutboxEntryId{BatchNumber: batchNum, Path: ev.TransactionIndex.Uint64()}] = true
+		}
+	}
+	return executed, nil
+}
@@ -3,6 +3,7 @@ package nitroexport
 import (
 	"context"
 	"errors"
+	"io"
 	"math/big"
 	"os"
 	"path"
@@ -18,9 +19,13 @@ type ExportRPCServer struct {
 	db         *CrossDB
 	arbcore    core.ArbCore
 	pathPrefix string
+	chainId    *big.Int
+	l1Outbox   *L1OutboxConfig
 }
 
-func NewExportRPCServer(ctx context.Context, txDB *txdb.TxDB, arbcore core.ArbCore, pathPrefix string, chainId *big.Int) (*ExportRPCServer, error) {
+// NewExportRPCServer creates the export RPCs. l1Outbox is used to leave
+// messages already executed on L1 out of the outbox export.
+func NewExportRPCServer(ctx context.Context, txDB *txdb.TxDB, arbcore core.ArbCore, pathPrefix string, chainId *big.Int, l1Outbox *L1OutboxConfig) (*ExportRPCServer, error) {
 	ethDbPath := path.Join(pathPrefix, "nitro")
 	err := os.MkdirAll(ethDbPath, os.ModePerm)
 	if err != nil {
@@ -35,6 +40,8 @@ func NewExportRPCServer(ctx context.Context, txDB *txdb.TxDB, arbcore core.ArbCo
 		db:         db,
 		arbcore:    arbcore,
 		pathPrefix: pathPrefix,
+		chainId:    chainId,
+		l1Outbox:   l1Outbox,
 	}, nil
 }
 
@@ -96,7 +103,7 @@ func (r *ExportRPCServer) ExportState(blockNumber rpc.BlockNumber) error {
 	if err != nil {
 		return err
 	}
-	err = ExportState(r.arbcore, blockU64, path.Join(r.pathPrefix, "state", hexutil.EncodeUint64(blockU64)))
+	err = ExportState(context.Background(), r.db.txDB, r.arbcore, blockU64, r.stateDir(blockU64))
 	if err != nil {
 		log.Error().Err(err).Uint64("blockNumber", blockU64).Msg("export state failed")
 		return err
@@ -104,3 +111,120 @@ func (r *ExportRPCServer) ExportState(blockNumber rpc.BlockNumber) error {
 	log.Info().Uint64("blockNumber", blockU64).Msg("State export done")
 	return nil
 }
+
+// ExportStateStatus returns the manifest of the state export of the given
+// block, listing the files that have been completely written so far
+func (r *ExportRPCServer) ExportStateStatus(blockNumber rpc.BlockNumber) (*StateManifest, error) {
+	blockU64, err := r.blockNumToU6(blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := ReadStateManifest(r.stateDir(blockU64))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return manifest, err
+}
+
+func (r *ExportRPCServer) stateDir(blockNumber uint64) string {
+	return path.Join(r.pathPrefix, "state", hexutil.EncodeUint64(blockNumber))
+}
+
+// ExportGenesis converts the finished state export of the given block into
+// genesis.json in the same directory and returns the number of accounts
+func (r *ExportRPCServer) ExportGenesis(blockNumber rpc.BlockNumber) (hexutil.Uint64, error) {
+	blockU64, err := r.blockNumToU6(blockNumber)
+	if err != nil {
+		return 0, err
+	}
+	block, err := r.db.txDB.GetBlock(blockU64)
+	if err != nil {
+		return 0, err
+	}
+	if block == nil {
+		return 0, errors.New("block not found")
+	}
+	dir := r.stateDir(blockU64)
+	count, err := exportToFile(path.Join(dir, "genesis.json"), func(w io.Writer) (uint64, error) {
+		return WriteGenesis(dir, GenesisChainConfig(r.chainId), block.Header, w)
+	})
+	if err != nil {
+		log.Error().Err(err).Uint64("blockNumber", blockU64).Msg("export genesis failed")
+	}
+	return hexutil.Uint64(count), err
+}
+
+func (r *ExportRPCServer) migrationDir(blockNumber uint64) string {
+	return path.Join(r.pathPrefix, "migration", hexutil.EncodeUint64(blockNumber))
+}
+
+// ExportRetryables writes the retryables pending at the given block to
+// retryables.jsonl and returns how many were written
+func (r *ExportRPCServer) ExportRetryables(ctx context.Context, blockNumber rpc.BlockNumber) (hexutil.Uint64, error) {
+	blockU64, err := r.blockNumToU6(blockNumber)
+	if err != nil {
+		return 0, err
+	}
+	count, err := exportToFile(path.Join(r.migrationDir(blockU64), "retryables.jsonl"), func(w io.Writer) (uint64, error) {
+		return ExportRetryables(ctx, r.db.txDB, blockU64, w)
+	})
+	if err != nil {
+		log.Error().Err(err).Uint64("blockNumber", blockU64).Msg("export retryables failed")
+	}
+	return hexutil.Uint64(count), err
+}
+
+// ExportAddressTable writes the address table at the given block to
+// addresstable.jsonl and returns how many entries were written
+func (r *ExportRPCServer) ExportAddressTable(ctx context.Context, blockNumber rpc.BlockNumber) (hexutil.Uint64, error) {
+	blockU64, err := r.blockNumToU6(blockNumber)
+	if err != nil {
+		return 0, err
+	}
+	count, err := exportToFile(path.Join(r.migrationDir(blockU64), "addresstable.jsonl"), func(w io.Writer) (uint64, error) {
+		return ExportAddressTable(ctx, r.db.txDB, blockU64, w)
+	})
+	if err != nil {
+		log.Error().Err(err).Uint64("blockNumber", blockU64).Msg("export address table failed")
+	}
+	return hexutil.Uint64(count), err
+}
+
+// ExportUnexecutedOutbox writes the outbox messages that haven't been
+// executed on L1 to outbox.jsonl and returns how many were written
+func (r *ExportRPCServer) ExportUnexecutedOutbox(ctx context.Context) (hexutil.Uint64, error) {
+	executed, err := FindExecutedOutboxEntries(ctx, r.l1Outbox.Client, r.l1Outbox.BridgeAddress, r.l1Outbox.FromBlock, r.l1Outbox.BlockSearchSize)
+	if err != nil {
+		return 0, err
+	}
+	count, err := exportToFile(path.Join(r.pathPrefix, "migration", "outbox.jsonl"), func(w io.Writer) (uint64, error) {
+		return ExportOutbox(ctx, r.db.txDB, executed, w)
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("export outbox failed")
+	}
+	return hexutil.Uint64(count), err
+}
+
+// exportToFile writes a dataset under a temporary name so a failed export
+// never leaves a truncated file behind
+func exportToFile(filename string, export func(io.Writer) (uint64, error)) (uint64, error) {
+	if err := os.MkdirAll(path.Dir(filename), os.ModePerm); err != nil {
+		return 0, err
+	}
+	tmpFilename := filename + ".tmp"
+	file, err := os.Create(tmpFilename)
+	if err != nil {
+		return 0, err
+	}
+	count, err := export(file)
+	if err != nil {
+		_ = file.Close()
+		_ = os.Remove(tmpFilename)
+		return 0, err
+	}
+	if err := file.Close(); err != nil {
+		return 0, err
+	}
+	return count, os.Rename(tmpFilename, filename)
+}
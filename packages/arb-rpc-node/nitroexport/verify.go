@@ -0,0 +1,219 @@
+/*
+* Copyright 2022, Offchain Labs, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package nitroexport
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+)
+
+// StateTotals summarizes the contents of a state export
+type StateTotals struct {
+	Accounts            uint64       `json:"accounts"`
+	AccountBalances     *hexutil.Big `json:"accountBalances"`
+	Retryables          uint64       `json:"retryables"`
+	RetryableCallvalue  *hexutil.Big `json:"retryableCallvalue"`
+	AddressTableEntries uint64       `json:"addressTableEntries"`
+}
+
+// exportedAccount is an entry of accounts.json as written by ArbCore
+type exportedAccount struct {
+	Addr         common.Address
+	Nonce        uint64
+	Balance      string
+	ContractInfo *exportedContract
+}
+
+type exportedContract struct {
+	Code            byteArray
+	ContractStorage map[common.Hash]common.Hash
+}
+
+// byteArray decodes bytes written as a JSON array of numbers
+type byteArray []byte
+
+func (b *byteArray) UnmarshalJSON(data []byte) error {
+	var values []uint16
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	bytes := make([]byte, 0, len(values))
+	for _, val := range values {
+		if val > 255 {
+			return errors.Errorf("invalid byte %v", val)
+		}
+		bytes = append(bytes, byte(val))
+	}
+	*b = bytes
+	return nil
+}
+
+type exportedRetryable struct {
+	Id        common.Hash
+	Callvalue string
+}
+
+// VerifyState re-reads a finished state export without needing the node's
+// database. Every file must match the hash recorded in the manifest, entries
+// must be unique, and the exported balances and retryable callvalue must add
+// up to the total ArbOS reported when the export was started.
+func VerifyState(dirname string) (*StateTotals, error) {
+	manifest, err := ReadStateManifest(dirname)
+	if err != nil {
+		return nil, err
+	}
+	if !manifest.Complete() {
+		return nil, errors.Errorf("export of block %v is incomplete", manifest.Block)
+	}
+	for filename, expected := range manifest.Files {
+		actual, err := describeFile(filepath.Join(dirname, filename))
+		if err != nil {
+			return nil, err
+		}
+		if *actual != expected {
+			return nil, errors.Errorf("%v has hash %v and %v entries but the manifest lists %v and %v entries", filename, actual.Hash, actual.Entries, expected.Hash, expected.Entries)
+		}
+	}
+
+	indexData, err := ioutil.ReadFile(filepath.Join(dirname, IndexFilename))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var index core.ArbosStateIndex
+	if err := json.Unmarshal(indexData, &index); err != nil {
+		return nil, errors.Wrap(err, "invalid index")
+	}
+	if index.NextBlockNumber != manifest.Block+1 {
+		return nil, errors.Errorf("index starts at block %v but the export is of block %v", index.NextBlockNumber, manifest.Block)
+	}
+
+	totals := &StateTotals{}
+	accountBalances := new(big.Int)
+	accounts := make(map[common.Address]struct{})
+	err = forEachEntry(filepath.Join(dirname, index.AccountsPath), func(data []byte) error {
+		var account exportedAccount
+		if err := json.Unmarshal(data, &account); err != nil {
+			return errors.Wrapf(err, "invalid account %v", totals.Accounts)
+		}
+		if _, ok := accounts[account.Addr]; ok {
+			return errors.Errorf("account %v exported twice", account.Addr)
+		}
+		accounts[account.Addr] = struct{}{}
+		balance, ok := new(big.Int).SetString(account.Balance, 10)
+		if !ok {
+			return errors.Errorf("account %v has invalid balance %v", account.Addr, account.Balance)
+		}
+		accountBalances.Add(accountBalances, balance)
+		totals.Accounts++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	totals.AccountBalances = (*hexutil.Big)(accountBalances)
+
+	retryableCallvalue := new(big.Int)
+	retryables := make(map[common.Hash]struct{})
+	err = forEachEntry(filepath.Join(dirname, index.RetryableDataPath), func(data []byte) error {
+		var retryable exportedRetryable
+		if err := json.Unmarshal(data, &retryable); err != nil {
+			return errors.Wrapf(err, "invalid retryable %v", totals.Retryables)
+		}
+		if _, ok := retryables[retryable.Id]; ok {
+			return errors.Errorf("retryable %v exported twice", retryable.Id)
+		}
+		retryables[retryable.Id] = struct{}{}
+		callvalue, ok := new(big.Int).SetString(retryable.Callvalue, 10)
+		if !ok {
+			return errors.Errorf("retryable %v has invalid callvalue %v", retryable.Id, retryable.Callvalue)
+		}
+		retryableCallvalue.Add(retryableCallvalue, callvalue)
+		totals.Retryables++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	totals.RetryableCallvalue = (*hexutil.Big)(retryableCallvalue)
+
+	tableEntries := make(map[common.Address]struct{})
+	err = forEachEntry(filepath.Join(dirname, index.AddressTableContentsPath), func(data []byte) error {
+		var address common.Address
+		if err := json.Unmarshal(data, &address); err != nil {
+			return errors.Wrapf(err, "invalid address table entry %v", totals.AddressTableEntries)
+		}
+		if _, ok := tableEntries[address]; ok {
+			return errors.Errorf("address %v is in the address table twice", address)
+		}
+		tableEntries[address] = struct{}{}
+		totals.AddressTableEntries++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest.TotalBalance == nil {
+		return nil, errors.New("manifest is missing the ArbOS total of eth balances")
+	}
+	// ArbOS totals the balances of every account along with the callvalue
+	// escrowed for retryables, which isn't held by any account
+	expected := manifest.TotalBalance.ToInt()
+	total := new(big.Int).Add(accountBalances, retryableCallvalue)
+	if total.Cmp(expected) != 0 {
+		return nil, errors.Errorf("accounts hold %v and retryables escrow %v but ArbOS reported a total of %v", accountBalances, retryableCallvalue, expected)
+	}
+	return totals, nil
+}
+
+// forEachEntry calls f with each line of an exported file. Lines aren't size
+// limited since accounts include their full contract storage.
+func forEachEntry(path string, f func([]byte) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 && line[len(line)-1] == '\n' {
+			if err := f(line); err != nil {
+				return err
+			}
+		} else if len(line) > 0 {
+			return errors.Errorf("%v ends with a partial entry", path)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+}
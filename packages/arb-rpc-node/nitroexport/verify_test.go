@@ -0,0 +1,99 @@
+/*
+* Copyright 2022, Offchain Labs, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package nitroexport
+
+import (
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+)
+
+func writeTestExport(t *testing.T, dirname string, totalBalance int64) {
+	t.Helper()
+	manifest := &StateManifest{
+		Block:        10,
+		TotalBalance: (*hexutil.Big)(big.NewInt(totalBalance)),
+		Files:        make(map[string]ExportedFile),
+	}
+	files := map[string]string{
+		core.ArbosStateAccounts.Filename(): `{"Addr":"0x0000000000000000000000000000000000000001","Nonce":0,"Balance":"100"}
+{"Addr":"0x0000000000000000000000000000000000000002","Nonce":3,"Balance":"50"}
+`,
+		core.ArbosStateRetryables.Filename():   `{"Id":"0x0000000000000000000000000000000000000000000000000000000000000001","Callvalue":"25"}` + "\n",
+		core.ArbosStateAddressTable.Filename(): `"0x0000000000000000000000000000000000000001"` + "\n",
+		IndexFilename:                          `{"NextBlockNumber":11,"AddressTableContentsPath":"addresstable.json","RetryableDataPath":"retryables.json","AccountsPath":"accounts.json"}`,
+	}
+	for filename, contents := range files {
+		test.FailIfError(t, ioutil.WriteFile(filepath.Join(dirname, filename+".tmp"), []byte(contents), 0644))
+		test.FailIfError(t, finishFile(dirname, filename, manifest))
+	}
+}
+
+func TestVerifyState(t *testing.T) {
+	dirname := t.TempDir()
+	writeTestExport(t, dirname, 175)
+	totals, err := VerifyState(dirname)
+	test.FailIfError(t, err)
+	if totals.Accounts != 2 || totals.Retryables != 1 || totals.AddressTableEntries != 1 {
+		t.Error("wrong totals", totals)
+	}
+	if totals.AccountBalances.ToInt().Cmp(big.NewInt(150)) != 0 {
+		t.Error("wrong account balances", totals.AccountBalances)
+	}
+	if totals.RetryableCallvalue.ToInt().Cmp(big.NewInt(25)) != 0 {
+		t.Error("wrong retryable callvalue", totals.RetryableCallvalue)
+	}
+
+	// The total includes escrowed callvalue, so accounts alone don't match
+	withoutEscrow := t.TempDir()
+	writeTestExport(t, withoutEscrow, 150)
+	if _, err := VerifyState(withoutEscrow); err == nil {
+		t.Error("expected balance mismatch without retryable callvalue")
+	}
+
+	wrongTotal := t.TempDir()
+	writeTestExport(t, wrongTotal, 176)
+	if _, err := VerifyState(wrongTotal); err == nil {
+		t.Error("expected balance mismatch")
+	}
+}
+
+func TestVerifyStateDetectsChanges(t *testing.T) {
+	dirname := t.TempDir()
+	writeTestExport(t, dirname, 175)
+	accountsPath := filepath.Join(dirname, core.ArbosStateAccounts.Filename())
+	test.FailIfError(t, ioutil.WriteFile(accountsPath, []byte(`{"Addr":"0x0000000000000000000000000000000000000001","Nonce":0,"Balance":"150"}`+"\n"), 0644))
+	if _, err := VerifyState(dirname); err == nil {
+		t.Error("expected modified file to fail verification")
+	}
+
+	incomplete := t.TempDir()
+	writeTestExport(t, incomplete, 175)
+	manifest, err := ReadStateManifest(incomplete)
+	test.FailIfError(t, err)
+	delete(manifest.Files, IndexFilename)
+	test.FailIfError(t, writeStateManifest(incomplete, manifest))
+	if _, err := VerifyState(incomplete); err == nil {
+		t.Error("expected incomplete export to fail verification")
+	}
+}
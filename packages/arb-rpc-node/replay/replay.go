@@ -0,0 +1,254 @@
+/*
+* Copyright 2022, Offchain Labs, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package replay
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/arblog"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/machine"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+var logger = arblog.Logger.With().Str("component", "replay").Logger()
+
+const (
+	messageBatchSize = 1000
+	gasPerExecution  = 1000000000
+	progressInterval = 10000
+)
+
+// Divergence describes the first log a replay produced differently from
+// the stored chain
+type Divergence struct {
+	LogIndex    uint64       `json:"logIndex"`
+	Block       *uint64      `json:"block,omitempty"`
+	TxHash      *common.Hash `json:"txHash,omitempty"`
+	Differences []string     `json:"differences"`
+	Expected    string       `json:"expected"`
+	Actual      string       `json:"actual"`
+}
+
+// Range selects the L2 blocks to replay, inclusive
+type Range struct {
+	Start uint64
+	End   uint64
+}
+
+// Replayer re-executes stored inbox messages and compares the logs produced
+// to the ones stored in the database
+type Replayer struct {
+	lookup core.ArbCoreLookup
+	store  machine.NodeStore
+}
+
+func NewReplayer(lookup core.ArbCoreLookup, store machine.NodeStore) *Replayer {
+	return &Replayer{lookup: lookup, store: store}
+}
+
+// CheckpointMachine returns the stored machine as of the start of the range,
+// along with the number of messages it has read and logs it has emitted
+func (r *Replayer) CheckpointMachine(blocks Range) (machine.Machine, uint64, uint64, error) {
+	var cursor core.ExecutionCursor
+	var err error
+	if blocks.Start == 0 {
+		cursor, err = r.lookup.GetExecutionCursor(big.NewInt(0), true)
+	} else {
+		cursor, err = r.lookup.GetExecutionCursorAtEndOfBlock(blocks.Start-1, true)
+	}
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	messagesRead := cursor.TotalMessagesRead().Uint64()
+	logCount := cursor.TotalLogCount().Uint64()
+	mach, err := r.lookup.TakeMachine(cursor)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return mach, messagesRead, logCount, nil
+}
+
+// EndMessage returns the number of messages read by the end of the range
+func (r *Replayer) EndMessage(blocks Range) (uint64, error) {
+	_, _, endMessage, err := r.rangeBounds(blocks)
+	return endMessage, err
+}
+
+// Replay executes mach, which must have read messagesRead messages and
+// emitted logCount logs, through the end of the range. Logs from the range
+// are compared to the stored ones and the first divergence is returned, or
+// nil if the range was reproduced exactly.
+func (r *Replayer) Replay(ctx context.Context, mach machine.Machine, messagesRead, logCount uint64, blocks Range) (*Divergence, error) {
+	startLog, endLog, endMessage, err := r.rangeBounds(blocks)
+	if err != nil {
+		return nil, err
+	}
+	if logCount > startLog || messagesRead > endMessage {
+		return nil, errors.Errorf("machine at log %v is past the start of the range at log %v", logCount, startLog)
+	}
+	logger.Info().
+		Uint64("startLog", startLog).
+		Uint64("endLog", endLog).
+		Uint64("messages", endMessage-messagesRead).
+		Msg("replaying blocks")
+
+	nextMessage := messagesRead
+	lastProgress := logCount
+	var pending []inbox.InboxMessage
+	for logCount < endLog {
+		if len(pending) == 0 && nextMessage < endMessage {
+			count := endMessage - nextMessage
+			if count > messageBatchSize {
+				count = messageBatchSize
+			}
+			pending, err = r.lookup.GetMessages(new(big.Int).SetUint64(nextMessage), new(big.Int).SetUint64(count))
+			if err != nil {
+				return nil, err
+			}
+			nextMessage += count
+		}
+		assertion, _, _, err := mach.ExecuteAssertionAdvanced(ctx, gasPerExecution, false, pending, nil, false, false)
+		if err != nil {
+			return nil, err
+		}
+		pending = pending[assertion.InboxMessagesConsumed:]
+		for _, actual := range assertion.Logs {
+			if logCount >= startLog && logCount < endLog {
+				divergence, err := r.compareLog(logCount, actual)
+				if err != nil || divergence != nil {
+					return divergence, err
+				}
+			}
+			logCount++
+		}
+		stalled := assertion.NumGas == 0 && assertion.InboxMessagesConsumed == 0 && (len(pending) > 0 || nextMessage >= endMessage)
+		if stalled && logCount < endLog {
+			return &Divergence{
+				LogIndex:    logCount,
+				Differences: []string{fmt.Sprintf("machine stopped after emitting %v of %v logs", logCount, endLog)},
+			}, nil
+		}
+		if logCount-lastProgress >= progressInterval {
+			logger.Info().Uint64("log", logCount).Uint64("endLog", endLog).Msg("replay progress")
+			lastProgress = logCount
+		}
+	}
+	return nil, nil
+}
+
+// rangeBounds returns the logs emitted by the range and the number of
+// messages read by its end
+func (r *Replayer) rangeBounds(blocks Range) (uint64, uint64, uint64, error) {
+	if blocks.End < blocks.Start {
+		return 0, 0, 0, errors.Errorf("invalid block range %v-%v", blocks.Start, blocks.End)
+	}
+	blockCount, err := r.store.BlockCount()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if blocks.End >= blockCount {
+		return 0, 0, 0, errors.Errorf("block %v not in database with %v blocks", blocks.End, blockCount)
+	}
+	startInfo, err := r.store.GetBlockInfo(blocks.Start)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	endInfo, err := r.store.GetBlockInfo(blocks.End)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if startInfo == nil || endInfo == nil {
+		return 0, 0, 0, errors.New("missing block info")
+	}
+	endBlockLog, err := core.GetZeroOrOneLog(r.lookup, new(big.Int).SetUint64(endInfo.BlockLog))
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if endBlockLog.Value == nil {
+		return 0, 0, 0, errors.Errorf("missing log for block %v", blocks.End)
+	}
+	return startInfo.InitialLogIndex(), endInfo.BlockLog + 1, endBlockLog.Inbox.Count.Uint64(), nil
+}
+
+func (r *Replayer) compareLog(index uint64, actual value.Value) (*Divergence, error) {
+	stored, err := core.GetZeroOrOneLog(r.lookup, new(big.Int).SetUint64(index))
+	if err != nil {
+		return nil, err
+	}
+	if stored.Value == nil {
+		return nil, errors.Errorf("missing stored log %v", index)
+	}
+	if stored.Value.Equal(actual) {
+		return nil, nil
+	}
+	divergence := &Divergence{
+		LogIndex: index,
+		Expected: stored.Value.String(),
+		Actual:   actual.String(),
+	}
+	divergence.Differences = CompareLogValues(stored.Value, actual)
+	if res, err := evm.NewResultFromValue(stored.Value); err == nil {
+		switch res := res.(type) {
+		case *evm.TxResult:
+			block := res.IncomingRequest.L2BlockNumber.Uint64()
+			divergence.Block = &block
+			divergence.TxHash = &res.IncomingRequest.MessageID
+			divergence.Expected = res.String()
+		case *evm.BlockInfo:
+			block := res.BlockNum.Uint64()
+			divergence.Block = &block
+		}
+	}
+	if res, err := evm.NewResultFromValue(actual); err == nil {
+		if txRes, ok := res.(*evm.TxResult); ok {
+			divergence.Actual = txRes.String()
+		}
+	}
+	return divergence, nil
+}
+
+// CompareLogValues describes how two differing AVM logs differ, comparing
+// transaction and block results field by field
+func CompareLogValues(expected, actual value.Value) []string {
+	expectedRes, err1 := evm.NewResultFromValue(expected)
+	actualRes, err2 := evm.NewResultFromValue(actual)
+	var differences []string
+	switch {
+	case err1 != nil || err2 != nil:
+	case fmt.Sprintf("%T", expectedRes) != fmt.Sprintf("%T", actualRes):
+		differences = append(differences, fmt.Sprintf("different result types %T and %T", expectedRes, actualRes))
+	default:
+		switch expectedRes := expectedRes.(type) {
+		case *evm.TxResult:
+			differences = evm.CompareResults(expectedRes, actualRes.(*evm.TxResult))
+		case *evm.BlockInfo:
+			differences = evm.CompareBlockResults(expectedRes, actualRes.(*evm.BlockInfo))
+		}
+	}
+	if len(differences) == 0 && !expected.Equal(actual) {
+		differences = append(differences, "different log values")
+	}
+	return differences
+}
@@ -0,0 +1,198 @@
+/*
+* Copyright 2022, Offchain Labs, Inc.
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*
+*    http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+ */
+
+package replay
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/arbos"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/message"
+	"github.com/offchainlabs/arbitrum/packages/arb-node-core/monitor"
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/dev"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/inbox"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/protocol"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/value"
+)
+
+// newTestChain creates a dev chain holding a few deposits
+func newTestChain(t *testing.T) (*monitor.Monitor, uint64) {
+	ctx := context.Background()
+	arbosPath, err := arbos.Path(false)
+	test.FailIfError(t, err)
+	backend, db, mon, cancel, _, err := dev.NewDevNode(ctx, t.TempDir(), arbosPath, big.NewInt(42161), common.RandAddress(), 0, false)
+	test.FailIfError(t, err)
+	t.Cleanup(cancel)
+
+	params := protocol.ChainParams{
+		GracePeriod:               common.NewTimeBlocksInt(3),
+		ArbGasSpeedLimitPerSecond: 2000000000000,
+	}
+	initMsg, err := message.NewInitMessage(params, common.RandAddress(), nil)
+	test.FailIfError(t, err)
+	_, err = backend.AddInboxMessage(ctx, initMsg, common.Address{})
+	test.FailIfError(t, err)
+	for i := 0; i < 3; i++ {
+		deposit := message.EthDepositTx{
+			L2Message: message.NewSafeL2Message(message.ContractTransaction{
+				BasicTx: message.BasicTx{
+					MaxGas:      big.NewInt(1000000),
+					GasPriceBid: big.NewInt(0),
+					DestAddress: common.RandAddress(),
+					Payment:     big.NewInt(100),
+				},
+			}),
+		}
+		_, err = backend.AddInboxMessage(ctx, deposit, common.RandAddress())
+		test.FailIfError(t, err)
+	}
+	blockCount, err := db.BlockCount()
+	test.FailIfError(t, err)
+	return mon, blockCount
+}
+
+func TestReplay(t *testing.T) {
+	mon, blockCount := newTestChain(t)
+	r := NewReplayer(mon.Core, mon.Storage.GetNodeStore())
+	ranges := []Range{
+		{Start: 0, End: blockCount - 1},
+		{Start: 1, End: 2},
+		{Start: blockCount - 1, End: blockCount - 1},
+	}
+	for _, blocks := range ranges {
+		mach, messagesRead, logCount, err := r.CheckpointMachine(blocks)
+		test.FailIfError(t, err)
+		divergence, err := r.Replay(context.Background(), mach, messagesRead, logCount, blocks)
+		test.FailIfError(t, err)
+		if divergence != nil {
+			t.Error("replaying blocks", blocks.Start, "to", blocks.End, "diverged:", divergence.Differences)
+		}
+	}
+
+	if _, err := r.Replay(context.Background(), nil, 0, 0, Range{Start: 0, End: blockCount}); err == nil {
+		t.Error("replayed past the last block")
+	}
+}
+
+// retimedLookup serves one message with a different timestamp than the one
+// stored
+type retimedLookup struct {
+	core.ArbCoreLookup
+	seqNum uint64
+}
+
+func (l retimedLookup) GetMessages(startIndex, count *big.Int) ([]inbox.InboxMessage, error) {
+	messages, err := l.ArbCoreLookup.GetMessages(startIndex, count)
+	if err != nil {
+		return nil, err
+	}
+	for i := range messages {
+		if messages[i].InboxSeqNum.Uint64() == l.seqNum {
+			messages[i].ChainTime.Timestamp = new(big.Int).Add(messages[i].ChainTime.Timestamp, big.NewInt(1000))
+		}
+	}
+	return messages, nil
+}
+
+func TestReplayDivergence(t *testing.T) {
+	mon, blockCount := newTestChain(t)
+	msgCount, err := mon.Core.GetMessageCount()
+	test.FailIfError(t, err)
+
+	// The last deposit is followed by the message ending its block
+	lookup := retimedLookup{ArbCoreLookup: mon.Core, seqNum: msgCount.Uint64() - 2}
+	r := NewReplayer(lookup, mon.Storage.GetNodeStore())
+	blocks := Range{Start: 1, End: blockCount - 1}
+	mach, messagesRead, logCount, err := r.CheckpointMachine(blocks)
+	test.FailIfError(t, err)
+	divergence, err := r.Replay(context.Background(), mach, messagesRead, logCount, blocks)
+	test.FailIfError(t, err)
+	if divergence == nil {
+		t.Fatal("replay with a changed message didn't diverge")
+	}
+	if divergence.Block == nil || *divergence.Block < blocks.Start || *divergence.Block > blocks.End {
+		t.Error("divergence in unexpected block", divergence.Block)
+	}
+	if len(divergence.Differences) == 0 || divergence.Expected == divergence.Actual {
+		t.Error("divergence not described", divergence)
+	}
+}
+
+func TestCompareDivergentReceipt(t *testing.T) {
+	mon, _ := newTestChain(t)
+	r := NewReplayer(mon.Core, mon.Storage.GetNodeStore())
+	logCount, err := mon.Core.GetLogCount()
+	test.FailIfError(t, err)
+	for index := logCount.Uint64(); index > 0; index-- {
+		stored, err := core.GetZeroOrOneLog(mon.Core, new(big.Int).SetUint64(index-1))
+		test.FailIfError(t, err)
+		res, err := evm.NewResultFromValue(stored.Value)
+		if err != nil {
+			continue
+		}
+		txRes, ok := res.(*evm.TxResult)
+		if !ok {
+			continue
+		}
+
+		// Rebuild the receipt with more gas used
+		tup := stored.Value.(*value.TupleValue)
+		contents := append([]value.Value(nil), tup.Contents()...)
+		contents[3] = value.NewTuple2(
+			value.NewIntValue(new(big.Int).Add(txRes.GasUsed, big.NewInt(1))),
+			value.NewIntValue(txRes.GasPrice),
+		)
+		actual, err := value.NewTupleFromSlice(contents)
+		test.FailIfError(t, err)
+
+		divergence, err := r.compareLog(index-1, actual)
+		test.FailIfError(t, err)
+		if divergence == nil {
+			t.Fatal("changed receipt not reported")
+		}
+		if divergence.TxHash == nil || *divergence.TxHash != txRes.IncomingRequest.MessageID {
+			t.Error("wrong divergent transaction", divergence.TxHash)
+		}
+		if len(divergence.Differences) != 1 || !strings.HasPrefix(divergence.Differences[0], "different gas used") {
+			t.Error("wrong differences", divergence.Differences)
+		}
+
+		divergence, err = r.compareLog(index-1, stored.Value)
+		test.FailIfError(t, err)
+		if divergence != nil {
+			t.Error("stored receipt diverged from itself", divergence.Differences)
+		}
+		return
+	}
+	t.Fatal("no transaction receipt found")
+}
+
+func TestCompareLogValues(t *testing.T) {
+	if differences := CompareLogValues(value.NewInt64Value(1), value.NewInt64Value(1)); len(differences) != 0 {
+		t.Error("unexpected differences", differences)
+	}
+	differences := CompareLogValues(value.NewInt64Value(1), value.NewInt64Value(2))
+	if len(differences) != 1 || differences[0] != "different log values" {
+		t.Error("wrong differences", differences)
+	}
+}
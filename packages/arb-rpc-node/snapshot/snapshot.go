@@ -463,6 +463,63 @@ func (s *Snapshot) GetBalance(ctx context.Context, account common.Address) (*big
 	return arbos.ParseBalanceResult(res.ReturnData)
 }
 
+func (s *Snapshot) GetTotalOfEthBalances(ctx context.Context) (*big.Int, error) {
+	res, err := s.basicCall(ctx, arbos.GetTotalOfEthBalances(), common.NewAddressFromEth(arbos.ARB_OWNER_ADDRESS))
+	if err != nil {
+		return nil, err
+	}
+	if err := checkValidResult(res); err != nil {
+		return nil, err
+	}
+	return arbos.ParseTotalOfEthBalancesResult(res.ReturnData)
+}
+
+// GetRetryableTimeout returns the timeout of a retryable ticket, which is zero
+// if the ticket was redeemed, canceled or never existed
+func (s *Snapshot) GetRetryableTimeout(ctx context.Context, ticketId common.Hash) (*big.Int, error) {
+	res, err := s.basicCall(ctx, arbos.GetTimeoutData(ticketId), common.NewAddressFromEth(arbos.ARB_RETRYABLE_ADDRESS))
+	if err != nil {
+		return nil, err
+	}
+	if err := checkValidResult(res); err != nil {
+		return nil, err
+	}
+	return arbos.ParseGetTimeoutResult(res.ReturnData)
+}
+
+func (s *Snapshot) GetRetryableBeneficiary(ctx context.Context, ticketId common.Hash) (common.Address, error) {
+	res, err := s.basicCall(ctx, arbos.GetBeneficiaryData(ticketId), common.NewAddressFromEth(arbos.ARB_RETRYABLE_ADDRESS))
+	if err != nil {
+		return common.Address{}, err
+	}
+	if err := checkValidResult(res); err != nil {
+		return common.Address{}, err
+	}
+	return arbos.ParseGetBeneficiaryResult(res.ReturnData)
+}
+
+func (s *Snapshot) AddressTableSize(ctx context.Context) (*big.Int, error) {
+	res, err := s.basicCall(ctx, arbos.AddressTableSizeData(), common.NewAddressFromEth(arbos.ARB_ADDRESS_TABLE_ADDRESS))
+	if err != nil {
+		return nil, err
+	}
+	if err := checkValidResult(res); err != nil {
+		return nil, err
+	}
+	return arbos.ParseAddressTableSizeResult(res.ReturnData)
+}
+
+func (s *Snapshot) AddressTableLookupIndex(ctx context.Context, index *big.Int) (common.Address, error) {
+	res, err := s.basicCall(ctx, arbos.AddressTableLookupIndexData(index), common.NewAddressFromEth(arbos.ARB_ADDRESS_TABLE_ADDRESS))
+	if err != nil {
+		return common.Address{}, err
+	}
+	if err := checkValidResult(res); err != nil {
+		return common.Address{}, err
+	}
+	return arbos.ParseAddressTableLookupIndexResult(res.ReturnData)
+}
+
 func (s *Snapshot) GetTransactionCount(ctx context.Context, account common.Address) (*big.Int, error) {
 	res, err := s.basicCall(ctx, arbos.TransactionCountData(account), common.NewAddressFromEth(arbos.ARB_SYS_ADDRESS))
 	if err != nil {
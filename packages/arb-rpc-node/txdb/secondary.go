@@ -0,0 +1,352 @@
+/*
+ * Copyright 2022, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package txdb
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	ethcore "github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/configuration"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/core"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/machine"
+)
+
+// secondaryHistoryLength is how many published blocks a secondary remembers
+// to find where the primary reorged and which logs were removed. If a reorg
+// goes back further, including to blocks from before the secondary started,
+// the fork point is unknown and every cached block and machine is dropped.
+const secondaryHistoryLength = 256
+
+// NewSecondary creates a read only TxDB on a database opened with
+// core.database.secondary.enable. Instead of reading logs from ArbCore it
+// periodically catches up with the primary process and publishes the blocks
+// the primary saved.
+func NewSecondary(
+	ctx context.Context,
+	arbCore core.ArbCore,
+	storage machine.ArbStorage,
+	nodeConfig *configuration.Node,
+	pollInterval time.Duration,
+) (*TxDB, <-chan error, error) {
+	db, err := newTxDB(arbCore, storage.GetNodeStore(), nodeConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	count, err := db.as.BlockCount()
+	if err != nil {
+		return nil, nil, err
+	}
+	reader := &secondaryReader{
+		db:           db,
+		storage:      storage,
+		pollInterval: pollInterval,
+		count:        count,
+		completed:    make(chan bool, 1),
+	}
+	base, err := reader.baseHash(count)
+	if err != nil {
+		return nil, nil, err
+	}
+	reader.history = &blockHistory{start: count, base: base, limit: secondaryHistoryLength}
+	errChan := reader.Start(ctx)
+	db.secondaryReader = reader
+	return db, errChan, nil
+}
+
+type publishedBlock struct {
+	hash ethcommon.Hash
+	logs []*types.Log
+}
+
+// blockHistory holds the most recent blocks published by a secondary
+type blockHistory struct {
+	start uint64
+	// base is the hash of the block before start, or empty if start is 0
+	base   ethcommon.Hash
+	blocks []publishedBlock
+	limit  int
+}
+
+func (h *blockHistory) next() uint64 {
+	return h.start + uint64(len(h.blocks))
+}
+
+func (h *blockHistory) add(block publishedBlock) {
+	h.blocks = append(h.blocks, block)
+	if len(h.blocks) > h.limit {
+		h.base = h.blocks[0].hash
+		h.blocks = append(h.blocks[:0], h.blocks[1:]...)
+		h.start++
+	}
+}
+
+// reorgHeight returns the first published block that no longer matches the
+// database holding count blocks, or next() if nothing was reorged. found is
+// false if the block before the history changed too, in which case the fork
+// point is unknown and the returned height is where the history can restart.
+func (h *blockHistory) reorgHeight(count uint64, hashAt func(uint64) (ethcommon.Hash, error)) (height uint64, found bool, err error) {
+	height = h.next()
+	if count < height {
+		height = count
+	}
+	for height > h.start {
+		hash, err := hashAt(height - 1)
+		if err != nil {
+			return 0, false, err
+		}
+		if hash == h.blocks[height-1-h.start].hash {
+			return height, true, nil
+		}
+		height--
+	}
+	if height == 0 {
+		return 0, true, nil
+	}
+	if height < h.start {
+		// Blocks before the history were removed
+		return height, false, nil
+	}
+	hash, err := hashAt(height - 1)
+	if err != nil {
+		return 0, false, err
+	}
+	return height, hash == h.base, nil
+}
+
+// truncate forgets blocks from height on and returns them, height must be in
+// the history
+func (h *blockHistory) truncate(height uint64) []publishedBlock {
+	removed := append([]publishedBlock(nil), h.blocks[height-h.start:]...)
+	h.blocks = h.blocks[:height-h.start]
+	return removed
+}
+
+// reset forgets every block and restarts the history at height, the block
+// before which has hash base
+func (h *blockHistory) reset(height uint64, base ethcommon.Hash) []publishedBlock {
+	removed := h.blocks
+	h.blocks = nil
+	h.start = height
+	h.base = base
+	return removed
+}
+
+type secondaryReader struct {
+	db           *TxDB
+	storage      machine.ArbStorage
+	pollInterval time.Duration
+	history      *blockHistory
+
+	// Block count seen by the last update, queries may have cached any of
+	// these blocks even if they were not published yet
+	count uint64
+
+	cancelFunc context.CancelFunc
+	completed  chan bool
+}
+
+func (r *secondaryReader) Start(parentCtx context.Context) <-chan error {
+	errChan := make(chan error, 1)
+	ctx, cancelFunc := context.WithCancel(parentCtx)
+	go func() {
+		defer close(errChan)
+		errChan <- r.follow(ctx)
+		r.completed <- true
+	}()
+	r.cancelFunc = cancelFunc
+	return errChan
+}
+
+func (r *secondaryReader) Stop() {
+	r.cancelFunc()
+	<-r.completed
+}
+
+func (r *secondaryReader) follow(ctx context.Context) error {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+	for {
+		if err := r.storage.CatchUpWithPrimary(); err != nil {
+			logger.Warn().Err(err).Msg("failed to catch up with primary database")
+		} else if err := r.update(); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// update handles any reorg of the primary, then publishes the blocks it added
+// since the last update
+func (r *secondaryReader) update() error {
+	db := r.db
+	count, err := db.as.BlockCount()
+	if err != nil {
+		return err
+	}
+
+	oldHeight := r.history.next()
+	if r.count > oldHeight {
+		oldHeight = r.count
+	}
+	reorgHeight, found, err := r.history.reorgHeight(count, r.blockHash)
+	if err != nil {
+		return err
+	}
+	if !found {
+		if err := r.reset(oldHeight, reorgHeight); err != nil {
+			return err
+		}
+	} else if reorgHeight < oldHeight {
+		r.reorg(oldHeight, reorgHeight)
+	}
+	r.count = count
+
+	for height := r.history.next(); height < count; height++ {
+		published, err := r.publish(height)
+		if err != nil {
+			return err
+		}
+		if !published {
+			// Primary is reorging, try again after catching up
+			break
+		}
+	}
+	return nil
+}
+
+func (r *secondaryReader) blockHash(height uint64) (ethcommon.Hash, error) {
+	info, err := r.db.as.GetBlockInfo(height)
+	if err != nil || info == nil {
+		return ethcommon.Hash{}, err
+	}
+	return info.Header.Hash(), nil
+}
+
+// baseHash returns the hash of the block before height
+func (r *secondaryReader) baseHash(height uint64) (ethcommon.Hash, error) {
+	if height == 0 {
+		return ethcommon.Hash{}, nil
+	}
+	return r.blockHash(height - 1)
+}
+
+func (r *secondaryReader) reorg(oldHeight uint64, reorgHeight uint64) {
+	db := r.db
+	logger.Info().Uint64("oldHeight", oldHeight).Uint64("reorgHeight", reorgHeight).Msg("primary database reorged")
+
+	r.removeLogs(r.history.truncate(reorgHeight))
+	db.reorgCaches(oldHeight, reorgHeight)
+
+	// Machines cached by ArbCore after the last unchanged block are invalid,
+	// drop them all if that block can't be found
+	nextGasUsed := big.NewInt(0)
+	if reorgHeight > 0 {
+		gasUsed, err := db.Lookup.GetGasAtEndOfBlock(reorgHeight - 1)
+		if err != nil {
+			logger.Warn().Err(err).Uint64("block", reorgHeight-1).Msg("clearing machine cache after reorg")
+		} else {
+			nextGasUsed.Add(gasUsed, big.NewInt(1))
+		}
+	}
+	db.Lookup.ReorgMachineCache(nextGasUsed)
+}
+
+// reset handles a reorg past the start of the history. Since the fork point
+// is unknown every cached block and machine is dropped, and publishing
+// restarts at height.
+func (r *secondaryReader) reset(oldHeight uint64, height uint64) error {
+	db := r.db
+	logger.Warn().
+		Uint64("oldHeight", oldHeight).
+		Uint64("historyStart", r.history.start).
+		Uint64("height", height).
+		Msg("primary database reorged past secondary history, clearing all caches")
+
+	base, err := r.baseHash(height)
+	if err != nil {
+		return err
+	}
+	r.removeLogs(r.history.reset(height, base))
+	db.clearCaches()
+	db.Lookup.ReorgMachineCache(big.NewInt(0))
+	return nil
+}
+
+// removeLogs sends the logs of blocks removed by a reorg, newest first
+func (r *secondaryReader) removeLogs(removed []publishedBlock) {
+	db := r.db
+	for i := len(removed) - 1; i >= 0; i-- {
+		logs := removed[i].logs
+		if len(logs) == 0 {
+			continue
+		}
+		oldEthLogs := make([]*types.Log, 0, len(logs))
+		for j := range logs {
+			// Add logs in reverse
+			oldEthLogs = append(oldEthLogs, logs[len(logs)-1-j])
+		}
+		db.rmLogsFeed.Send(ethcore.RemovedLogsEvent{Logs: oldEthLogs})
+	}
+}
+
+// publish sends feed events for a block saved by the primary, returning false
+// if the block changed while being read
+func (r *secondaryReader) publish(height uint64) (bool, error) {
+	db := r.db
+	info, err := db.as.GetBlockInfo(height)
+	if err != nil || info == nil {
+		return false, err
+	}
+	l2Block, txResults, err := db.GetBlockResults(info)
+	if err != nil || l2Block == nil {
+		return false, err
+	}
+
+	processedResults := evm.FilterEthTxResults(txResults)
+	ethTxes := make([]*types.Transaction, 0, len(processedResults))
+	for _, res := range processedResults {
+		ethTxes = append(ethTxes, res.Tx)
+	}
+	block := types.NewBlockWithHeader(info.Header).WithBody(ethTxes, nil)
+	ethLogs := make([]*types.Log, 0)
+	for _, res := range processedResults {
+		ethLogs = append(ethLogs, res.Result.EthLogs(common.NewHashFromEth(block.Hash()))...)
+	}
+
+	if len(ethTxes) > 0 {
+		db.newTxsFeed.Send(ethcore.NewTxsEvent{Txs: ethTxes})
+	}
+	db.chainFeed.Send(ethcore.ChainEvent{Block: block, Hash: block.Hash(), Logs: ethLogs})
+	db.chainHeadFeed.Send(ethcore.ChainEvent{Block: block, Hash: block.Hash(), Logs: ethLogs})
+	if len(ethLogs) > 0 {
+		db.logsFeed.Send(ethLogs)
+	}
+	r.history.add(publishedBlock{hash: block.Hash(), logs: ethLogs})
+	return true, nil
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright 2022, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package txdb
+
+import (
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-util/test"
+)
+
+func TestBlockHistory(t *testing.T) {
+	chain := make(map[uint64]ethcommon.Hash)
+	hashAt := func(height uint64) (ethcommon.Hash, error) {
+		return chain[height], nil
+	}
+	for height := uint64(0); height < 16; height++ {
+		chain[height] = ethcommon.BytesToHash([]byte{byte(height)})
+	}
+	history := &blockHistory{start: 10, base: chain[9], limit: 4}
+	checkReorg := func(count uint64, expected uint64, expectedFound bool) {
+		t.Helper()
+		height, found, err := history.reorgHeight(count, hashAt)
+		test.FailIfError(t, err)
+		if height != expected || found != expectedFound {
+			t.Errorf("expected reorg at %v found %v but got %v found %v", expected, expectedFound, height, found)
+		}
+	}
+
+	for height := uint64(10); height < 16; height++ {
+		history.add(publishedBlock{hash: chain[height]})
+	}
+	if history.start != 12 || history.next() != 16 || history.base != chain[11] {
+		t.Fatalf("history holds blocks %v to %v", history.start, history.next())
+	}
+
+	checkReorg(18, 16, true)
+
+	chain[14] = ethcommon.Hash{1}
+	chain[15] = ethcommon.Hash{2}
+	checkReorg(16, 14, true)
+	checkReorg(13, 13, true)
+	if removed := history.truncate(13); len(removed) != 3 || history.next() != 13 {
+		t.Errorf("removed %v blocks leaving %v", len(removed), history.next())
+	}
+
+	// Reorg of every remembered block that left the block before unchanged
+	chain[12] = ethcommon.Hash{3}
+	checkReorg(16, 12, true)
+
+	// Reorg past the start of the history
+	chain[11] = ethcommon.Hash{4}
+	checkReorg(16, 12, false)
+	checkReorg(5, 5, false)
+	if removed := history.reset(5, chain[4]); len(removed) != 1 || history.start != 5 || history.next() != 5 {
+		t.Errorf("removed %v blocks leaving %v to %v", len(removed), history.start, history.next())
+	}
+	checkReorg(8, 5, true)
+	chain[4] = ethcommon.Hash{5}
+	checkReorg(8, 5, false)
+	checkReorg(0, 0, true)
+}
@@ -51,6 +51,7 @@ type TxDB struct {
 	allowSlowLookup bool
 	as              machine.NodeStore
 	logReader       *core.LogReader
+	secondaryReader *secondaryReader
 
 	newTxsFeed      event.Feed
 	rmLogsFeed      event.Feed
@@ -72,42 +73,54 @@ func New(
 	as machine.NodeStore,
 	nodeConfig *configuration.Node,
 ) (*TxDB, <-chan error, error) {
+	db, err := newTxDB(arbCore, as, nodeConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	logReader := core.NewLogReader(db, arbCore, big.NewInt(0), big.NewInt(int64(nodeConfig.LogProcessCount)), nodeConfig.LogIdleSleep)
+	errChan := logReader.Start(ctx)
+	db.logReader = logReader
+	return db, errChan, nil
+}
+
+func newTxDB(arbCore core.ArbCoreLookup, as machine.NodeStore, nodeConfig *configuration.Node) (*TxDB, error) {
 	var snapshotLRUCache *lru.Cache
 	var blockInfoLRUCache *lru.Cache
 	if nodeConfig.Cache.LRUSize > 0 {
 		var err error
 		snapshotLRUCache, err = lru.New(nodeConfig.Cache.LRUSize)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 	}
 	if nodeConfig.Cache.BlockInfoLRUSize > 0 {
 		var err error
 		blockInfoLRUCache, err = lru.New(nodeConfig.Cache.BlockInfoLRUSize)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 	}
 	snapshotTimedCache, err := blockcache.New(nodeConfig.Cache.TimedInitialSize, nodeConfig.Cache.TimedExpire)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	db := &TxDB{
+	return &TxDB{
 		Lookup:             arbCore,
 		as:                 as,
 		snapshotLRUCache:   snapshotLRUCache,
 		blockInfoLRUCache:  blockInfoLRUCache,
 		snapshotTimedCache: snapshotTimedCache,
 		allowSlowLookup:    nodeConfig.Cache.AllowSlowLookup,
-	}
-	logReader := core.NewLogReader(db, arbCore, big.NewInt(0), big.NewInt(int64(nodeConfig.LogProcessCount)), nodeConfig.LogIdleSleep)
-	errChan := logReader.Start(ctx)
-	db.logReader = logReader
-	return db, errChan, nil
+	}, nil
 }
 
 func (db *TxDB) Close() {
-	db.logReader.Stop()
+	if db.logReader != nil {
+		db.logReader.Stop()
+	}
+	if db.secondaryReader != nil {
+		db.secondaryReader.Stop()
+	}
 }
 
 func (db *TxDB) GetBlockResults(block *machine.BlockInfo) (*evm.BlockInfo, []*evm.TxResult, error) {
@@ -270,23 +283,39 @@ func (db *TxDB) DeleteLogs(avmLogs []core.ValueAndInbox) error {
 		if err != nil {
 			return err
 		}
+		db.reorgCaches(oldHeight, reorgBlockHeight)
+	}
 
-		if db.snapshotLRUCache != nil {
-			for i := oldHeight; i > reorgBlockHeight; i-- {
-				db.snapshotLRUCache.Remove(i)
-			}
-			db.snapshotLRUCache.Remove(reorgBlockHeight)
+	return nil
+}
+
+// reorgCaches removes cached blocks and snapshots from reorgBlockHeight up to
+// and including oldHeight
+func (db *TxDB) reorgCaches(oldHeight uint64, reorgBlockHeight uint64) {
+	if db.snapshotLRUCache != nil {
+		for i := oldHeight; i > reorgBlockHeight; i-- {
+			db.snapshotLRUCache.Remove(i)
 		}
-		if db.blockInfoLRUCache != nil {
-			for i := oldHeight; i > reorgBlockHeight; i-- {
-				db.blockInfoLRUCache.Remove(i)
-			}
-			db.blockInfoLRUCache.Remove(reorgBlockHeight)
+		db.snapshotLRUCache.Remove(reorgBlockHeight)
+	}
+	if db.blockInfoLRUCache != nil {
+		for i := oldHeight; i > reorgBlockHeight; i-- {
+			db.blockInfoLRUCache.Remove(i)
 		}
-		db.snapshotTimedCache.Reorg(reorgBlockHeight)
+		db.blockInfoLRUCache.Remove(reorgBlockHeight)
 	}
+	db.snapshotTimedCache.Reorg(reorgBlockHeight)
+}
 
-	return nil
+// clearCaches removes every cached block and snapshot
+func (db *TxDB) clearCaches() {
+	if db.snapshotLRUCache != nil {
+		db.snapshotLRUCache.Purge()
+	}
+	if db.blockInfoLRUCache != nil {
+		db.blockInfoLRUCache.Purge()
+	}
+	db.snapshotTimedCache.Reorg(0)
 }
 
 func (db *TxDB) handleBlockReceipt(blockInfo *evm.BlockInfo) (*types.Header, error) {
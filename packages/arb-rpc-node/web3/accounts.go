@@ -30,6 +30,9 @@ import (
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/ethersphere/bee/pkg/crypto/eip712"
 	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-rpc-node/batcher"
+	arbcommon "github.com/offchainlabs/arbitrum/packages/arb-util/common"
 )
 
 type Accounts struct {
@@ -82,8 +85,12 @@ func (s *Accounts) SendTransaction(ctx context.Context, args *SendTransactionArg
 		sender = *args.From
 	}
 	privKey, ok := s.privateKeys[sender]
+	var impersonator batcher.Impersonator
 	if !ok {
-		return common.Hash{}, errors.New("sender does not have unlocked wallet")
+		impersonator = s.srv.srv.Impersonator()
+		if impersonator == nil || !impersonator.IsImpersonating(arbcommon.NewAddressFromEth(sender)) {
+			return common.Hash{}, errors.New("sender does not have unlocked wallet")
+		}
 	}
 
 	var nonce uint64
@@ -137,6 +144,10 @@ func (s *Accounts) SendTransaction(ctx context.Context, args *SendTransactionArg
 			data,
 		)
 	}
+	if impersonator != nil {
+		txHash, err := impersonator.SendImpersonatedTransaction(ctx, arbcommon.NewAddressFromEth(sender), tx)
+		return txHash.ToEthHash(), err
+	}
 	signedTx, err := types.SignTx(tx, s.signer, privKey)
 	if err != nil {
 		return [32]byte{}, err
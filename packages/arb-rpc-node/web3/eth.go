@@ -54,6 +54,7 @@ const maxGas = 1<<31 - 1
 type Server struct {
 	srv                   *aggregator.Server
 	ganacheMode           bool
+	receiptGasBreakdown   bool
 	maxAVMGas             uint64
 	aggregator            *arbcommon.Address
 	sequencerInboxWatcher *ethbridge.SequencerInboxWatcher
@@ -83,6 +84,7 @@ func NewServer(
 	return &Server{
 		srv:                   srv,
 		ganacheMode:           config.Mode == configuration.GanacheRpcMode,
+		receiptGasBreakdown:   config.ReceiptGasBreakdown,
 		maxAVMGas:             maxGas,
 		aggregator:            srv.Aggregator(),
 		sequencerInboxWatcher: sequencerInboxWatcher,
@@ -440,6 +442,14 @@ func (s *Server) GetTransactionReceipt(ctx context.Context, txHash hexutil.Bytes
 		}
 	}
 
+	var gasBreakdown *GasBreakdownResult
+	if s.receiptGasBreakdown {
+		gasBreakdown, err = s.gasBreakdown(res, info)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &GetTransactionReceiptResult{
 		TransactionHash:   receipt.TxHash,
 		TransactionIndex:  hexutil.Uint64(receipt.TransactionIndex),
@@ -464,6 +474,7 @@ func (s *Server) GetTransactionReceipt(ctx context.Context, txHash hexutil.Bytes
 		},
 		L1BlockNumber:    (*hexutil.Big)(res.IncomingRequest.L1BlockNumber),
 		L1InboxBatchInfo: l1InboxBatchInfo,
+		GasBreakdown:     gasBreakdown,
 	}, nil
 }
 
@@ -0,0 +1,87 @@
+/*
+ * Copyright 2021, Offchain Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package web3
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/pkg/errors"
+
+	"github.com/offchainlabs/arbitrum/packages/arb-evm/evm"
+	"github.com/offchainlabs/arbitrum/packages/arb-util/machine"
+)
+
+// GasBreakdown serves the dev namespace call explaining what a transaction
+// paid for
+type GasBreakdown struct {
+	s *Server
+}
+
+// GetTransactionGasBreakdown returns an error for transactions that ran
+// while fees were disabled, as every component would be priced at zero
+func (g *GasBreakdown) GetTransactionGasBreakdown(txHash hexutil.Bytes) (*GasBreakdownResult, error) {
+	res, info, _, _, err := g.s.getTransactionInfoByHash(txHash)
+	if err != nil || res == nil {
+		return nil, err
+	}
+	if res.FeeStats.Price.Total().Sign() == 0 {
+		return nil, errors.New("transaction ran without fees, so it paid nothing")
+	}
+	return g.s.gasBreakdown(res, info)
+}
+
+func newGasComponent(fees *evm.FeeStats, get func(*evm.FeeSet) *big.Int) *GasComponentResult {
+	return &GasComponentResult{
+		Units: (*hexutil.Big)(get(fees.UnitsUsed)),
+		Price: (*hexutil.Big)(get(fees.Price)),
+		Paid:  (*hexutil.Big)(get(fees.Paid)),
+	}
+}
+
+func (s *Server) gasBreakdown(res *evm.TxResult, info *machine.BlockInfo) (*GasBreakdownResult, error) {
+	blockLog, err := s.srv.BlockLogFromInfo(info)
+	if err != nil {
+		return nil, err
+	}
+	fees := res.FeeStats
+	var aggregator *common.Address
+	if fees.Aggregator != nil {
+		agg := fees.Aggregator.ToEthAddress()
+		aggregator = &agg
+	}
+	summary := blockLog.GasSummary
+	return &GasBreakdownResult{
+		L1Transaction: newGasComponent(fees, func(fs *evm.FeeSet) *big.Int { return fs.L1Transaction }),
+		L1Calldata:    newGasComponent(fees, func(fs *evm.FeeSet) *big.Int { return fs.L1Calldata }),
+		L2Storage:     newGasComponent(fees, func(fs *evm.FeeSet) *big.Int { return fs.L2Storage }),
+		L2Computation: newGasComponent(fees, func(fs *evm.FeeSet) *big.Int { return fs.L2Computation }),
+		TotalPaid:     (*hexutil.Big)(fees.Paid.Total()),
+		GasUsed:       (*hexutil.Big)(res.CalcGasUsed()),
+		GasUsedForL1:  (*hexutil.Big)(res.CalcGasUsedForL1()),
+		ArbGasUsed:    (*hexutil.Big)(res.GasUsed),
+		Aggregator:    aggregator,
+		BlockPrices: &BlockGasPricesResult{
+			PricePerL1CalldataByte:   (*hexutil.Big)(summary.PricePerL1CalldataByte),
+			PricePerStorageCell:      (*hexutil.Big)(summary.PricePerStorageCell),
+			PricePerArbGasBase:       (*hexutil.Big)(summary.PricePerArbGasBase),
+			PricePerArbGasCongestion: (*hexutil.Big)(summary.PricePerArbGasCongestion),
+			PricePerArbGasTotal:      (*hexutil.Big)(summary.PricePerArbGasTotal),
+		},
+	}, nil
+}
@@ -70,6 +70,37 @@ type FeeStatsResult struct {
 	Paid      *FeeSetResult `json:"paid"`
 }
 
+type GasComponentResult struct {
+	Units *hexutil.Big `json:"units"`
+	Price *hexutil.Big `json:"price"`
+	Paid  *hexutil.Big `json:"paid"`
+}
+
+type BlockGasPricesResult struct {
+	PricePerL1CalldataByte   *hexutil.Big `json:"pricePerL1CalldataByte"`
+	PricePerStorageCell      *hexutil.Big `json:"pricePerStorageCell"`
+	PricePerArbGasBase       *hexutil.Big `json:"pricePerArbGasBase"`
+	PricePerArbGasCongestion *hexutil.Big `json:"pricePerArbGasCongestion"`
+	PricePerArbGasTotal      *hexutil.Big `json:"pricePerArbGasTotal"`
+}
+
+// GasBreakdownResult splits what a transaction paid into the components
+// ArbOS charges for. GasUsed is the total expressed in L2 gas at the
+// computation price, GasUsedForL1 the part of it spent on L1 costs and
+// ArbGasUsed the computation actually executed.
+type GasBreakdownResult struct {
+	L1Transaction *GasComponentResult   `json:"l1Transaction"`
+	L1Calldata    *GasComponentResult   `json:"l1Calldata"`
+	L2Storage     *GasComponentResult   `json:"l2Storage"`
+	L2Computation *GasComponentResult   `json:"l2Computation"`
+	TotalPaid     *hexutil.Big          `json:"totalPaid"`
+	GasUsed       *hexutil.Big          `json:"gasUsed"`
+	GasUsedForL1  *hexutil.Big          `json:"gasUsedForL1"`
+	ArbGasUsed    *hexutil.Big          `json:"arbGasUsed"`
+	Aggregator    *common.Address       `json:"aggregator"`
+	BlockPrices   *BlockGasPricesResult `json:"blockPrices"`
+}
+
 type L1InboxBatchInfo struct {
 	Confirmations *hexutil.Big   `json:"confirmations"`
 	BlockNumber   *hexutil.Big   `json:"blockNumber"`
@@ -95,11 +126,12 @@ type GetTransactionReceiptResult struct {
 	Status            hexutil.Uint64  `json:"status"`
 
 	// Arbitrum Specific Fields
-	ReturnCode       hexutil.Uint64    `json:"returnCode"`
-	ReturnData       hexutil.Bytes     `json:"returnData"`
-	FeeStats         *FeeStatsResult   `json:"feeStats"`
-	L1BlockNumber    *hexutil.Big      `json:"l1BlockNumber"`
-	L1InboxBatchInfo *L1InboxBatchInfo `json:"l1InboxBatchInfo"`
+	ReturnCode       hexutil.Uint64      `json:"returnCode"`
+	ReturnData       hexutil.Bytes       `json:"returnData"`
+	FeeStats         *FeeStatsResult     `json:"feeStats"`
+	L1BlockNumber    *hexutil.Big        `json:"l1BlockNumber"`
+	L1InboxBatchInfo *L1InboxBatchInfo   `json:"l1InboxBatchInfo"`
+	GasBreakdown     *GasBreakdownResult `json:"gasBreakdown,omitempty"`
 }
 
 type ArbGetTxReceiptOpts struct {
@@ -39,6 +39,9 @@ type ServerConfig struct {
 	MaxCallAVMGas uint64
 	Tracing       configuration.Tracing
 	DevopsStubs   bool
+
+	// ReceiptGasBreakdown adds the per-component fee breakdown to receipts
+	ReceiptGasBreakdown bool
 }
 
 func GenerateWeb3Server(server *aggregator.Server, privateKeys []*ecdsa.PrivateKey, config ServerConfig, coreConfig *configuration.Core, plugins map[string]interface{}, inboxReader *monitor.InboxReader) (*rpc.Server, error) {
@@ -73,6 +76,12 @@ func GenerateWeb3Server(server *aggregator.Server, privateKeys []*ecdsa.PrivateK
 			return nil, err
 		}
 
+		if config.Mode == configuration.GanacheRpcMode {
+			if err := s.RegisterName("dev", &GasBreakdown{s: ethServer}); err != nil {
+				return nil, err
+			}
+		}
+
 		if config.Tracing.Enable {
 			tracer := NewTracer(ethServer, coreConfig)
 			if err := s.RegisterName(config.Tracing.Namespace, tracer); err != nil {
@@ -57,15 +57,29 @@ type Conf struct {
 	String    string `koanf:"string"`
 }
 
+type DatabaseBackup struct {
+	Enable      bool `koanf:"enable"`
+	Generations int  `koanf:"generations"`
+	Tarball     bool `koanf:"tarball"`
+}
+
+type DatabaseSecondary struct {
+	Enable       bool          `koanf:"enable"`
+	Path         string        `koanf:"path"`
+	PollInterval time.Duration `koanf:"poll-interval"`
+}
+
 type Database struct {
-	Compact       bool          `koanf:"compact"`
-	ExitAfter     bool          `koanf:"exit-after"`
-	Metadata      bool          `koanf:"metadata"`
-	L0Files       int           `koanf:"l0-files"`
-	SaveInterval  time.Duration `koanf:"save-interval"`
-	SaveOnStartup bool          `koanf:"save-on-startup"`
-	SavePath      string        `koanf:"save-path"`
-	Threads       int           `koanf:"threads"`
+	Backup        DatabaseBackup    `koanf:"backup"`
+	Compact       bool              `koanf:"compact"`
+	ExitAfter     bool              `koanf:"exit-after"`
+	Metadata      bool              `koanf:"metadata"`
+	L0Files       int               `koanf:"l0-files"`
+	SaveInterval  time.Duration     `koanf:"save-interval"`
+	SaveOnStartup bool              `koanf:"save-on-startup"`
+	SavePath      string            `koanf:"save-path"`
+	Secondary     DatabaseSecondary `koanf:"secondary"`
+	Threads       int               `koanf:"threads"`
 }
 
 type Core struct {
@@ -319,6 +333,47 @@ type NodeCache struct {
 	TimedExpire      time.Duration `koanf:"timed-expire"`
 }
 
+type Audit struct {
+	Filename string        `koanf:"filename"`
+	Interval time.Duration `koanf:"interval"`
+	Once     bool          `koanf:"once"`
+}
+
+type Query struct {
+	Enable bool `koanf:"enable"`
+	JSON   bool `koanf:"json"`
+}
+
+type Migrate struct {
+	Enable bool `koanf:"enable"`
+	DryRun bool `koanf:"dry-run"`
+}
+
+type Checkpoints struct {
+	List      bool   `koanf:"list"`
+	Estimate  bool   `koanf:"estimate"`
+	Block     uint64 `koanf:"block"`
+	Prune     bool   `koanf:"prune"`
+	FromBlock uint64 `koanf:"from-block"`
+	ToBlock   uint64 `koanf:"to-block"`
+	MaxGap    int    `koanf:"max-gap"`
+	DryRun    bool   `koanf:"dry-run"`
+}
+
+type Restore struct {
+	Backup string `koanf:"backup"`
+}
+
+type InboxArchive struct {
+	Export string `koanf:"export"`
+	Import string `koanf:"import"`
+}
+
+type Verify struct {
+	Enable            bool `koanf:"enable"`
+	CheckpointSamples int  `koanf:"checkpoint-samples"`
+}
+
 type Persistent struct {
 	Chain        string `koanf:"chain"`
 	GlobalConfig string `koanf:"global-config"`
@@ -335,16 +390,50 @@ type Rollup struct {
 }
 
 type Validator struct {
+	StrategyImpl                  string                `koanf:"strategy"`
+	UtilsAddress                  string                `koanf:"utils-address"`
+	StakerDelay                   time.Duration         `koanf:"staker-delay"`
+	WalletFactoryAddress          string                `koanf:"wallet-factory-address"`
+	L1PostingStrategy             L1PostingStrategy     `koanf:"l1-posting-strategy"`
+	DontChallenge                 bool                  `koanf:"dont-challenge"`
+	WithdrawDestination           string                `koanf:"withdraw-destination"`
+	OnlyCreateWalletContract      bool                  `koanf:"only-create-wallet-contract"`
+	ContractWalletAddress         string                `koanf:"contract-wallet-address"`
+	ContractWalletAddressFilename string                `koanf:"contract-wallet-address-filename"`
+	ChallengeMovesFilename        string                `koanf:"challenge-moves-filename"`
+	Alerts                        ValidatorAlerts       `koanf:"alerts"`
+	Stake                         ValidatorStake        `koanf:"stake"`
+	Additional                    []AdditionalValidator `koanf:"additional"`
+}
+
+// AdditionalValidator is an extra staking identity run by the same node,
+// configured through the config file. Unset fields use the main validator settings.
+type AdditionalValidator struct {
 	StrategyImpl                  string            `koanf:"strategy"`
-	UtilsAddress                  string            `koanf:"utils-address"`
-	StakerDelay                   time.Duration     `koanf:"staker-delay"`
-	WalletFactoryAddress          string            `koanf:"wallet-factory-address"`
 	L1PostingStrategy             L1PostingStrategy `koanf:"l1-posting-strategy"`
 	DontChallenge                 bool              `koanf:"dont-challenge"`
 	WithdrawDestination           string            `koanf:"withdraw-destination"`
-	OnlyCreateWalletContract      bool              `koanf:"only-create-wallet-contract"`
 	ContractWalletAddress         string            `koanf:"contract-wallet-address"`
 	ContractWalletAddressFilename string            `koanf:"contract-wallet-address-filename"`
+	Wallet                        WalletLocal       `koanf:"wallet"`
+}
+
+type ValidatorAlerts struct {
+	Webhooks                []string      `koanf:"webhook"`
+	File                    string        `koanf:"file"`
+	Timeout                 time.Duration `koanf:"timeout"`
+	LowBalance              float64       `koanf:"low-balance"`
+	ChallengeDeadlineBlocks int64         `koanf:"challenge-deadline-blocks"`
+}
+
+// ValidatorStake controls automatic management of the validator's deposit.
+// Multipliers are relative to the larger of the current required stake and
+// the base stake.
+type ValidatorStake struct {
+	Manage           bool          `koanf:"manage"`
+	TargetMultiplier float64       `koanf:"target-multiplier"`
+	ReduceMultiplier float64       `koanf:"reduce-multiplier"`
+	WithdrawInterval time.Duration `koanf:"withdraw-interval"`
 }
 
 type ValidatorStrategy uint8
@@ -365,6 +454,26 @@ func (s ValidatorStrategy) IsActive() bool {
 	return false
 }
 
+// ForAdditional returns the configuration of the given additional validator,
+// using the main validator settings for anything it doesn't override
+func (c *Validator) ForAdditional(additional AdditionalValidator) Validator {
+	out := *c
+	out.Additional = nil
+	out.ContractWalletAddress = additional.ContractWalletAddress
+	out.ContractWalletAddressFilename = additional.ContractWalletAddressFilename
+	out.DontChallenge = additional.DontChallenge
+	if len(additional.StrategyImpl) != 0 {
+		out.StrategyImpl = additional.StrategyImpl
+	}
+	if additional.L1PostingStrategy != (L1PostingStrategy{}) {
+		out.L1PostingStrategy = additional.L1PostingStrategy
+	}
+	if len(additional.WithdrawDestination) != 0 {
+		out.WithdrawDestination = additional.WithdrawDestination
+	}
+	return out
+}
+
 func (c *Validator) Strategy() ValidatorStrategy {
 	if strings.EqualFold(c.StrategyImpl, "Watchtower") {
 		return WatchtowerStrategy
@@ -438,12 +547,15 @@ type Metrics struct {
 }
 
 type Config struct {
-	BridgeUtilsAddress string      `koanf:"bridge-utils-address"`
-	Conf               Conf        `koanf:"conf"`
-	Core               Core        `koanf:"core"`
-	Feed               Feed        `koanf:"feed"`
-	GasPrice           float64     `koanf:"gas-price"`
-	Healthcheck        Healthcheck `koanf:"healthcheck"`
+	Audit              Audit        `koanf:"audit"`
+	BridgeUtilsAddress string       `koanf:"bridge-utils-address"`
+	Checkpoints        Checkpoints  `koanf:"checkpoints"`
+	Conf               Conf         `koanf:"conf"`
+	Core               Core         `koanf:"core"`
+	Feed               Feed         `koanf:"feed"`
+	GasPrice           float64      `koanf:"gas-price"`
+	Healthcheck        Healthcheck  `koanf:"healthcheck"`
+	InboxArchive       InboxArchive `koanf:"inbox-archive"`
 	L1                 struct {
 		ChainID uint64 `koanf:"chain-id"`
 		URL     string `koanf:"url"`
@@ -452,11 +564,15 @@ type Config struct {
 		DisableUpstream bool `koanf:"disable-upstream"`
 	} `koanf:"l2"`
 	Log           Log        `koanf:"log"`
+	Migrate       Migrate    `koanf:"migrate"`
 	Node          Node       `koanf:"node"`
 	Persistent    Persistent `koanf:"persistent"`
 	PProfEnable   bool       `koanf:"pprof-enable"`
+	Query         Query      `koanf:"query"`
+	Restore       Restore    `koanf:"restore"`
 	Rollup        Rollup     `koanf:"rollup"`
 	Validator     Validator  `koanf:"validator"`
+	Verify        Verify     `koanf:"verify"`
 	WaitToCatchUp bool       `koanf:"wait-to-catch-up"`
 	Wallet        Wallet     `koanf:"wallet"`
 
@@ -544,6 +660,30 @@ func ParseDBTool() (*Config, error) {
 	AddPersistent(f)
 	AddCore(f, 0)
 
+	f.Bool("verify.enable", false, "check the database for corruption instead of applying core settings")
+	f.Int("verify.checkpoint-samples", 10, "number of checkpoints to check by re-executing to the next checkpoint")
+
+	f.String("inbox-archive.export", "", "write the inbox to this archive file and exit")
+	f.String("inbox-archive.import", "", "rebuild the database inbox from this archive file and exit")
+	f.String("rollup.machine.filename", "", "file to load machine from when importing into a new database")
+
+	f.Bool("query.enable", false, "read query commands from stdin, enter help to list them")
+	f.Bool("query.json", false, "print each query result as a single line of JSON")
+
+	f.String("restore.backup", "", "validate this backup directory or tarball and replace the database with it")
+
+	f.Bool("migrate.enable", false, "upgrade the database to the newest schema version")
+	f.Bool("migrate.dry-run", false, "print the migrations that would be applied without changing the database")
+
+	f.Bool("checkpoints.list", false, "print every checkpoint with a summary of their spacing and size")
+	f.Bool("checkpoints.estimate", false, "estimate the cost of a query at the end of checkpoints.block using core.checkpoint-load-gas-cost")
+	f.Uint64("checkpoints.block", 0, "L2 block to estimate query cost for")
+	f.Bool("checkpoints.prune", false, "delete checkpoints from checkpoints.from-block up to checkpoints.to-block")
+	f.Uint64("checkpoints.from-block", 0, "first L2 block of checkpoints to prune")
+	f.Uint64("checkpoints.to-block", 0, "L2 block to stop pruning checkpoints before, 0 for the latest")
+	f.Int("checkpoints.max-gap", 0, "most gas allowed between remaining checkpoints when pruning, 0 to use core.checkpoint-max-execution-gas")
+	f.Bool("checkpoints.dry-run", false, "print the checkpoints that would be pruned without deleting them")
+
 	k, err := beginCommonParse(f)
 	if err != nil {
 		return nil, err
@@ -563,6 +703,25 @@ func AddL1PostingStrategyOptions(f *flag.FlagSet, prefix string) {
 	f.Int64(prefix+"l1-posting-strategy.high-gas-delay-blocks", 270, "wait up to this many more blocks when gas costs are high")
 }
 
+func AddInboxReaderOptions(f *flag.FlagSet) {
+	f.Int64("node.inbox-reader.delay-blocks", 4, "number of L1 blocks to wait for confirmation before updating L2 state")
+	f.Bool("node.inbox-reader.paranoid", false, "if enabled, check for reorgs before searching for messages")
+	f.Duration("node.inbox-reader.sequencer-signature-expiry", 10*time.Minute, "length of time between verifying sequencer feed signing address on-chain")
+}
+
+func ParseAudit(ctx context.Context) (*Config, *ethutils.RPCEthClient, error) {
+	f := flag.NewFlagSet("", flag.ContinueOnError)
+
+	AddInboxReaderOptions(f)
+
+	f.String("audit.filename", "audit.json", "file the audit is saved to and resumed from")
+	f.Duration("audit.interval", time.Minute, "delay between audit updates")
+	f.Bool("audit.once", false, "update the audit once then exit")
+
+	config, _, l1Client, _, err := ParseNonRelay(ctx, f, "audit-wallet", 0)
+	return config, l1Client, err
+}
+
 func ParseNode(ctx context.Context) (*Config, *Wallet, *ethutils.RPCEthClient, *big.Int, error) {
 	f := flag.NewFlagSet("", flag.ContinueOnError)
 
@@ -580,6 +739,16 @@ func ParseNode(ctx context.Context) (*Config, *Wallet, *ethutils.RPCEthClient, *
 	f.String("validator.wallet-factory-address", "", "strategy for validator to use")
 	f.Bool("validator.dont-challenge", false, "don't challenge any other validators' assertions")
 	f.String("validator.withdraw-destination", "", "the address to withdraw funds to (defaults to the wallet address)")
+	f.String("validator.challenge-moves-filename", "challengeMoves.json", "json lines file that challenge moves made by the validator are recorded in")
+	f.StringSlice("validator.alerts.webhook", []string{}, "URL to POST JSON validator alerts to")
+	f.String("validator.alerts.file", "", "file to append JSON validator alerts to")
+	f.Duration("validator.alerts.timeout", 10*time.Second, "timeout for delivering an alert to a webhook")
+	f.Float64("validator.alerts.low-balance", 0, "alert when the validator wallet balance drops below this many ETH (0 to disable)")
+	f.Int64("validator.alerts.challenge-deadline-blocks", 1000, "alert when it's our turn in a challenge and fewer than this many blocks remain")
+	f.Bool("validator.stake.manage", false, "top up and reduce the validator deposit to track the required stake")
+	f.Float64("validator.stake.target-multiplier", 1, "deposit to keep relative to the required stake")
+	f.Float64("validator.stake.reduce-multiplier", 1.5, "reduce the deposit back to target once it exceeds this multiple of the required stake")
+	f.Duration("validator.stake.withdraw-interval", 0, "minimum time between withdrawals of funds to the withdraw destination (0 to withdraw whenever possible)")
 
 	f.String("node.aggregator.inbox-address", "", "address of the inbox contract")
 	f.Int("node.aggregator.max-batch-time", 10, "max-batch-time=NumSeconds")
@@ -595,9 +764,7 @@ func ParseNode(ctx context.Context) (*Config, *Wallet, *ethutils.RPCEthClient, *
 	f.String("node.forwarder.submitter-address", "", "address of the node that will submit your transaction to the chain")
 	f.String("node.forwarder.rpc-mode", "full", "RPC mode: either full, non-mutating (no eth_sendRawTransaction), or forwarding-only (only requests forwarded upstream are permitted)")
 
-	f.Int64("node.inbox-reader.delay-blocks", 4, "number of L1 blocks to wait for confirmation before updating L2 state")
-	f.Bool("node.inbox-reader.paranoid", false, "if enabled, check for reorgs before searching for messages")
-	f.Duration("node.inbox-reader.sequencer-signature-expiry", 10*time.Minute, "length of time between verifying sequencer feed signing address on-chain")
+	AddInboxReaderOptions(f)
 
 	f.Duration("node.log-idle-sleep", 100*time.Millisecond, "milliseconds for log reader to sleep between reading logs")
 	f.Int("node.log-process-count", 100, "maximum number of logs to process at a time")
@@ -854,6 +1021,19 @@ func resolveDirectoryNames(out *Config, wallet *Wallet) error {
 		out.Core.Database.SavePath = path.Join(out.Persistent.Chain, out.Core.Database.SavePath)
 	}
 
+	// Each secondary database process needs its own directory for rocksdb metadata
+	if out.Core.Database.Secondary.Enable {
+		if out.Core.Database.Secondary.Path == "" {
+			return errors.New("Secondary database needs --core.database.secondary.path")
+		}
+		if !filepath.IsAbs(out.Core.Database.Secondary.Path) {
+			out.Core.Database.Secondary.Path = path.Join(out.Persistent.Chain, out.Core.Database.Secondary.Path)
+		}
+		if err := os.MkdirAll(out.Core.Database.Secondary.Path, os.ModePerm); err != nil {
+			return errors.Wrap(err, "Unable to create secondary database directory")
+		}
+	}
+
 	if len(out.Rollup.Machine.Filename) == 0 {
 		// Machine not provided, so use default chain specific machine
 		out.Rollup.Machine.Filename = path.Join(out.Persistent.Chain, "arbos.mexe")
@@ -877,6 +1057,40 @@ func resolveDirectoryNames(out *Config, wallet *Wallet) error {
 		out.Validator.ContractWalletAddressFilename = path.Join(out.Persistent.Chain, out.Validator.ContractWalletAddressFilename)
 	}
 
+	// Make audit file relative to chain directory if not already absolute
+	if len(out.Audit.Filename) != 0 && !filepath.IsAbs(out.Audit.Filename) {
+		out.Audit.Filename = path.Join(out.Persistent.Chain, out.Audit.Filename)
+	}
+
+	// Make validator challenge moves file relative to chain directory if not already absolute
+	if !filepath.IsAbs(out.Validator.ChallengeMovesFilename) {
+		out.Validator.ChallengeMovesFilename = path.Join(out.Persistent.Chain, out.Validator.ChallengeMovesFilename)
+	}
+
+	for i := range out.Validator.Additional {
+		additional := &out.Validator.Additional[i]
+		if len(additional.ContractWalletAddressFilename) == 0 {
+			additional.ContractWalletAddressFilename = fmt.Sprintf("chainState-%d.json", i+1)
+		}
+		if !filepath.IsAbs(additional.ContractWalletAddressFilename) {
+			additional.ContractWalletAddressFilename = path.Join(out.Persistent.Chain, additional.ContractWalletAddressFilename)
+		}
+		if len(additional.Wallet.Pathname) == 0 {
+			additional.Wallet.Pathname = fmt.Sprintf("validator-wallet-%d", i+1)
+		}
+		if !filepath.IsAbs(additional.Wallet.Pathname) {
+			additional.Wallet.Pathname = path.Join(out.Persistent.Chain, additional.Wallet.Pathname)
+		}
+		if len(additional.Wallet.PasswordImpl) == 0 {
+			additional.Wallet.PasswordImpl = PASSWORD_NOT_SET
+		}
+	}
+
+	// Make validator alerts file relative to chain directory if not already absolute
+	if len(out.Validator.Alerts.File) != 0 && !filepath.IsAbs(out.Validator.Alerts.File) {
+		out.Validator.Alerts.File = path.Join(out.Persistent.Chain, out.Validator.Alerts.File)
+	}
+
 	return nil
 }
 
@@ -934,12 +1148,18 @@ func AddCore(f *flag.FlagSet, maxExecutionGas int) {
 	f.Bool("core.checkpoint-prune-on-startup", false, "perform full database pruning on startup")
 	f.String("core.checkpoint-pruning-mode", "default", "Prune old checkpoints: 'on', 'off', or 'default'")
 
+	f.Bool("core.database.backup.enable", false, "write manifests for database backups in save-path and apply retention")
+	f.Int("core.database.backup.generations", 3, "number of database backups to keep, 0 to keep all")
+	f.Bool("core.database.backup.tarball", false, "pack database backups into tarballs")
 	f.Bool("core.database.compact", false, "perform database compaction")
 	f.Bool("core.database.exit-after", false, "exit after loading or manipulating database")
 	f.Bool("core.database.metadata", false, "just print database metadata and exit")
 	f.Duration("core.database.save-interval", 0, "duration between saving database backups, 0 to disable")
 	f.Bool("core.database.save-on-startup", false, "save database backup on start")
 	f.String("core.database.save-path", "db_checkpoints", "path to save database backups in")
+	f.Bool("core.database.secondary.enable", false, "open database read only as a secondary of the node writing to it")
+	f.String("core.database.secondary.path", "", "directory unique to this process for secondary database metadata")
+	f.Duration("core.database.secondary.poll-interval", time.Second, "how often a secondary catches up with the primary database")
 
 	f.Bool("core.debug", false, "print extra debug messages in arbcore")
 	f.Bool("core.debug-timing", false, "print extra debug timing messages in arbcore")
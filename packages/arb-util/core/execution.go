@@ -6,6 +6,7 @@ import (
 
 	"github.com/pkg/errors"
 
+	"github.com/offchainlabs/arbitrum/packages/arb-util/common"
 	"github.com/offchainlabs/arbitrum/packages/arb-util/machine"
 )
 
@@ -173,3 +174,25 @@ func IsAssertionValid(assertion *Assertion, execTracker *ExecutionTracker, targe
 	}
 	return assertion.After.CutHash() == localExecutionState.CutHash(), nil
 }
+
+// IsNodeValid checks a node's assertion against our local execution, using
+// the inbox accumulator at the point the assertion finished reading messages
+func IsNodeValid(node *NodeInfo, execTracker *ExecutionTracker) (bool, error) {
+	var batchItemEndAcc common.Hash
+	if node.Assertion.After.TotalMessagesRead.Cmp(node.AfterInboxBatchEndCount) == 0 {
+		batchItemEndAcc = node.AfterInboxBatchAcc
+	} else if node.Assertion.After.TotalMessagesRead.Cmp(big.NewInt(0)) > 0 {
+		var haveBatchEndAcc common.Hash
+		var err error
+		index1 := new(big.Int).Sub(node.Assertion.After.TotalMessagesRead, big.NewInt(1))
+		index2 := new(big.Int).Sub(node.AfterInboxBatchEndCount, big.NewInt(1))
+		batchItemEndAcc, haveBatchEndAcc, err = execTracker.lookup.GetInboxAccPair(index1, index2)
+		if err != nil {
+			return false, err
+		}
+		if haveBatchEndAcc != node.AfterInboxBatchAcc {
+			return false, errors.New("inbox reorg detected by batch end acc mismatch")
+		}
+	}
+	return IsAssertionValid(node.Assertion, execTracker, batchItemEndAcc)
+}
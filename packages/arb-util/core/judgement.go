@@ -25,6 +25,7 @@ type NodeInfo struct {
 	NodeHash                common.Hash
 	AfterInboxBatchEndCount *big.Int
 	AfterInboxBatchAcc      common.Hash
+	CreationTxHash          common.Hash
 }
 
 func (n *NodeInfo) AfterState() *NodeState {
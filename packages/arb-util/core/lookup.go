@@ -56,6 +56,69 @@ func (ms MessageStatus) String() string {
 	}
 }
 
+// ArbosStateSection is one part of the ArbOS state written by DumpArbosStateSection
+type ArbosStateSection uint8
+
+const (
+	ArbosStateAccounts ArbosStateSection = iota
+	ArbosStateRetryables
+	ArbosStateAddressTable
+)
+
+var ArbosStateSections = []ArbosStateSection{ArbosStateAccounts, ArbosStateRetryables, ArbosStateAddressTable}
+
+func (s ArbosStateSection) String() string {
+	switch s {
+	case ArbosStateAccounts:
+		return "accounts"
+	case ArbosStateRetryables:
+		return "retryables"
+	case ArbosStateAddressTable:
+		return "addresstable"
+	default:
+		return "unknown"
+	}
+}
+
+// Filename is where the section is stored in a state dump directory
+func (s ArbosStateSection) Filename() string {
+	return s.String() + ".json"
+}
+
+// ArbosStateIndex is the index.json file describing a dump of the ArbOS state
+type ArbosStateIndex struct {
+	NextBlockNumber          uint64
+	AddressTableContentsPath string
+	RetryableDataPath        string
+	AccountsPath             string
+}
+
+func NewArbosStateIndex(blockNum uint64) ArbosStateIndex {
+	return ArbosStateIndex{
+		NextBlockNumber:          blockNum + 1,
+		AddressTableContentsPath: ArbosStateAddressTable.Filename(),
+		RetryableDataPath:        ArbosStateRetryables.Filename(),
+		AccountsPath:             ArbosStateAccounts.Filename(),
+	}
+}
+
+// CheckpointInfo describes a machine checkpoint saved in the database
+type CheckpointInfo struct {
+	TotalGasUsed       *big.Int `json:"totalGasUsed"`
+	MessageCount       *big.Int `json:"messageCount"`
+	L2BlockNumber      *big.Int `json:"l2BlockNumber"`
+	LogCount           *big.Int `json:"logCount"`
+	SendCount          *big.Int `json:"sendCount"`
+	LastInboxTimestamp *big.Int `json:"lastInboxTimestamp"`
+
+	// HasMachine is false for checkpoints that only hold machine output
+	HasMachine bool `json:"hasMachine"`
+
+	// EntrySize is the size of the checkpoint entry itself, machine values
+	// are shared between checkpoints and not included
+	EntrySize uint64 `json:"entrySize"`
+}
+
 type MachineEmission struct {
 	Value    value.Value
 	LogCount *big.Int
@@ -113,10 +176,27 @@ type ArbCoreLookup interface {
 	// will be pruned
 	UpdateCheckpointPruningGas(gas *big.Int)
 
+	// ListCheckpoints returns every checkpoint in the database ordered by gas
+	ListCheckpoints() ([]CheckpointInfo, error)
+
+	// DeleteCheckpoints deletes the checkpoints saved at the given gas values,
+	// always keeping the first two and last checkpoints
+	DeleteCheckpoints(gasValues []*big.Int) (uint64, error)
+
+	// GetGasAtEndOfBlock returns the total gas used by the machine after
+	// executing the given L2 block
+	GetGasAtEndOfBlock(blockNumber uint64) (*big.Int, error)
+
+	// ReorgMachineCache drops cached machines at or after nextGasUsed
+	ReorgMachineCache(nextGasUsed *big.Int)
+
 	// SaveRocksdbCheckpoint tells rocksdb to save a copy of the current database state
 	SaveRocksdbCheckpoint()
 
 	DumpArbosState(mach machine.Machine, blockNum uint64, dirname string) error
+
+	// DumpArbosStateSection writes one part of the ArbOS state of mach to filename
+	DumpArbosStateSection(mach machine.Machine, section ArbosStateSection, filename string) error
 }
 
 type ArbCoreInbox interface {
@@ -220,6 +300,29 @@ func GetSingleSend(lookup ArbOutputLookup, index *big.Int) ([]byte, error) {
 	return sends[0], nil
 }
 
+const sequencerBatchItemPageSize = 1000
+
+// ForEachSequencerBatchItem calls f with every sequencer batch item from the
+// one containing startIndex on, reading them from the database in pages
+func ForEachSequencerBatchItem(lookup ArbOutputLookup, startIndex *big.Int, f func(inbox.SequencerBatchItem) error) error {
+	next := new(big.Int).Set(startIndex)
+	for {
+		items, err := lookup.GetSequencerBatchItemsPage(next, sequencerBatchItemPageSize)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err := f(item); err != nil {
+				return err
+			}
+		}
+		if len(items) < sequencerBatchItemPageSize {
+			return nil
+		}
+		next = new(big.Int).Add(items[len(items)-1].LastSeqNum, big.NewInt(1))
+	}
+}
+
 func GetZeroOrOneLog(lookup ArbOutputLookup, index *big.Int) (ValueAndInbox, error) {
 	logs, err := lookup.GetLogs(index, big.NewInt(1))
 	if err != nil {
@@ -27,6 +27,18 @@ type ArbStorage interface {
 	Initialized() bool
 	CloseArbStorage() bool
 
+	// CurrentSchemaVersion is the ArbCore schema version this binary reads,
+	// older databases are upgraded by the migrations in arb-node-core/dbmigrate
+	CurrentSchemaVersion() uint64
+	// SchemaVersion returns the ArbCore schema version recorded in the
+	// database, found is false for databases that haven't been initialized
+	SchemaVersion() (version uint64, found bool, err error)
+	SetSchemaVersion(version uint64) error
+
+	// CatchUpWithPrimary loads changes written by the primary process into a
+	// database opened with core.database.secondary.enable
+	CatchUpWithPrimary() error
+
 	GetNodeStore() NodeStore
 }
 